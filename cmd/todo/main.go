@@ -1,15 +1,62 @@
 package main
 
 import (
+	"LazyToDo/internal/config"
+	"LazyToDo/internal/models"
+	"LazyToDo/internal/repository"
 	"LazyToDo/internal/server"
+	"errors"
+	"flag"
 	"log"
 	"os"
+	_ "time/tzdata" // embedded timezone database, runtime image doesn't have one
 )
 
-// Entry point: starts the server on port defined in environmental variables.
+// Entry point: loads configuration from file, environment variables and flags (see config.Load and README)
+// and starts the server.
 func main() {
-	port := os.Getenv("PORT")
-	if err := server.Start(port); err != nil {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	workflow, err := loadWorkflow(cfg.Storage.WorkflowFile)
+	if err != nil {
+		log.Fatalf("Failed to load workflow: %v", err)
+	}
+	storage := repository.Config{
+		Driver: cfg.Storage.Driver,
+		DSN:    cfg.Storage.DSN,
+		Pool: repository.PoolConfig{
+			MaxOpenConns:    cfg.Storage.MaxOpenConns,
+			MaxIdleConns:    cfg.Storage.MaxIdleConns,
+			ConnMaxLifetime: cfg.Storage.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.Storage.ConnMaxIdleTime,
+		},
+		ConnectTimeout:    cfg.Storage.ConnectTimeout,
+		Workflow:          workflow,
+		TrashRetention:    cfg.Storage.TrashRetention,
+		IdempotencyWindow: cfg.Server.IdempotencyWindow,
+	}
+	if err := server.Start(cfg, storage); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// loadWorkflow reads workflow from file at given path, nil means default workflow.
+func loadWorkflow(path string) (*models.Workflow, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := models.ParseWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
@@ -0,0 +1,384 @@
+// Package config loads settings of the service from defaults, configuration file, environment variables
+// and command line flags, later sources override earlier ones.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is configuration of the service.
+type Config struct {
+	Server   Server
+	Storage  Storage
+	Log      Log
+	Features Features
+}
+
+// Server configures HTTP server.
+type Server struct {
+	// Port the API is served on.
+	Port string
+	// ReadTimeout, WriteTimeout and IdleTimeout limit reading requests, writing responses and keeping idle
+	// connections open, 0 means no limit.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long requests in flight are waited for on shutdown before they are cancelled.
+	ShutdownTimeout time.Duration
+	// DocsDir is directory with swagger.yaml served by Swagger UI.
+	DocsDir string
+	// IdempotencyWindow is how long responses of requests with Idempotency-Key are replayed.
+	IdempotencyWindow time.Duration
+}
+
+// Storage configures storage backend.
+type Storage struct {
+	// Driver is postgres, sqlite or memory.
+	Driver string
+	// DSN is connection string (postgres) or database file path (sqlite).
+	DSN string
+	// MaxOpenConns and MaxIdleConns limit connections of the pool, 0 means no limit and no idle connections.
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime and ConnMaxIdleTime close connections used or idle for too long, 0 keeps them open.
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout is how long database is pinged on startup until it responds.
+	ConnectTimeout time.Duration
+	// WorkflowFile is JSON file with workflow of to-do items, default workflow is used if empty.
+	WorkflowFile string
+	// TrashRetention is how long deleted items are kept in trash, 0 keeps them forever.
+	TrashRetention time.Duration
+}
+
+// Log configures logging.
+type Log struct {
+	// Level is debug, info, warn or error.
+	Level string
+}
+
+// Features toggles optional parts of the API.
+type Features struct {
+	// Swagger serves Swagger UI and its specification.
+	Swagger bool
+	// LegacyRoutes serves deprecated routes without version prefix.
+	LegacyRoutes bool
+}
+
+// Log levels.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Default returns configuration used for settings missing in all sources.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:              "8080",
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+			DocsDir:           "cmd/todo/docs",
+			IdempotencyWindow: 24 * time.Hour,
+		},
+		Storage: Storage{
+			Driver:          "postgres",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+			TrashRetention:  30 * 24 * time.Hour,
+		},
+		Log:      Log{Level: LevelInfo},
+		Features: Features{Swagger: true, LegacyRoutes: true},
+	}
+}
+
+// setting is single configuration value: key in configuration file, environment variable and flag (key with
+// dots and underscores replaced by dashes, e.g. -storage-max-open-conns). Secret settings can be read from file
+// named by key with "_file" suffix (and its variable and flag), e.g. STORAGE_DSN_FILE=/run/secrets/dsn.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	set    func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{key: "server.port", env: "PORT", usage: "port the API is served on",
+		set: func(c *Config, v string) error { c.Server.Port = v; return nil }},
+	{key: "server.read_timeout", env: "READ_TIMEOUT", usage: "limit of reading request",
+		set: func(c *Config, v string) error { return setDuration(&c.Server.ReadTimeout, v) }},
+	{key: "server.write_timeout", env: "WRITE_TIMEOUT", usage: "limit of writing response",
+		set: func(c *Config, v string) error { return setDuration(&c.Server.WriteTimeout, v) }},
+	{key: "server.idle_timeout", env: "IDLE_TIMEOUT", usage: "how long idle keep-alive connections are kept",
+		set: func(c *Config, v string) error { return setDuration(&c.Server.IdleTimeout, v) }},
+	{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", usage: "how long requests in flight are waited for on shutdown",
+		set: func(c *Config, v string) error { return setDuration(&c.Server.ShutdownTimeout, v) }},
+	{key: "server.docs_dir", env: "DOCS_DIR", usage: "directory with swagger.yaml",
+		set: func(c *Config, v string) error { c.Server.DocsDir = v; return nil }},
+	{key: "server.idempotency_window", env: "IDEMPOTENCY_WINDOW", usage: "how long responses of requests with Idempotency-Key are replayed",
+		set: func(c *Config, v string) error { return setDuration(&c.Server.IdempotencyWindow, v) }},
+	{key: "storage.driver", env: "STORAGE_DRIVER", usage: "storage backend: postgres, sqlite or memory",
+		set: func(c *Config, v string) error { c.Storage.Driver = v; return nil }},
+	{key: "storage.dsn", env: "STORAGE_DSN", usage: "connection string (postgres) or database file (sqlite)", secret: true,
+		set: func(c *Config, v string) error { c.Storage.DSN = v; return nil }},
+	{key: "storage.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum of open connections, 0 is unlimited",
+		set: func(c *Config, v string) error { return setInt(&c.Storage.MaxOpenConns, v) }},
+	{key: "storage.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum of idle connections",
+		set: func(c *Config, v string) error { return setInt(&c.Storage.MaxIdleConns, v) }},
+	{key: "storage.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "how long connection may be reused, 0 is forever",
+		set: func(c *Config, v string) error { return setDuration(&c.Storage.ConnMaxLifetime, v) }},
+	{key: "storage.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", usage: "how long connection may be idle, 0 is forever",
+		set: func(c *Config, v string) error { return setDuration(&c.Storage.ConnMaxIdleTime, v) }},
+	{key: "storage.connect_timeout", env: "DB_CONNECT_TIMEOUT", usage: "how long database is pinged on startup until it responds",
+		set: func(c *Config, v string) error { return setDuration(&c.Storage.ConnectTimeout, v) }},
+	{key: "storage.workflow_file", env: "WORKFLOW_FILE", usage: "JSON file with workflow of items",
+		set: func(c *Config, v string) error { c.Storage.WorkflowFile = v; return nil }},
+	{key: "storage.trash_retention", env: "TRASH_RETENTION", usage: "how long deleted items are kept, 0 is forever",
+		set: func(c *Config, v string) error { return setDuration(&c.Storage.TrashRetention, v) }},
+	{key: "log.level", env: "LOG_LEVEL", usage: "log level: debug, info, warn or error",
+		set: func(c *Config, v string) error { c.Log.Level = strings.ToLower(v); return nil }},
+	{key: "features.swagger", env: "FEATURE_SWAGGER", usage: "serve Swagger UI",
+		set: func(c *Config, v string) error { return setBool(&c.Features.Swagger, v) }},
+	{key: "features.legacy_routes", env: "FEATURE_LEGACY_ROUTES", usage: "serve deprecated routes without /api/v1 prefix",
+		set: func(c *Config, v string) error { return setBool(&c.Features.LegacyRoutes, v) }},
+}
+
+// aliases are other environment variables of settings read when the setting's own variable isn't set.
+// DATABASE_URL is shared with migrate tool of Docker image.
+var aliases = map[string]string{"storage.dsn": "DATABASE_URL"}
+
+// configFileFlag and configFileEnv name configuration file.
+const (
+	configFileFlag = "config"
+	configFileEnv  = "CONFIG_FILE"
+)
+
+// Load reads configuration from defaults, YAML or TOML file (by extension), environment variables and
+// command line flags, each overriding the previous ones, and validates it. Empty variables count as not set.
+// Configuration file is given by -config flag or CONFIG_FILE variable.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	flags := flag.NewFlagSet("lazytodo", flag.ContinueOnError)
+	configFile := flags.String(configFileFlag, "", "YAML or TOML configuration file")
+	flagSettings := make(map[string]setting)
+	flagKeys := make(map[string]string)
+	for _, s := range settings {
+		keys := []string{s.key}
+		if s.secret {
+			keys = append(keys, s.key+"_file")
+		}
+		for _, key := range keys {
+			usage := s.usage
+			if key != s.key {
+				usage = "file with " + usage
+			}
+			name := strings.NewReplacer(".", "-", "_", "-").Replace(key)
+			flags.String(name, "", usage)
+			flagSettings[name], flagKeys[name] = s, key
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = getenv(configFileEnv)
+	}
+	values := make(map[string]string)
+	if path != "" {
+		var err error
+		if values, err = readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, s := range settings {
+		envs := []string{s.env}
+		if alias, ok := aliases[s.key]; ok {
+			envs = append(envs, alias)
+		}
+		for _, env := range envs {
+			if value := getenv(env); value != "" {
+				setValue(values, s, s.key, value)
+				break
+			}
+			if value := getenv(env + "_FILE"); s.secret && value != "" {
+				setValue(values, s, s.key+"_file", value)
+				break
+			}
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if s, ok := flagSettings[f.Name]; ok {
+			setValue(values, s, flagKeys[f.Name], f.Value.String())
+		}
+	})
+
+	cfg := Default()
+	var errs []error
+	for _, s := range settings {
+		value, ok := values[s.key]
+		if path, fromFile := values[s.key+"_file"]; s.secret && fromFile && !ok {
+			secret, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_file: %w", s.key, err))
+				continue
+			}
+			value, ok = strings.TrimRight(string(secret), "\r\n"), true
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(&cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// setValue stores value of the setting, so it overrides value of the same setting read from previous source,
+// including the secret given the other way.
+func setValue(values map[string]string, s setting, key, value string) {
+	delete(values, s.key)
+	delete(values, s.key+"_file")
+	values[key] = value
+}
+
+// readFile reads flattened settings ("section.key") from YAML or TOML configuration file.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	document := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("unsupported configuration file %s, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string]string)
+	flatten(values, "", document)
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+		if s.secret {
+			known[s.key+"_file"] = true
+		}
+	}
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s: unknown settings: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, document map[string]any) {
+	for key, value := range document {
+		if nested, ok := value.(map[string]any); ok {
+			flatten(values, prefix+key+".", nested)
+			continue
+		}
+		values[prefix+key] = fmt.Sprint(value)
+	}
+}
+
+// Validate checks all settings and returns all problems together.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port: %q isn't valid port", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout: must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout: must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout: must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(c.Server.IdempotencyWindow > 0, "server.idempotency_window: must be positive")
+	switch c.Storage.Driver {
+	case "postgres":
+		check(c.Storage.DSN != "", "storage.dsn: is required for postgres driver")
+	case "sqlite", "memory":
+	default:
+		check(false, "storage.driver: unknown driver %q, expected postgres, sqlite or memory", c.Storage.Driver)
+	}
+	check(c.Storage.MaxOpenConns >= 0, "storage.max_open_conns: must not be negative")
+	check(c.Storage.MaxIdleConns >= 0, "storage.max_idle_conns: must not be negative")
+	check(c.Storage.MaxOpenConns == 0 || c.Storage.MaxIdleConns <= c.Storage.MaxOpenConns,
+		"storage.max_idle_conns: must not exceed storage.max_open_conns")
+	check(c.Storage.ConnMaxLifetime >= 0, "storage.conn_max_lifetime: must not be negative")
+	check(c.Storage.ConnMaxIdleTime >= 0, "storage.conn_max_idle_time: must not be negative")
+	check(c.Storage.ConnectTimeout > 0, "storage.connect_timeout: must be positive")
+	check(c.Storage.TrashRetention >= 0, "storage.trash_retention: must not be negative")
+	switch c.Log.Level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+	default:
+		check(false, "log.level: unknown level %q, expected debug, info, warn or error", c.Log.Level)
+	}
+	return errors.Join(errs...)
+}
+
+func setDuration(target *time.Duration, value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*target = duration
+	return nil
+}
+
+func setInt(target *int, value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q isn't integer", value)
+	}
+	*target = number
+	return nil
+}
+
+func setBool(target *bool, value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%q isn't boolean", value)
+	}
+	*target = parsed
+	return nil
+}
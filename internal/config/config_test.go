@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	yamlFile := write("config.yaml", `
+server:
+  port: 9000
+  read_timeout: 5s
+storage:
+  driver: postgres
+  dsn: postgres://file
+  max_open_conns: 50
+features:
+  legacy_routes: false
+`)
+	tomlFile := write("config.toml", `
+[storage]
+driver = "sqlite"
+dsn = "todos.db"
+max_idle_conns = 2
+
+[log]
+level = "DEBUG"
+`)
+	secretFile := write("dsn", "postgres://secret\n")
+	unknownFile := write("unknown.yaml", "server:\n  prot: 9000\n")
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		expected    func(c *Config)
+		expectError bool
+	}{
+		{
+			name: "defaults with DSN",
+			env:  map[string]string{"STORAGE_DSN": "postgres://env"},
+			expected: func(c *Config) {
+				c.Storage.DSN = "postgres://env"
+			},
+		},
+		{
+			name: "YAML file",
+			args: []string{"-config", yamlFile},
+			expected: func(c *Config) {
+				c.Server.Port = "9000"
+				c.Server.ReadTimeout = 5 * time.Second
+				c.Storage.DSN = "postgres://file"
+				c.Storage.MaxOpenConns = 50
+				c.Features.LegacyRoutes = false
+			},
+		},
+		{
+			name: "TOML file from variable",
+			env:  map[string]string{"CONFIG_FILE": tomlFile},
+			expected: func(c *Config) {
+				c.Storage.Driver = "sqlite"
+				c.Storage.DSN = "todos.db"
+				c.Storage.MaxIdleConns = 2
+				c.Log.Level = LevelDebug
+			},
+		},
+		{
+			name: "variables override file and flags override variables",
+			args: []string{"-config", yamlFile, "-server-port", "9200"},
+			env:  map[string]string{"PORT": "9100", "STORAGE_DSN": "postgres://env", "DB_MAX_OPEN_CONNS": "40"},
+			expected: func(c *Config) {
+				c.Server.Port = "9200"
+				c.Server.ReadTimeout = 5 * time.Second
+				c.Storage.DSN = "postgres://env"
+				c.Storage.MaxOpenConns = 40
+				c.Features.LegacyRoutes = false
+			},
+		},
+		{
+			name: "secret from file overrides value of file",
+			args: []string{"-config", yamlFile},
+			env:  map[string]string{"STORAGE_DSN_FILE": secretFile},
+			expected: func(c *Config) {
+				c.Server.Port = "9000"
+				c.Server.ReadTimeout = 5 * time.Second
+				c.Storage.DSN = "postgres://secret"
+				c.Storage.MaxOpenConns = 50
+				c.Features.LegacyRoutes = false
+			},
+		},
+		{
+			name: "secret flag",
+			args: []string{"-storage-dsn-file", secretFile},
+			expected: func(c *Config) {
+				c.Storage.DSN = "postgres://secret"
+			},
+		},
+		{
+			name: "DATABASE_URL alias",
+			env:  map[string]string{"DATABASE_URL": "postgres://alias"},
+			expected: func(c *Config) {
+				c.Storage.DSN = "postgres://alias"
+			},
+		},
+		{
+			name:        "missing DSN of postgres",
+			expectError: true,
+		},
+		{
+			name:        "invalid values",
+			env:         map[string]string{"STORAGE_DSN": "postgres://env", "READ_TIMEOUT": "soon", "FEATURE_SWAGGER": "maybe"},
+			expectError: true,
+		},
+		{
+			name:        "invalid settings",
+			env:         map[string]string{"STORAGE_DRIVER": "mongo", "PORT": "http", "LOG_LEVEL": "verbose"},
+			expectError: true,
+		},
+		{
+			name: "shutdown timeout flag",
+			args: []string{"-server-shutdown-timeout", "45s"},
+			env:  map[string]string{"STORAGE_DSN": "postgres://env"},
+			expected: func(c *Config) {
+				c.Server.ShutdownTimeout = 45 * time.Second
+				c.Storage.DSN = "postgres://env"
+			},
+		},
+		{
+			name:        "zero shutdown timeout",
+			env:         map[string]string{"STORAGE_DSN": "postgres://env", "SHUTDOWN_TIMEOUT": "0s"},
+			expectError: true,
+		},
+		{
+			name:        "idle connections over open ones",
+			env:         map[string]string{"STORAGE_DSN": "postgres://env", "DB_MAX_OPEN_CONNS": "2", "DB_MAX_IDLE_CONNS": "3"},
+			expectError: true,
+		},
+		{
+			name:        "unknown setting in file",
+			args:        []string{"-config", unknownFile},
+			env:         map[string]string{"STORAGE_DSN": "postgres://env"},
+			expectError: true,
+		},
+		{
+			name:        "missing secret file",
+			env:         map[string]string{"STORAGE_DSN_FILE": filepath.Join(dir, "missing")},
+			expectError: true,
+		},
+		{
+			name:        "unknown flag",
+			args:        []string{"-verbose"},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := Load(test.args, func(name string) string {
+				return test.env[name]
+			})
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expected := Default()
+			test.expected(&expected)
+			assert.Equal(t, expected, cfg)
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Storage.Driver = "mongo"
+	cfg.Server.Port = "0"
+	cfg.Log.Level = "verbose"
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.port")
+	assert.Contains(t, err.Error(), "storage.driver")
+	assert.Contains(t, err.Error(), "log.level")
+}
@@ -1,16 +1,21 @@
 -- name: CreateTodo :one
-INSERT INTO todos (description, status, created, updated)
-VALUES ($1, $2, $3, $4)
+INSERT INTO todos (description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: GetTodo :one
 SELECT * FROM todos
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: LockTodo :one
+SELECT * FROM todos
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR UPDATE;
 
 -- name: UpdateTodo :one
 UPDATE todos
-SET description = $2, status = $3, updated = $4
-WHERE id = $1
+SET description = $2, status = $3, updated = $4, start_at = $5, due_at = $6, priority = $7, project_id = $8, parent_id = $9, recurrence = $10, version = version + 1
+WHERE id = $1 AND version = $11
 RETURNING *;
 
 -- name: DeleteTodo :exec
@@ -1,14 +1,89 @@
+-- Create the "projects" table, projects group to-do items
+CREATE TABLE projects (
+                          id BIGSERIAL PRIMARY KEY,
+                          name VARCHAR(255) NOT NULL, -- Project name
+                          description TEXT, -- Optional description
+                          color VARCHAR(7), -- Optional #rrggbb color
+                          archived BOOLEAN NOT NULL DEFAULT FALSE, -- Archived projects don't accept new items
+                          created BIGINT, -- Created timestamp
+                          updated BIGINT -- Updated timestamp
+);
+
 -- Create the "todos" table
 CREATE TABLE todos (
                        id BIGSERIAL PRIMARY KEY,              -- Auto-incrementing primary key
                        description VARCHAR(255),  -- Description with a maximum length of 255 characters
                        status VARCHAR(255) DEFAULT 'TO DO',       -- Status with a maximum length of 255 characters
                        created BIGINT, -- Created timestamp
-                       updated BIGINT -- Updated timestamp
+                       updated BIGINT, -- Updated timestamp
+                       start_at BIGINT, -- Optional start timestamp
+                       due_at BIGINT, -- Optional due timestamp
+                       priority SMALLINT NOT NULL DEFAULT 0, -- Priority ordinal, 0 (none) to 4 (urgent)
+                       project_id BIGINT REFERENCES projects(id) ON DELETE SET NULL, -- Optional project
+                       parent_id BIGINT REFERENCES todos(id) ON DELETE SET NULL, -- Optional parent item of subtask
+                       recurrence VARCHAR(255), -- Optional RFC 5545 recurrence rule
+                       deleted_at BIGINT, -- Timestamp of moving to trash, NULL for active items
+                       version BIGINT NOT NULL DEFAULT 1 -- Incremented on every change, exposed as ETag
 );
 
 -- Create an index on the "updated" column if you plan to sort/filter by it often
 CREATE INDEX idx_id ON todos(id);
 CREATE INDEX idx_status ON todos(status);
 CREATE INDEX idx_created ON todos(created);
-CREATE INDEX idx_updated ON todos(updated);
\ No newline at end of file
+CREATE INDEX idx_updated ON todos(updated);
+CREATE INDEX idx_due_at ON todos(due_at);
+CREATE INDEX idx_priority ON todos(priority);
+CREATE INDEX idx_project_id ON todos(project_id);
+CREATE INDEX idx_parent_id ON todos(parent_id);
+CREATE INDEX idx_deleted_at ON todos(deleted_at);
+
+-- Full-text search over descriptions. Queries must use the same expression to hit the index.
+CREATE INDEX idx_todos_search ON todos USING GIN (to_tsvector('simple', COALESCE(description, '')));
+-- Tags, shared by to-do items through "todo_tags" join table
+CREATE TABLE tags (
+                      id BIGSERIAL PRIMARY KEY,
+                      name VARCHAR(64) NOT NULL UNIQUE, -- Lowercase tag name
+                      color VARCHAR(7) -- Optional #rrggbb color
+);
+
+CREATE TABLE todo_tags (
+                           todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+                           tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+                           PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX idx_todo_tags_tag_id ON todo_tags(tag_id);
+
+-- Dependencies: item "todo_id" is blocked by item "blocker_id" until the blocker is finished
+CREATE TABLE todo_dependencies (
+                                   todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+                                   blocker_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+                                   PRIMARY KEY (todo_id, blocker_id)
+);
+
+CREATE INDEX idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
+
+-- Audit log: append-only changes of to-do items, kept after items are purged
+CREATE TABLE todo_events (
+                             id BIGSERIAL PRIMARY KEY,
+                             todo_id BIGINT NOT NULL, -- Changed item, no foreign key to keep history of purged items
+                             action VARCHAR(16) NOT NULL, -- created, updated, deleted, restored or purged
+                             actor VARCHAR(255) NOT NULL, -- Who made the change
+                             changes TEXT NOT NULL, -- JSON list of field changes with values before and after
+                             created BIGINT NOT NULL -- Timestamp of the change
+);
+
+CREATE INDEX idx_todo_events_todo_id ON todo_events(todo_id);
+CREATE INDEX idx_todo_events_created ON todo_events(created);
+
+CREATE TABLE idempotency_keys (
+                                  key VARCHAR(255) PRIMARY KEY, -- Value of Idempotency-Key header
+                                  request_hash VARCHAR(64) NOT NULL, -- SHA-256 of method, path and body of the request
+                                  status INTEGER NOT NULL DEFAULT 0, -- Status of stored response, 0 while the request is processed
+                                  content_type VARCHAR(255) NOT NULL DEFAULT '', -- Content type of stored response
+                                  body TEXT NOT NULL DEFAULT '', -- Body of stored response
+                                  headers TEXT NOT NULL DEFAULT '{}', -- Replayed headers of stored response as JSON object
+                                  created BIGINT NOT NULL -- Timestamp of the first request
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created);
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// Audit log is always paginated, so a single request can't read the whole log.
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// GetToDoHistory processes request for getting all changes of item by given id from params, oldest first.
+// History of deleted and purged items is available as well.
+func (h TodoHandler) GetToDoHistory(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	events, err := h.repo.GetHistory(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err, "Failed getting history")
+		return
+	}
+	if len(events) == 0 {
+		_ = c.Error(models.NotFoundError(fmt.Sprintf("No history found for item with id %d", id), nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": events})
+}
+
+// GetAuditLog processes request for getting changes of all items, most recent first.
+// Log can be filtered by todo_id, actor, action and since/until unix timestamps, it's paginated by limit and page.
+func (h TodoHandler) GetAuditLog(c *gin.Context) {
+	filter, err := extractEventFilter(c)
+	if err != nil {
+		invalidParams(c, err)
+		return
+	}
+	events, err := h.repo.GetAuditLog(c.Request.Context(), filter)
+	if err != nil {
+		respondWithError(c, err, "Failed getting audit log")
+		return
+	}
+	if len(events) == 0 {
+		_ = c.Error(models.NotFoundError("No events found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": events})
+}
+
+func extractEventFilter(c *gin.Context) (models.EventFilter, error) {
+	action, err := models.ParseEventAction(c.Query("action"))
+	if err != nil {
+		return models.EventFilter{}, err
+	}
+	filter := models.EventFilter{Actor: c.Query("actor"), Action: action, Limit: defaultAuditPageSize}
+	if value := c.Query("todo_id"); len(value) != 0 {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			return models.EventFilter{}, fmt.Errorf("invalid todo_id: %s", value)
+		}
+		filter.TodoID = id
+	}
+	if filter.Since, err = timestampParam(c, "since"); err != nil {
+		return models.EventFilter{}, err
+	}
+	if filter.Until, err = timestampParam(c, "until"); err != nil {
+		return models.EventFilter{}, err
+	}
+	if value := c.Query("limit"); len(value) != 0 {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditPageSize {
+			return models.EventFilter{}, fmt.Errorf("invalid limit: %s, it must be between 1 and %d", value, maxAuditPageSize)
+		}
+		filter.Limit = limit
+	}
+	if value := c.Query("page"); len(value) != 0 {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return models.EventFilter{}, fmt.Errorf("invalid page: %s", value)
+		}
+		filter.Offset = (page - 1) * filter.Limit
+	}
+	return filter, nil
+}
+
+// timestampParam reads optional unix timestamp from query param with given name.
+func timestampParam(c *gin.Context, name string) (*int64, error) {
+	value := c.Query(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &timestamp, nil
+}
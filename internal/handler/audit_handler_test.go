@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetToDoHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	events := []models.Event{{ID: 1, TodoID: 1, Action: models.EventCreated, Actor: "alice", Changes: []models.FieldChange{}}}
+	tests := []struct {
+		name               string
+		requestParam       string
+		mockEvents         []models.Event
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "GetToDoHistory returns BadRequest for invalid id",
+			requestParam:       "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoHistory returns NotFound for item without history",
+			requestParam:       "1",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetToDoHistory returns InternalServerError",
+			requestParam:       "1",
+			mockError:          models.InternalError("Unable to get history of item with id 1", nil),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "GetToDoHistory returns OK",
+			requestParam:       "1",
+			mockEvents:         events,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam+"/history", nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, Events: test.mockEvents})
+
+			serve(c, h.GetToDoHistory)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestGetAuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	since, until := int64(1700000000), int64(1800000000)
+	events := []models.Event{{ID: 1, TodoID: 1, Action: models.EventCreated, Actor: "alice", Changes: []models.FieldChange{}}}
+	tests := []struct {
+		name               string
+		query              string
+		mockEvents         []models.Event
+		mockError          error
+		expectedStatusCode int
+		expectedFilter     models.EventFilter
+	}{
+		{
+			name:               "GetAuditLog returns OK with default page",
+			mockEvents:         events,
+			expectedStatusCode: http.StatusOK,
+			expectedFilter:     models.EventFilter{Limit: defaultAuditPageSize},
+		},
+		{
+			name:               "GetAuditLog applies filters and pagination",
+			query:              "todo_id=1&actor=alice&action=UPDATED&since=1700000000&until=1800000000&limit=10&page=3",
+			mockEvents:         events,
+			expectedStatusCode: http.StatusOK,
+			expectedFilter: models.EventFilter{TodoID: 1, Actor: "alice", Action: models.EventUpdated,
+				Since: &since, Until: &until, Limit: 10, Offset: 20},
+		},
+		{
+			name:               "GetAuditLog returns NotFound for no events",
+			query:              "actor=bob",
+			expectedStatusCode: http.StatusNotFound,
+			expectedFilter:     models.EventFilter{Actor: "bob", Limit: defaultAuditPageSize},
+		},
+		{
+			name:               "GetAuditLog returns InternalServerError",
+			mockError:          models.InternalError("Unable to get audit log", nil),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedFilter:     models.EventFilter{Limit: defaultAuditPageSize},
+		},
+		{
+			name:               "GetAuditLog returns BadRequest for invalid action",
+			query:              "action=renamed",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetAuditLog returns BadRequest for invalid todo_id",
+			query:              "todo_id=0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetAuditLog returns BadRequest for invalid since",
+			query:              "since=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetAuditLog returns BadRequest for too large limit",
+			query:              "limit=1001",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetAuditLog returns BadRequest for invalid page",
+			query:              "page=0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/audit?"+test.query, nil)
+
+			repo := &mockRepo{Error: test.mockError, Events: test.mockEvents}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.GetAuditLog)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedFilter, repo.Filter)
+		})
+	}
+}
+
+func TestRequestActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		actor              string
+		expectedStatusCode int
+		expectedActor      string
+	}{
+		{
+			name:               "Missing actor is recorded as anonymous",
+			expectedStatusCode: http.StatusOK,
+			expectedActor:      models.AnonymousActor,
+		},
+		{
+			name:               "Actor is taken from header",
+			actor:              " alice ",
+			expectedStatusCode: http.StatusOK,
+			expectedActor:      "alice",
+		},
+		{
+			name:               "Too long actor is rejected",
+			actor:              strings.Repeat("a", 256),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodDelete, "/todos/1", nil)
+			c.Request.Header.Set(actorHeader, test.actor)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: "1"},
+			}
+
+			repo := &mockRepo{}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.DeleteToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedActor, repo.Actor)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// BulkToDos processes request executing create, update and delete operations in single transaction.
+// Failed atomic request responds with problem of the failed operation listing results of all operations,
+// otherwise result of every operation is returned with 200.
+func (h TodoHandler) BulkToDos(c *gin.Context) {
+	request, err := models.ParseBulkRequest(readRequestBody(c), h.repo.Workflow())
+	if err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	results, err := h.executeBulk(c, request, actor)
+	if err != nil {
+		respondWithError(c, err, "Failed executing bulk operations")
+		return
+	}
+	if request.Atomic() {
+		for _, result := range results {
+			if result.Status >= http.StatusBadRequest && result.Status != http.StatusFailedDependency {
+				_ = c.Error(bulkError{err: bulkFailure(result, results), results: results})
+				return
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Executed bulk operations", "results": results})
+}
+
+// executeBulk executes operations of the request with valid items. Operations with invalid items fail with 400
+// listing their violations, atomic request isn't executed at all then: its other operations aren't applied.
+func (h TodoHandler) executeBulk(c *gin.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	valid, indexes, rejected := request.Split()
+	if len(rejected) == 0 {
+		return h.repo.BulkToDos(c.Request.Context(), request, actor)
+	}
+	results := make([]models.BulkResult, len(request.Operations))
+	for _, result := range rejected {
+		results[result.Index] = result
+	}
+	if request.Atomic() {
+		for _, i := range indexes {
+			results[i] = models.NotApplied(i, rejected[0].Index)
+		}
+		return results, nil
+	}
+	if len(valid.Operations) == 0 {
+		return results, nil
+	}
+	executed, err := h.repo.BulkToDos(c.Request.Context(), valid, actor)
+	if err != nil {
+		return nil, err
+	}
+	for k, result := range executed {
+		result.Index = indexes[k]
+		results[result.Index] = result
+	}
+	return results, nil
+}
+
+// PatchToDos processes request for patching every item matching query params (see GetAllToDos) in single
+// transaction, sorting params are ignored. At least one filtering param is required, so a mistyped one doesn't
+// patch every item; pagination params are rejected. Body is patch like in PatchToDo.
+// "force=true" allows finishing blocked items.
+func (h TodoHandler) PatchToDos(c *gin.Context) {
+	for _, name := range []string{"limit", "page", "cursor"} {
+		if _, ok := c.GetQuery(name); ok {
+			invalidParam(c, name, "isn't supported, all matching items are patched")
+			return
+		}
+	}
+	params, err := aggregateParams(c, h.repo.Workflow())
+	if err != nil {
+		invalidParams(c, err)
+		return
+	}
+	if len(params.Filter.Filters) == 0 && len(params.Search) == 0 && len(params.Tags.Names) == 0 && params.Blocked == nil {
+		_ = c.Error(models.ValidationError("Items to patch must be selected with filter, status, due, q, tag or blocked param", nil))
+		return
+	}
+	force, ok := queryBool(c, "force")
+	if !ok {
+		return
+	}
+	patch, ok := readPatch(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	items, err := h.repo.PatchToDos(c.Request.Context(), params, patch, force, actor)
+	if err != nil {
+		respondWithError(c, err, "Failed patching To-Do items")
+		return
+	}
+	if items == nil {
+		items = []models.ToDo{}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Patched items", "count": len(items), "items": items})
+}
+
+// bulkFailure returns error of failed operation of atomic request with the same status and code.
+// Violations of fields of all operations are listed with path of the operation, e.g. operations[2].description.
+func bulkFailure(result models.BulkResult, results []models.BulkResult) *models.AppError {
+	message := fmt.Sprintf("Bulk operations rolled back, operation %d failed: %s", result.Index, result.Error)
+	var err *models.AppError
+	switch result.Status {
+	case http.StatusBadRequest:
+		err = models.ValidationError(message, nil)
+	case http.StatusNotFound:
+		err = models.NotFoundError(message, nil)
+	case http.StatusConflict:
+		err = models.ConflictError(message, nil)
+	case http.StatusPreconditionFailed:
+		err = models.PreconditionFailedError(message, nil)
+	case http.StatusUnprocessableEntity:
+		err = models.UnprocessableError(message, nil)
+	default:
+		err = models.InternalError(message, nil)
+	}
+	for _, failed := range results {
+		for _, field := range failed.Errors {
+			err.WithFields(models.FieldError{Field: fmt.Sprintf("operations[%d].%s", failed.Index, field.Field), Message: field.Message})
+		}
+	}
+	return err.WithCode(result.Code)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"LazyToDo/internal/repository"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBulkToDos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body := `{"mode": "%s", "operations": [{"op": "create", "item": {"description": "Write"}}, {"op": "delete", "id": 42}]}`
+	failed := []models.BulkResult{
+		{Index: 0, Status: http.StatusFailedDependency, Error: "Not applied, operation 1 failed"},
+		{Index: 1, Status: http.StatusNotFound, Error: "Unable to find item with id 42"},
+	}
+	tests := []struct {
+		name               string
+		requestBody        string
+		mockResults        []models.BulkResult
+		mockError          error
+		expectedStatusCode int
+		expectedMode       string
+	}{
+		{
+			name:               "BulkToDos returns BadRequest for invalid request",
+			requestBody:        `{"operations": [{"op": "delete"}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "BulkToDos returns BadRequest for atomic request with server-set fields of item",
+			requestBody:        `{"operations": [{"op": "create", "item": {"id": 7, "description": "Write"}}, {"op": "delete", "id": 42}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "BulkToDos returns InternalServerError",
+			requestBody:        fmt.Sprintf(body, models.BulkAtomic),
+			mockError:          models.InternalError("Unable to execute bulk operations", nil),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedMode:       models.BulkAtomic,
+		},
+		{
+			name:               "BulkToDos returns status of failed operation of atomic request",
+			requestBody:        fmt.Sprintf(body, ""),
+			mockResults:        failed,
+			expectedStatusCode: http.StatusNotFound,
+			expectedMode:       models.BulkAtomic,
+		},
+		{
+			name:               "BulkToDos returns OK for best effort request with failed operation",
+			requestBody:        fmt.Sprintf(body, models.BulkBestEffort),
+			mockResults:        []models.BulkResult{{Index: 0, Status: http.StatusCreated, Item: &models.ToDo{ID: 1}}, failed[1]},
+			expectedStatusCode: http.StatusOK,
+			expectedMode:       models.BulkBestEffort,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/todos/bulk", strings.NewReader(test.requestBody))
+			c.Request.Header.Set(actorHeader, "alice")
+
+			repo := &mockRepo{Error: test.mockError, Results: test.mockResults}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.BulkToDos)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedMode, repo.Bulk.Mode)
+			if test.mockResults != nil {
+				assert.Equal(t, "alice", repo.Actor)
+				assert.Contains(t, w.Body.String(), `"results":[`)
+			}
+		})
+	}
+}
+
+// TestBulkValidation checks that items of bulk operations are validated like request bodies and their violations
+// are listed in results, using real repository, as operations are executed by it.
+func TestBulkValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	operations := `"operations": [
+		{"op": "create", "item": {"description": "Valid"}},
+		{"op": "create", "item": {"description": "` + strings.Repeat("a", models.MaxDescriptionLength+1) + `"}},
+		{"op": "update", "id": 1, "patch": {"description": null}},
+		{"op": "update", "id": 1, "item": {"description": "Copy", "status": "DONE", "created": 0}}
+	]`
+	tooLong := []models.FieldError{{Field: "description", Message: "must be at most 255 characters"}}
+	tests := []struct {
+		name               string
+		requestBody        string
+		expectedStatusCode int
+		expectedErrors     []models.FieldError
+		expectedResults    []models.BulkResult
+	}{
+		{
+			name:               "BulkToDos returns BadRequest with violations of failed operation of atomic request",
+			requestBody:        `{"mode": "atomic", ` + operations + `}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []models.FieldError{
+				{Field: "operations[1].description", Message: "must be at most 255 characters"},
+				{Field: "operations[3].created", Message: "is unknown field"},
+			},
+		},
+		{
+			name:               "BulkToDos returns OK with violations of failed operations of best effort request",
+			requestBody:        `{"mode": "best_effort", ` + operations + `}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []models.BulkResult{
+				{Index: 1, Status: http.StatusBadRequest, Code: "validation_failed", Errors: tooLong},
+				{Index: 2, Status: http.StatusBadRequest, Code: "validation_failed",
+					Errors: []models.FieldError{{Field: "description", Message: "is required"}}},
+				{Index: 3, Status: http.StatusBadRequest, Code: "validation_failed",
+					Errors: []models.FieldError{{Field: "created", Message: "is unknown field"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/todos/bulk", strings.NewReader(test.requestBody))
+
+			h := NewTodoHandler(repository.NewMemoryRepo())
+
+			serve(c, h.BulkToDos)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			var body struct {
+				Errors  []models.FieldError `json:"errors"`
+				Results []models.BulkResult `json:"results"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, test.expectedErrors, body.Errors)
+			var failed []models.BulkResult
+			for _, result := range body.Results {
+				if result.Status == http.StatusBadRequest {
+					result.Error = ""
+					failed = append(failed, result)
+				}
+			}
+			if test.expectedResults != nil {
+				assert.Equal(t, test.expectedResults, failed)
+			}
+		})
+	}
+}
+
+func TestPatchToDos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		contentType        string
+		requestBody        string
+		mockError          error
+		expectedStatusCode int
+		expectedFilters    int
+		expectedForce      bool
+	}{
+		{
+			name:               "PatchToDos returns BadRequest for invalid query",
+			query:              "?blocked=maybe",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"priority": "high"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDos returns BadRequest without filter",
+			query:              "?stauts=DONE",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"priority": "high"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDos returns BadRequest for pagination",
+			query:              "?status=DONE&limit=10",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"priority": "high"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDos returns UnsupportedMediaType",
+			query:              "?status=DONE",
+			contentType:        "text/plain",
+			requestBody:        `priority=high`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:               "PatchToDos returns Conflict for failed test",
+			query:              "?status=DONE",
+			contentType:        models.JSONPatchType,
+			requestBody:        `[{"op": "test", "path": "/priority", "value": "low"}]`,
+			mockError:          models.ConflictError("test operation failed", models.ErrPatchTest),
+			expectedStatusCode: http.StatusConflict,
+			expectedFilters:    1,
+		},
+		{
+			name:               "PatchToDos returns OK",
+			query:              "?status=DONE&force=true",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"priority": "high"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedFilters:    1,
+			expectedForce:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPatch, "/todos"+test.query, strings.NewReader(test.requestBody))
+			c.Request.Header.Set("Content-Type", test.contentType)
+
+			repo := &mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: DummyId, Status: models.StatusDone}}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.PatchToDos)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedForce, repo.Force)
+			if test.expectedFilters > 0 {
+				assert.Len(t, repo.Params.Filter.Filters, test.expectedFilters)
+			}
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"count":1`)
+				assert.Contains(t, w.Body.String(), `"priority":"high"`)
+			}
+		})
+	}
+}
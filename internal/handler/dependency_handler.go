@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetToDoDependencies processes request for getting items blocking the item with given id and items it blocks.
+func (h TodoHandler) GetToDoDependencies(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	dependencies, err := h.repo.GetDependencies(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err, "Failed getting dependencies")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retrieved dependencies", "blocked_by": dependencies.BlockedBy, "blocks": dependencies.Blocks})
+}
+
+// AddToDoDependency processes request for marking item from params as blocked by item given as "blocker_id" in JSON body.
+func (h TodoHandler) AddToDoDependency(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		BlockerID int64 `json:"blocker_id"`
+	}
+	if err := json.Unmarshal(readRequestBody(c), &body); err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	if body.BlockerID < 1 {
+		invalidField(c, "blocker_id", "must be positive integer")
+		return
+	}
+	dependencies, err := h.repo.AddDependency(c.Request.Context(), id, body.BlockerID)
+	if err != nil {
+		respondWithError(c, err, "Failed adding dependency")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency added", "blocked_by": dependencies.BlockedBy, "blocks": dependencies.Blocks})
+}
+
+// RemoveToDoDependency processes request for unblocking item from params from item given as "blocker_id" param.
+func (h TodoHandler) RemoveToDoDependency(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	blockerID, ok := positiveParam(c, "blocker_id")
+	if !ok {
+		return
+	}
+	if err := h.repo.RemoveDependency(c.Request.Context(), id, blockerID); err != nil {
+		respondWithError(c, err, "Failed removing dependency")
+		return
+	}
+	respondDeleted(c, gin.H{"message": "Dependency removed", "ID": id, "blocker_id": blockerID})
+}
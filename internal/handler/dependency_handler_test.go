@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetToDoDependencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "GetToDoDependencies returns BadRequest for invalid id",
+			requestParam:       "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoDependencies returns NotFound",
+			requestParam:       "1",
+			mockError:          models.NotFoundError("Unable to find item with id 1", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetToDoDependencies returns OK",
+			requestParam:       "1",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam+"/dependencies", nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: 2}})
+
+			serve(c, h.GetToDoDependencies)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestAddToDoDependency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		requestBody        string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "AddToDoDependency returns BadRequest for invalid id",
+			requestParam:       "0",
+			requestBody:        `{"blocker_id": 2}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "AddToDoDependency returns BadRequest for wrong body",
+			requestParam:       "1",
+			requestBody:        `{"blocker_id": "two"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "AddToDoDependency returns BadRequest for missing blocker",
+			requestParam:       "1",
+			requestBody:        `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "AddToDoDependency returns BadRequest for cycle",
+			requestParam:       "1",
+			requestBody:        `{"blocker_id": 2}`,
+			mockError:          models.ValidationError("Item with id 2 is already blocked by item with id 1", nil),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "AddToDoDependency returns OK",
+			requestParam:       "1",
+			requestBody:        `{"blocker_id": 2}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/todos/"+test.requestParam+"/dependencies", strings.NewReader(test.requestBody))
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: 2}})
+
+			serve(c, h.AddToDoDependency)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestRemoveToDoDependency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		blockerParam       string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "RemoveToDoDependency returns BadRequest for invalid blocker id",
+			requestParam:       "1",
+			blockerParam:       "-2",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "RemoveToDoDependency returns NotFound for missing link",
+			requestParam:       "1",
+			blockerParam:       "2",
+			mockError:          models.NotFoundError("Item with id 1 isn't blocked by item with id 2", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "RemoveToDoDependency returns OK",
+			requestParam:       "1",
+			blockerParam:       "2",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodDelete, "/todos/"+test.requestParam+"/dependencies/"+test.blockerParam, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+				gin.Param{Key: "blocker_id", Value: test.blockerParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError})
+
+			serve(c, h.RemoveToDoDependency)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
@@ -2,197 +2,448 @@ package handler
 
 import (
 	"LazyToDo/internal/models"
-	"LazyToDo/internal/repository"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"hash/fnv"
 	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // TodoRepository defines repository for manipulating to-do items.
+// Methods get context of the request, so their queries are cancelled when the client goes away or the server shuts down.
 type TodoRepository interface {
-	CreateToDo(*models.ToDo) (models.ToDo, error)
-	GetToDos(bag *models.ParamsBag) ([]models.ToDo, error)
-	GetToDo(id int64) (models.ToDo, error)
-	UpdateToDo(updatedItem *models.ToDo, id int64) (models.ToDo, error)
-	DeleteToDo(id int64) error
+	CreateToDo(ctx context.Context, item *models.ToDo, actor string) (models.ToDo, error)
+	GetToDos(ctx context.Context, bag *models.ParamsBag) (models.ToDoPage, error)
+	SearchToDos(ctx context.Context, query string, bag *models.ParamsBag) (models.SearchPage, error)
+	GetToDo(ctx context.Context, id int64) (models.ToDo, error)
+	GetToDoTree(ctx context.Context, id int64) (models.ToDo, error)
+	UpdateToDo(ctx context.Context, updatedItem *models.ToDo, id, version int64, force bool, actor string) (models.ToDo, error)
+	PatchToDo(ctx context.Context, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error)
+	PatchToDos(ctx context.Context, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error)
+	BulkToDos(ctx context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error)
+	DeleteToDo(ctx context.Context, id int64, subtasks models.SubtaskPolicy, actor string) error
+	GetTrash(ctx context.Context) ([]models.ToDo, error)
+	RestoreToDo(ctx context.Context, id int64, actor string) (models.ToDo, error)
+	PurgeToDo(ctx context.Context, id int64, actor string) error
+	PurgeTrash(ctx context.Context, before int64) (int64, error)
+	GetHistory(ctx context.Context, id int64) ([]models.Event, error)
+	GetAuditLog(ctx context.Context, filter models.EventFilter) ([]models.Event, error)
+	GetDependencies(ctx context.Context, id int64) (models.Dependencies, error)
+	AddDependency(ctx context.Context, id, blockerID int64) (models.Dependencies, error)
+	RemoveDependency(ctx context.Context, id, blockerID int64) error
+	GetTags(ctx context.Context) ([]models.Tag, error)
+	GetTag(ctx context.Context, id int64) (models.Tag, error)
+	CreateTag(ctx context.Context, tag *models.Tag) (models.Tag, error)
+	UpdateTag(ctx context.Context, tag *models.Tag, id int64) (models.Tag, error)
+	DeleteTag(ctx context.Context, id int64, actor string) error
+	MergeTags(ctx context.Context, sourceID, targetID int64, actor string) (models.Tag, error)
+	GetProjects(ctx context.Context, archived bool) ([]models.Project, error)
+	GetProject(ctx context.Context, id int64) (models.Project, error)
+	CreateProject(ctx context.Context, project *models.Project) (models.Project, error)
+	UpdateProject(ctx context.Context, project *models.Project, id int64) (models.Project, error)
+	ArchiveProject(ctx context.Context, id int64, archived bool) (models.Project, error)
+	DeleteProject(ctx context.Context, id int64, actor string) error
+	ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord, since int64) (models.IdempotencyRecord, bool, error)
+	SaveIdempotencyResponse(ctx context.Context, record models.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	Workflow() models.Workflow
 }
 
-// TodoHandler handles working with ToDoRepository.
+// TodoHandler serves requests of the API, its methods are gin handlers working with single repository
+// shared by all requests.
 type TodoHandler struct {
 	repo TodoRepository
 }
 
-var createHandler = func() TodoHandler {
-	return TodoHandler{repo: repository.NewToDoRepo()}
+// NewTodoHandler constructs TodoHandler working with given repository.
+func NewTodoHandler(repo TodoRepository) TodoHandler {
+	return TodoHandler{repo: repo}
 }
 
-// AddToDo processes request for adding to-do items to DB.
-func AddToDo(c *gin.Context) {
-	body := readRequestBody(c)
+// defaultPageSize is applied to cursor pagination, when limit isn't given.
+const defaultPageSize = 20
+
+// actorHeader names who makes the change, it's recorded in audit log.
+const actorHeader = "X-Actor"
 
-	item, err := models.FromJson(body)
+// AddToDo processes request for adding to-do items to DB.
+func (h TodoHandler) AddToDo(c *gin.Context) {
+	item, ok := h.readToDo(c, true)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	item, err := h.repo.CreateToDo(c.Request.Context(), &item, actor)
 	if err != nil {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Failed to process JSON",
-				"error":   err.Error(),
-			})
+		respondWithError(c, err, "Failed creating To-Do item")
 		return
 	}
+	respondCreated(c, fmt.Sprintf("%s/todos/%d", apiV1Prefix, item.ID), gin.H{"message": "Item added", "item": item})
+}
 
-	handler := createHandler()
-	item, err = handler.repo.CreateToDo(&item)
+// readToDo reads and validates body of request creating or replacing item, see models.ParseToDoRequest.
+func (h TodoHandler) readToDo(c *gin.Context, create bool) (models.ToDo, bool) {
+	item, err := models.ParseToDoRequest(readRequestBody(c), h.repo.Workflow(), create)
 	if err != nil {
-		var dbError *models.DBError
-		if errors.As(err, &dbError) {
-			c.JSON(dbError.Code(), gin.H{"message": dbError.Error(), "error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed creating To-Do item", "error": err.Error()})
-		}
-		return
+		invalidBody(c, err)
+		return models.ToDo{}, false
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Item added", "item": item})
+	return item, true
 }
 
 // GetAllToDos processes request for getting all to-do items from DB.
-func GetAllToDos(c *gin.Context) {
-	handler := createHandler()
+func (h TodoHandler) GetAllToDos(c *gin.Context) {
+	params, err := aggregateParams(c, h.repo.Workflow())
+	if err != nil {
+		invalidParams(c, err)
+		return
+	}
+	h.respondWithToDos(c, params)
+}
 
-	params := aggregateParams(c)
-	todos, err := handler.repo.GetToDos(params)
+// respondWithToDos responds with page of to-do items selected by params.
+func (h TodoHandler) respondWithToDos(c *gin.Context, params *models.ParamsBag) {
+	page, err := h.repo.GetToDos(c.Request.Context(), params)
+	if err != nil {
+		respondWithError(c, err, "Failed getting To-Do items")
+		return
+	}
+	if len(page.Items) == 0 {
+		_ = c.Error(models.NotFoundError("No To-Do items found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Got them all",
+		"items":       page.Items,
+		"total":       page.Total,
+		"has_more":    page.HasMore,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"links":       pageLinks(c, page),
+	})
+}
 
+// SearchToDos processes request for full-text search over to-do descriptions.
+// Supports the same filtering and limit/page pagination as GetAllToDos, results are ordered by relevance.
+func (h TodoHandler) SearchToDos(c *gin.Context) {
+	query := c.Query("q")
+	if len(strings.TrimSpace(query)) == 0 {
+		invalidParam(c, "q", "search query is required")
+		return
+	}
+	params, err := aggregateParams(c, h.repo.Workflow())
 	if err != nil {
-		var dbError *models.DBError
-		if errors.As(err, &dbError) {
-			c.JSON(dbError.Code(), gin.H{"message": dbError.Error(), "error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed getting To-Do items", "error": err.Error()})
-		}
+		invalidParams(c, err)
 		return
 	}
-	if len(todos) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "No To-Do items found"})
+	// Query is passed separately, so it isn't applied twice.
+	params.Search = ""
+
+	page, err := h.repo.SearchToDos(c.Request.Context(), query, params)
+	if err != nil {
+		respondWithError(c, err, "Failed searching To-Do items")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": todos})
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Search results",
+		"items":    page.Items,
+		"total":    page.Total,
+		"has_more": page.HasMore,
+	})
 }
 
 // GetSingleToDo processes request for getting single to-do item by given id from params.
-func GetSingleToDo(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Error processing request", "error": err.Error()})
+// With "tree=true" the item is returned with all its subtasks nested in "children".
+func (h TodoHandler) GetSingleToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
 		return
 	}
-	if id < 1 {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Invalid request",
-				"error":   fmt.Sprintf("Invalid id: %s", c.Param("id")),
-			})
+	tree, ok := queryBool(c, "tree")
+	if !ok {
 		return
 	}
 
-	handler := createHandler()
-	item, err := handler.repo.GetToDo(int64(id))
+	var item models.ToDo
+	var err error
+	if tree {
+		item, err = h.repo.GetToDoTree(c.Request.Context(), id)
+	} else {
+		item, err = h.repo.GetToDo(c.Request.Context(), id)
+	}
 	if err != nil {
-		var dbError *models.DBError
-		if errors.As(err, &dbError) {
-			c.JSON(dbError.Code(), gin.H{"message": dbError.Error(), "error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed getting To-Do item", "id": id, "error": err.Error()})
-		}
+		respondWithError(c, err, "Failed getting To-Do item")
+		return
+	}
+	etag := itemETag(item)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.AbortWithStatus(http.StatusNotModified)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Retrieved item", "item": item})
 }
 
-// UpdateToDo processes request for updating single to-do item with given id from params.
-func UpdateToDo(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// GetToDoChildren processes request for getting direct subtasks of the item.
+// Supports the same sorting, filtering and pagination as GetAllToDos.
+func (h TodoHandler) GetToDoChildren(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	params, err := aggregateParams(c, h.repo.Workflow())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Error processing request", "error": err.Error()})
+		invalidParams(c, err)
 		return
 	}
-	if id < 1 {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Invalid request",
-				"error":   fmt.Sprintf("Invalid id: %s", c.Param("id")),
-			})
+	if _, err := h.repo.GetToDo(c.Request.Context(), id); err != nil {
+		respondWithError(c, err, "Failed getting To-Do item")
 		return
 	}
+	params.Filter.Filters = append(params.Filter.Filters, models.Filter{
+		Field:    "parent_id",
+		Operator: models.OpEq,
+		Value:    strconv.FormatInt(id, 10),
+	})
+	h.respondWithToDos(c, params)
+}
 
-	body := readRequestBody(c)
+// UpdateToDo processes request for replacing single to-do item with given id from params.
+// Editable fields missing in the body are cleared, use PatchToDo to change only some of them.
+// "force=true" allows finishing item, that is blocked by unfinished items.
+// With "If-Match" header the item is updated only if it wasn't changed since its ETag was issued.
+func (h TodoHandler) UpdateToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	force, ok := queryBool(c, "force")
+	if !ok {
+		return
+	}
 
-	item, err := models.FromJson(body)
+	item, ok := h.readToDo(c, false)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	item, err := h.repo.UpdateToDo(c.Request.Context(), &item, id, version, force, actor)
 	if err != nil {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Failed to process JSON",
-				"error":   err.Error(),
-			})
+		respondWithError(c, err, "Failed updating To-Do item")
 		return
 	}
+	c.Header("ETag", itemETag(item))
+	c.JSON(http.StatusOK, gin.H{"message": "Updated item", "item": item})
+}
 
-	handler := createHandler()
-	item, err = handler.repo.UpdateToDo(&item, int64(id))
+// PatchToDo processes request for patching single to-do item with given id from params.
+// Body is JSON Merge Patch or JSON Patch selected by Content-Type, other media types are rejected with 415.
+// "force", "If-Match" and actor header work the same way as in UpdateToDo.
+func (h TodoHandler) PatchToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	force, ok := queryBool(c, "force")
+	if !ok {
+		return
+	}
+
+	patch, ok := readPatch(c)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	item, err := h.repo.PatchToDo(c.Request.Context(), id, patch, version, force, actor)
 	if err != nil {
-		var dbError *models.DBError
-		if errors.As(err, &dbError) {
-			c.JSON(dbError.Code(), gin.H{"message": dbError.Error(), "error": err})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed updating To-Do item", "id": id, "error": err.Error()})
-		}
+		respondWithError(c, err, "Failed patching To-Do item")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Updated item", "item": item})
+	c.Header("ETag", itemETag(item))
+	c.JSON(http.StatusOK, gin.H{"message": "Patched item", "item": item})
 }
 
-// DeleteToDo processes request for deleting single to-do item from DB by given id from params.
-func DeleteToDo(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// DeleteToDo processes request for moving single to-do item by given id from params to trash.
+// "subtasks" param selects what happens to subtasks of the item (see models.SubtaskPolicy), by default
+// items having subtasks aren't deleted.
+func (h TodoHandler) DeleteToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	policy, err := models.ParseSubtaskPolicy(c.Query("subtasks"))
 	if err != nil {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Error processing request",
-				"error":   err.Error(),
-			})
+		invalidParam(c, "subtasks", err.Error())
+		return
+	}
+
+	actor, ok := requestActor(c)
+	if !ok {
 		return
 	}
-	if id < 1 {
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{
-				"message": "Invalid request",
-				"error":   fmt.Sprintf("Invalid id: %s", c.Param("id")),
-			})
+	if err := h.repo.DeleteToDo(c.Request.Context(), id, policy, actor); err != nil {
+		respondWithError(c, err, "Failed deleting To-Do item")
 		return
 	}
 
-	handler := createHandler()
-	err = handler.repo.DeleteToDo(int64(id))
+	respondDeleted(c, gin.H{"message": "Item moved to trash", "ID": id})
+}
+
+// pathID reads id of the resource from params. Reports error and returns false, if it's invalid.
+func pathID(c *gin.Context) (int64, bool) {
+	return positiveParam(c, "id")
+}
 
+// positiveParam reads positive integer path parameter with given name. Reports error and returns false, if it's invalid.
+func positiveParam(c *gin.Context, name string) (int64, bool) {
+	value, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || value < 1 {
+		invalidParam(c, name, "must be positive integer")
+		return 0, false
+	}
+	return value, true
+}
+
+// queryBool reads boolean query parameter with given name, missing one is false.
+// Reports error and returns false, if it's invalid.
+func queryBool(c *gin.Context, name string) (bool, bool) {
+	value, err := strconv.ParseBool(c.DefaultQuery(name, "false"))
 	if err != nil {
-		var dbError *models.DBError
-		if errors.As(err, &dbError) {
-			c.JSON(dbError.Code(), gin.H{"message": dbError.Error(), "error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed deleting To-Do item", "id": id, "error": err.Error()})
+		invalidParam(c, name, "must be true or false")
+		return false, false
+	}
+	return value, true
+}
+
+// requestActor reads who makes the change from actorHeader, blank header means models.AnonymousActor.
+// Reports error and returns false, if it's invalid.
+func requestActor(c *gin.Context) (string, bool) {
+	actor, err := models.NormalizeActor(c.GetHeader(actorHeader))
+	if err != nil {
+		invalidParam(c, actorHeader, err.Error())
+		return "", false
+	}
+	return actor, true
+}
+
+// itemETag returns entity tag of the item: its version, which "If-Match" is checked against, and hash of
+// representation, so changes of computed fields (progress, blocked flag, children) change the tag as well.
+func itemETag(item models.ToDo) string {
+	data, err := json.Marshal(item)
+	if err != nil {
+		log.Printf("Failed to hash item %d: %v", item.ID, err)
+	}
+	hash := fnv.New64a()
+	hash.Write(data)
+	return fmt.Sprintf(`"%d-%x"`, item.Version, hash.Sum64())
+}
+
+// etagMatches compares "If-None-Match" header with current entity tag, weak tags match as well.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
 		}
+	}
+	return false
+}
+
+// ifMatchVersion reads version of the item expected by "If-Match" header, 0 means the header is missing or "*".
+// Weak and foreign tags never match. Reports error and returns false, if the header can't match.
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if len(header) == 0 || header == "*" {
+		return 0, true
+	}
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		value, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		if version, err := strconv.ParseInt(value, 10, 64); err == nil && version > 0 && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		_ = c.Error(models.PreconditionFailedError("If-Match doesn't match current version of the item", nil).
+			WithCode(models.CodeVersionMismatch))
+		return 0, false
+	case 1:
+		return versions[0], true
+	}
+	invalidParam(c, "If-Match", "must have single entity tag")
+	return 0, false
+}
+
+// respondCreated responds with created resource: 201 with Location of the resource for versioned API, 200 otherwise.
+func respondCreated(c *gin.Context, location string, body gin.H) {
+	if !versioned(c) {
+		c.JSON(http.StatusOK, body)
 		return
 	}
+	c.Header("Location", location)
+	c.JSON(http.StatusCreated, body)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Item deleted", "ID": id})
+// respondDeleted responds to deleting of resource: 204 without body for versioned API, 200 with body otherwise.
+func respondDeleted(c *gin.Context, body gin.H) {
+	if !versioned(c) {
+		c.JSON(http.StatusOK, body)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// readPatch reads body of PATCH request as JSON Merge Patch or JSON Patch selected by Content-Type.
+// Reports 415 for other media types or 400 for invalid patch and returns false.
+func readPatch(c *gin.Context) (models.ToDoPatch, bool) {
+	var patch models.ToDoPatch
+	var err error
+	body := readRequestBody(c)
+	switch c.ContentType() {
+	case models.MergePatchType:
+		patch, err = models.ParseMergePatch(body)
+	case models.JSONPatchType:
+		patch, err = models.ParseJSONPatch(body)
+	default:
+		c.Header("Accept-Patch", models.MergePatchType+", "+models.JSONPatchType)
+		_ = c.Error(models.UnsupportedMediaTypeError("Unsupported patch format: "+c.ContentType(), nil))
+		return nil, false
+	}
+	if err != nil {
+		_ = c.Error(models.ValidationError("Failed to process patch: "+err.Error(), err))
+		return nil, false
+	}
+	return patch, true
 }
 
 func readRequestBody(c *gin.Context) []byte {
@@ -210,12 +461,40 @@ func readRequestBody(c *gin.Context) []byte {
 	return body
 }
 
-func aggregateParams(c *gin.Context) *models.ParamsBag {
-	return &models.ParamsBag{
-		Sort:   extractSortingParams(c),
-		Filter: extractFilterParams(c),
-		Paging: extractPaginationParams(c),
+func aggregateParams(c *gin.Context, workflow models.Workflow) (*models.ParamsBag, error) {
+	filter, err := extractFilterParams(c, workflow)
+	if err != nil {
+		return nil, err
+	}
+	paging, err := extractPaginationParams(c)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := extractTagParams(c)
+	if err != nil {
+		return nil, err
 	}
+	var blocked *bool
+	if value := c.Query("blocked"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked: %s", value)
+		}
+		blocked = &parsed
+	}
+	sorting := extractSortingParams(c)
+	// Cursor carries sorting it was issued for, so clients can follow it without repeating sort params.
+	if paging.Cursor != nil && len(c.Query("orderBy")) == 0 {
+		sorting = models.SortParams{Field: paging.Cursor.Field, ASC: paging.Cursor.ASC}
+	}
+	return &models.ParamsBag{
+		Sort:    sorting,
+		Filter:  filter,
+		Paging:  paging,
+		Search:  c.Query("q"),
+		Tags:    tags,
+		Blocked: blocked,
+	}, nil
 }
 
 func extractSortingParams(c *gin.Context) models.SortParams {
@@ -236,40 +515,105 @@ func extractSortingParams(c *gin.Context) models.SortParams {
 	return models.SortParams{Field: orderBy, ASC: asc}
 }
 
-func extractFilterParams(c *gin.Context) models.FilterParams {
+// extractFilterParams reads "filter" expression (see models.ParseFilters).
+// "status" param is kept as shorthand for "status:eq:<value>".
+// "due" shortcut (see models.DueFilters) is computed in "tz" timezone, UTC by default.
+func extractFilterParams(c *gin.Context, workflow models.Workflow) (models.FilterParams, error) {
+	filters, err := models.ParseFilters(c.Query("filter"))
+	if err != nil {
+		return models.FilterParams{}, err
+	}
 	statusFilter := c.Query("status")
-	var filters []models.Filter
 	if statusFilter != "" {
-		filters = append(filters, models.Filter{Field: "status", Value: statusFilter})
+		filters = append(filters, models.Filter{Field: "status", Operator: models.OpEq, Value: statusFilter})
+	}
+	if due := c.Query("due"); due != "" {
+		location, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+		if err != nil {
+			return models.FilterParams{}, fmt.Errorf("invalid timezone: %s", c.Query("tz"))
+		}
+		dueFilters, err := models.DueFilters(due, time.Now().In(location), workflow)
+		if err != nil {
+			return models.FilterParams{}, err
+		}
+		filters = append(filters, dueFilters...)
+	}
+	return models.FilterParams{Filters: filters}, nil
+}
+
+// extractTagParams reads repeated "tag" params. "tag_mode=all" requires all of the tags, by default any of them matches.
+func extractTagParams(c *gin.Context) (models.TagFilter, error) {
+	names, err := models.NormalizeTags(c.QueryArray("tag"))
+	if err != nil {
+		return models.TagFilter{}, err
+	}
+	filter := models.TagFilter{Names: names}
+	switch mode := c.Query("tag_mode"); mode {
+	case "", "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		return models.TagFilter{}, fmt.Errorf("invalid tag_mode: %s", mode)
 	}
-	return models.FilterParams{Filters: filters}
+	return filter, nil
 }
 
-func extractPaginationParams(c *gin.Context) models.PaginationParams {
+// extractPaginationParams reads either limit+page or limit+cursor params.
+func extractPaginationParams(c *gin.Context) (models.PaginationParams, error) {
 	limitParam := c.Query("limit")
 	pageParam := c.Query("page")
-	// If no limit - don't apply pagination.
-	if len(limitParam) == 0 {
-		return models.PaginationParams{}
-	}
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil {
-		fmt.Println(err)
-		limit = 0
+	cursorParam := c.Query("cursor")
+
+	var paging models.PaginationParams
+	if len(limitParam) != 0 {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return models.PaginationParams{}, fmt.Errorf("invalid limit: %s", limitParam)
+		}
+		paging.Limit = limit
 	}
-	// If no page specified, apply limit without offset.
-	if len(pageParam) == 0 {
-		return models.PaginationParams{Limit: limit}
+
+	if len(cursorParam) != 0 {
+		if len(pageParam) != 0 {
+			return models.PaginationParams{}, errors.New("cursor and page can't be used together")
+		}
+		cursor, err := models.DecodeCursor(cursorParam)
+		if err != nil {
+			return models.PaginationParams{}, err
+		}
+		paging.Cursor = cursor
+		if paging.Limit == 0 {
+			paging.Limit = defaultPageSize
+		}
+		return paging, nil
 	}
 
+	// If no limit - don't apply pagination.
+	if paging.Limit == 0 || len(pageParam) == 0 {
+		return paging, nil
+	}
 	page, err := strconv.Atoi(pageParam)
-	if err != nil {
-		fmt.Println(err)
-		page = 0
+	if err != nil || page < 1 {
+		return models.PaginationParams{}, fmt.Errorf("invalid page: %s", pageParam)
+	}
+	paging.Offset = (page - 1) * paging.Limit
+	return paging, nil
+}
+
+// pageLinks builds URLs of neighbour pages: the same request with cursor instead of page.
+func pageLinks(c *gin.Context, page models.ToDoPage) gin.H {
+	links := gin.H{}
+	link := func(cursor string) string {
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Set("cursor", cursor)
+		return c.Request.URL.Path + "?" + query.Encode()
+	}
+	if len(page.NextCursor) != 0 {
+		links["next"] = link(page.NextCursor)
 	}
-	offset := (page - 1) * limit
-	if offset < 0 {
-		offset = 0
+	if len(page.PrevCursor) != 0 {
+		links["prev"] = link(page.PrevCursor)
 	}
-	return models.PaginationParams{Limit: limit, Offset: offset}
+	return links
 }
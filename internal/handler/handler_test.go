@@ -2,9 +2,13 @@ package handler
 
 import (
 	"LazyToDo/internal/models"
+	"LazyToDo/internal/repository"
+	"context"
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -18,46 +22,297 @@ const DummyId = 1
 type mockRepo struct {
 	Error       error
 	ReturnValue models.ToDo
+	// Params are the last params passed to GetToDos.
+	Params *models.ParamsBag
+	// Context is the last context passed to GetToDos.
+	Context context.Context
+	// Subtasks is the last policy passed to DeleteToDo.
+	Subtasks models.SubtaskPolicy
+	// Force is the last flag passed to UpdateToDo or PatchToDo.
+	Force bool
+	// Version is the last version passed to UpdateToDo or PatchToDo.
+	Version int64
+	// Patch is the last patch passed to PatchToDo or PatchToDos.
+	Patch models.ToDoPatch
+	// Bulk is the last request passed to BulkToDos, Results are returned by it.
+	Bulk    models.BulkRequest
+	Results []models.BulkResult
+	// Actor is the last actor passed to method changing items.
+	Actor string
+	// Events are returned by GetHistory and GetAuditLog.
+	Events []models.Event
+	// Filter is the last filter passed to GetAuditLog.
+	Filter models.EventFilter
+	// Idempotency are records of idempotency keys by their keys.
+	Idempotency map[string]models.IdempotencyRecord
 }
 
-func (m *mockRepo) CreateToDo(item *models.ToDo) (models.ToDo, error) {
+func (m *mockRepo) CreateToDo(_ context.Context, item *models.ToDo, actor string) (models.ToDo, error) {
+	m.Actor = actor
 	if m.Error != nil {
 		return models.ToDo{}, m.Error
 	}
 	return *item, nil
 }
 
-func (m *mockRepo) GetToDos(bag *models.ParamsBag) ([]models.ToDo, error) {
+func (m *mockRepo) GetToDos(ctx context.Context, bag *models.ParamsBag) (models.ToDoPage, error) {
+	m.Context = ctx
+	m.Params = bag
 	if m.Error != nil {
-		return nil, m.Error
+		return models.ToDoPage{}, m.Error
 	}
-	if m.ReturnValue == (models.ToDo{}) {
-		return []models.ToDo{}, m.Error
+	if m.ReturnValue.ID == 0 {
+		return models.ToDoPage{}, m.Error
 	}
-	return []models.ToDo{m.ReturnValue}, nil
+	return models.ToDoPage{Items: []models.ToDo{m.ReturnValue}, Total: 1}, nil
 }
 
-func (m *mockRepo) GetToDo(id int64) (models.ToDo, error) {
+func (m *mockRepo) SearchToDos(_ context.Context, query string, bag *models.ParamsBag) (models.SearchPage, error) {
+	if m.Error != nil {
+		return models.SearchPage{}, m.Error
+	}
+	return models.SearchPage{Items: []models.SearchResult{{ToDo: m.ReturnValue}}, Total: 1}, nil
+}
+
+func (m *mockRepo) GetToDo(_ context.Context, id int64) (models.ToDo, error) {
 	if m.Error != nil {
 		return models.ToDo{}, m.Error
 	}
 	return m.ReturnValue, nil
 }
 
-func (m *mockRepo) UpdateToDo(item *models.ToDo, id int64) (models.ToDo, error) {
+func (m *mockRepo) UpdateToDo(_ context.Context, item *models.ToDo, id, version int64, force bool, actor string) (models.ToDo, error) {
+	m.Actor = actor
+	m.Version = version
+	m.Force = force
 	if m.Error != nil {
 		return models.ToDo{}, m.Error
 	}
 	return m.ReturnValue, nil
 }
 
-func (m *mockRepo) DeleteToDo(id int64) error {
+func (m *mockRepo) PatchToDo(_ context.Context, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error) {
+	m.Actor = actor
+	m.Version = version
+	m.Force = force
+	m.Patch = patch
+	if m.Error != nil {
+		return models.ToDo{}, m.Error
+	}
+	return patch.Apply(m.ReturnValue)
+}
+
+func (m *mockRepo) PatchToDos(_ context.Context, bag *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error) {
+	m.Params = bag
+	m.Actor = actor
+	m.Force = force
+	m.Patch = patch
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	item, err := patch.Apply(m.ReturnValue)
+	return []models.ToDo{item}, err
+}
+
+func (m *mockRepo) BulkToDos(_ context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	m.Bulk = request
+	m.Actor = actor
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Results, nil
+}
+
+func (m *mockRepo) GetToDoTree(_ context.Context, id int64) (models.ToDo, error) {
+	if m.Error != nil {
+		return models.ToDo{}, m.Error
+	}
+	item := m.ReturnValue
+	item.Children = []models.ToDo{{ID: id + 1, ParentID: &id}}
+	return item, nil
+}
+
+func (m *mockRepo) DeleteToDo(_ context.Context, id int64, subtasks models.SubtaskPolicy, actor string) error {
+	m.Actor = actor
+	m.Subtasks = subtasks
 	if m.Error != nil {
 		return m.Error
 	}
 	return nil
 }
 
+func (m *mockRepo) GetTrash(_ context.Context) ([]models.ToDo, error) {
+	if m.Error != nil || m.ReturnValue.ID == 0 {
+		return nil, m.Error
+	}
+	return []models.ToDo{m.ReturnValue}, nil
+}
+
+func (m *mockRepo) RestoreToDo(_ context.Context, id int64, actor string) (models.ToDo, error) {
+	m.Actor = actor
+	if m.Error != nil {
+		return models.ToDo{}, m.Error
+	}
+	return m.ReturnValue, nil
+}
+
+func (m *mockRepo) PurgeToDo(_ context.Context, id int64, actor string) error {
+	m.Actor = actor
+	return m.Error
+}
+
+func (m *mockRepo) PurgeTrash(_ context.Context, before int64) (int64, error) {
+	return 0, m.Error
+}
+
+func (m *mockRepo) ReserveIdempotencyKey(_ context.Context, record models.IdempotencyRecord, since int64) (models.IdempotencyRecord, bool, error) {
+	if m.Error != nil {
+		return models.IdempotencyRecord{}, false, m.Error
+	}
+	if stored, ok := m.Idempotency[record.Key]; ok && stored.Created >= since {
+		return stored, false, nil
+	}
+	if m.Idempotency == nil {
+		m.Idempotency = make(map[string]models.IdempotencyRecord)
+	}
+	m.Idempotency[record.Key] = record
+	return record, true, nil
+}
+
+func (m *mockRepo) SaveIdempotencyResponse(_ context.Context, record models.IdempotencyRecord) error {
+	m.Idempotency[record.Key] = record
+	return nil
+}
+
+func (m *mockRepo) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	delete(m.Idempotency, key)
+	return nil
+}
+
+func (m *mockRepo) GetHistory(_ context.Context, id int64) ([]models.Event, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Events, nil
+}
+
+func (m *mockRepo) GetAuditLog(_ context.Context, filter models.EventFilter) ([]models.Event, error) {
+	m.Filter = filter
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Events, nil
+}
+
+func (m *mockRepo) GetDependencies(_ context.Context, id int64) (models.Dependencies, error) {
+	if m.Error != nil {
+		return models.Dependencies{}, m.Error
+	}
+	return models.Dependencies{BlockedBy: []models.ToDo{m.ReturnValue}}, nil
+}
+
+func (m *mockRepo) AddDependency(_ context.Context, id, blockerID int64) (models.Dependencies, error) {
+	if m.Error != nil {
+		return models.Dependencies{}, m.Error
+	}
+	return models.Dependencies{BlockedBy: []models.ToDo{m.ReturnValue}}, nil
+}
+
+func (m *mockRepo) RemoveDependency(_ context.Context, id, blockerID int64) error {
+	return m.Error
+}
+
+func (m *mockRepo) Workflow() models.Workflow {
+	return models.DefaultWorkflow()
+}
+
+func (m *mockRepo) GetTags(_ context.Context) ([]models.Tag, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return []models.Tag{{ID: DummyId, Name: "work"}}, nil
+}
+
+func (m *mockRepo) GetTag(_ context.Context, id int64) (models.Tag, error) {
+	if m.Error != nil {
+		return models.Tag{}, m.Error
+	}
+	return models.Tag{ID: id, Name: "work"}, nil
+}
+
+func (m *mockRepo) CreateTag(_ context.Context, tag *models.Tag) (models.Tag, error) {
+	if m.Error != nil {
+		return models.Tag{}, m.Error
+	}
+	return *tag, nil
+}
+
+func (m *mockRepo) UpdateTag(_ context.Context, tag *models.Tag, id int64) (models.Tag, error) {
+	if m.Error != nil {
+		return models.Tag{}, m.Error
+	}
+	return *tag, nil
+}
+
+func (m *mockRepo) DeleteTag(_ context.Context, id int64, actor string) error {
+	m.Actor = actor
+	return m.Error
+}
+
+func (m *mockRepo) GetProjects(_ context.Context, archived bool) ([]models.Project, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return []models.Project{{ID: DummyId, Name: "Team", Archived: archived}}, nil
+}
+
+func (m *mockRepo) GetProject(_ context.Context, id int64) (models.Project, error) {
+	if m.Error != nil {
+		return models.Project{}, m.Error
+	}
+	return models.Project{ID: id, Name: "Team"}, nil
+}
+
+func (m *mockRepo) CreateProject(_ context.Context, project *models.Project) (models.Project, error) {
+	if m.Error != nil {
+		return models.Project{}, m.Error
+	}
+	return *project, nil
+}
+
+func (m *mockRepo) UpdateProject(_ context.Context, project *models.Project, id int64) (models.Project, error) {
+	if m.Error != nil {
+		return models.Project{}, m.Error
+	}
+	return *project, nil
+}
+
+func (m *mockRepo) ArchiveProject(_ context.Context, id int64, archived bool) (models.Project, error) {
+	if m.Error != nil {
+		return models.Project{}, m.Error
+	}
+	return models.Project{ID: id, Name: "Team", Archived: archived}, nil
+}
+
+func (m *mockRepo) DeleteProject(_ context.Context, id int64, actor string) error {
+	m.Actor = actor
+	return m.Error
+}
+
+func (m *mockRepo) MergeTags(_ context.Context, sourceID, targetID int64, actor string) (models.Tag, error) {
+	m.Actor = actor
+	if m.Error != nil {
+		return models.Tag{}, m.Error
+	}
+	return models.Tag{ID: targetID, Name: "work"}, nil
+}
+
+// serve calls handler and renders its error the way Problems middleware does.
+func serve(c *gin.Context, handler gin.HandlerFunc) {
+	handler(c)
+	renderProblem(c)
+}
+
 // TestAddToDo covers all possible cases of adding to-do with respective return statuses.
 func TestAddToDo(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -67,12 +322,19 @@ func TestAddToDo(t *testing.T) {
 		requestBody        string
 		mockError          error
 		expectedStatusCode int
+		expectedFields     []string
 	}{
 		{
 			name:               "CreateToDo returns BadRequest",
 			requestBody:        `{"invalid json"}`,
 			expectedStatusCode: http.StatusBadRequest,
 		},
+		{
+			name:               "CreateToDo returns BadRequest with all violations",
+			requestBody:        `{"id": 7, "description": " ", "status": "LATER", "due_at": "tomorrow", "created": 1}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedFields:     []string{"description", "status", "due_at", "created", "id"},
+		},
 		{
 			name:               "CreateToDo returns InternalServerError",
 			requestBody:        `{"description": "Description", "status": "TO DO"}`,
@@ -92,17 +354,19 @@ func TestAddToDo(t *testing.T) {
 			c, _ := gin.CreateTestContext(w)
 			c.Request, _ = http.NewRequest(http.MethodPost, "/add", strings.NewReader(test.requestBody))
 
-			createHandlerMethod := createHandler
-			createHandler = func() TodoHandler {
-				return TodoHandler{repo: &mockRepo{Error: test.mockError}}
-			}
+			h := NewTodoHandler(&mockRepo{Error: test.mockError})
 
-			t.Cleanup(func() {
-				createHandler = createHandlerMethod
-			})
-
-			AddToDo(c)
+			serve(c, h.AddToDo)
 			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if test.expectedFields != nil {
+				var problem Problem
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				var fields []string
+				for _, fieldError := range problem.Errors {
+					fields = append(fields, fieldError.Field)
+				}
+				assert.Equal(t, test.expectedFields, fields)
+			}
 		})
 	}
 }
@@ -113,10 +377,61 @@ func TestGetAllToDos(t *testing.T) {
 
 	tests := []struct {
 		name               string
+		query              string
 		mockError          error
 		returnValue        models.ToDo
 		expectedStatusCode int
 	}{
+		{
+			name:               "GetToDos return BadRequest for malformed filter",
+			query:              "?filter=status:regex:DONE",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for unknown escape in filter",
+			query:              `?filter=description:like:C:%5CUsers`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for malformed limit",
+			query:              "?limit=ten",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for malformed cursor",
+			query:              "?cursor=not-a-cursor",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for cursor with page",
+			query:              "?limit=5&page=2&cursor=" + models.Cursor{Field: "id", ASC: true, ID: 5}.Encode(),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for unknown due shortcut",
+			query:              "?due=someday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for unknown timezone",
+			query:              "?due=today&tz=Mars/Olympus",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for unknown tag mode",
+			query:              "?tag=work&tag_mode=most",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for blank tag",
+			query:              "?tag=%20",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDos return BadRequest for malformed blocked",
+			query:              "?blocked=sometimes",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "GetToDos return InternalServerError",
 			mockError:          errors.New("something went wrong"),
@@ -133,24 +448,108 @@ func TestGetAllToDos(t *testing.T) {
 			returnValue:        models.ToDo{ID: DummyId},
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "GetToDos return OK with due shortcut",
+			query:              "?due=this_week&tz=Europe/Kyiv",
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GetToDos return OK with tags",
+			query:              "?tag=work&tag=Urgent&tag_mode=all",
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GetToDos return OK for actionable items",
+			query:              "?blocked=false",
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GetToDos return OK with filter",
+			query:              "?filter=updated:gte:1700000000,status:in:TO%20DO|DONE",
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
-			c.Request, _ = http.NewRequest(http.MethodGet, "/todos", nil)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos"+test.query, nil)
 
-			createHandlerMethod := createHandler
-			createHandler = func() TodoHandler {
-				return TodoHandler{repo: &mockRepo{Error: test.mockError, ReturnValue: test.returnValue}}
-			}
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: test.returnValue})
+
+			serve(c, h.GetAllToDos)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+// TestRequestContext checks that repository gets context of the request, so its queries are cancelled with the request.
+func TestRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/todos", nil)
+
+	repo := &mockRepo{ReturnValue: models.ToDo{ID: DummyId}}
+	h := NewTodoHandler(repo)
+
+	serve(c, h.GetAllToDos)
+	if assert.NotNil(t, repo.Context) {
+		assert.ErrorIs(t, repo.Context.Err(), context.Canceled)
+	}
+}
+
+// TestSearchToDos covers all possible cases of searching to-dos with respective return statuses.
+func TestSearchToDos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "SearchToDos returns BadRequest without query",
+			query:              "?q=%20",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "SearchToDos returns BadRequest for repository validation error",
+			query:              "?q=!!!",
+			mockError:          models.ValidationError("Search query has no words", nil),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "SearchToDos returns InternalServerError",
+			query:              "?q=milk",
+			mockError:          errors.New("something went wrong"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "SearchToDos returns OK",
+			query:              "?q=milk&limit=10",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/search"+test.query, nil)
 
-			t.Cleanup(func() {
-				createHandler = createHandlerMethod
-			})
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: DummyId}})
 
-			GetAllToDos(c)
+			serve(c, h.SearchToDos)
 			assert.Equal(t, test.expectedStatusCode, w.Code)
 		})
 	}
@@ -159,9 +558,12 @@ func TestGetAllToDos(t *testing.T) {
 // TestGetSingleToDo covers all possible cases of getting single to-do with respective return statuses.
 func TestGetSingleToDo(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+	item := models.ToDo{ID: DummyId, Version: 2}
 	tests := []struct {
 		name               string
 		requestParam       string
+		query              string
+		ifNoneMatch        string
 		mockError          error
 		returnValue        models.ToDo
 		expectedStatusCode int
@@ -181,9 +583,15 @@ func TestGetSingleToDo(t *testing.T) {
 		{
 			name:               "GetSingleToDo returns NotFound",
 			requestParam:       strconv.Itoa(DummyId),
-			mockError:          models.NewDBError("Not Found", http.StatusNotFound, errors.New("something went wrong")),
+			mockError:          models.NotFoundError("Not Found", errors.New("something went wrong")),
 			expectedStatusCode: http.StatusNotFound,
 		},
+		{
+			name:               "GetSingleToDo returns BadRequest for malformed tree",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?tree=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "GetSingleToDo returns OK",
 			requestParam:       strconv.Itoa(DummyId),
@@ -191,28 +599,113 @@ func TestGetSingleToDo(t *testing.T) {
 			returnValue:        models.ToDo{ID: DummyId},
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "GetSingleToDo returns OK with tree",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?tree=true",
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GetSingleToDo returns NotModified for current ETag",
+			requestParam:       strconv.Itoa(DummyId),
+			ifNoneMatch:        `"1-0", ` + itemETag(item),
+			returnValue:        item,
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:               "GetSingleToDo returns NotModified for weak ETag",
+			requestParam:       strconv.Itoa(DummyId),
+			ifNoneMatch:        "W/" + itemETag(item),
+			returnValue:        item,
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:               "GetSingleToDo returns OK for outdated ETag",
+			requestParam:       strconv.Itoa(DummyId),
+			ifNoneMatch:        itemETag(models.ToDo{ID: DummyId, Version: 1}),
+			returnValue:        item,
+			expectedStatusCode: http.StatusOK,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
-			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam, nil)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam+test.query, nil)
+			c.Request.Header.Set("If-None-Match", test.ifNoneMatch)
 			c.Params = gin.Params{
 				gin.Param{Key: "id", Value: test.requestParam},
 			}
 
-			createHandlerMethod := createHandler
-			createHandler = func() TodoHandler {
-				return TodoHandler{repo: &mockRepo{Error: test.mockError, ReturnValue: test.returnValue}}
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: test.returnValue})
+
+			serve(c, h.GetSingleToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, len(test.query) != 0, strings.Contains(w.Body.String(), `"children"`))
+			}
+			if len(test.query) == 0 && (test.expectedStatusCode == http.StatusOK || test.expectedStatusCode == http.StatusNotModified) {
+				assert.Equal(t, itemETag(test.returnValue), w.Header().Get("ETag"))
 			}
+		})
+	}
+}
 
-			t.Cleanup(func() {
-				createHandler = createHandlerMethod
-			})
+// TestGetToDoChildren covers all possible cases of getting subtasks of the item with respective return statuses.
+func TestGetToDoChildren(t *testing.T) {
+	gin.SetMode(gin.TestMode)
 
-			GetSingleToDo(c)
+	tests := []struct {
+		name               string
+		requestParam       string
+		query              string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "GetToDoChildren returns BadRequest for invalid id",
+			requestParam:       "0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoChildren returns BadRequest for malformed limit",
+			requestParam:       "3",
+			query:              "?limit=all",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoChildren returns NotFound for unknown item",
+			requestParam:       "3",
+			mockError:          models.NotFoundError("Unable to find item with id 3", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetToDoChildren returns OK",
+			requestParam:       "3",
+			query:              "?status=DONE",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam+"/children"+test.query, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			repo := &mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: DummyId}}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.GetToDoChildren)
 			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Contains(t, repo.Params.Filter.Filters, models.Filter{Field: "parent_id", Operator: models.OpEq, Value: "3"})
+			}
 		})
 	}
 }
@@ -223,10 +716,14 @@ func TestUpdateToDo(t *testing.T) {
 	tests := []struct {
 		name               string
 		requestParam       string
+		query              string
 		requestBody        string
+		ifMatch            string
 		mockError          error
 		returnValue        models.ToDo
 		expectedStatusCode int
+		expectedForce      bool
+		expectedVersion    int64
 	}{
 		{
 			name:               "UpdateToDo returns BadRequest with invalid ID type",
@@ -247,11 +744,17 @@ func TestUpdateToDo(t *testing.T) {
 			mockError:          errors.New("something went wrong"),
 			expectedStatusCode: http.StatusBadRequest,
 		},
+		{
+			name:               "UpdateToDo returns BadRequest without status",
+			requestParam:       strconv.Itoa(DummyId),
+			requestBody:        `{"description": "Description"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "UpdateToDo returns NotFound",
 			requestParam:       strconv.Itoa(DummyId),
 			requestBody:        `{"description": "Description", "status": "TO DO"}`,
-			mockError:          models.NewDBError("Not Found", http.StatusNotFound, errors.New("something went wrong")),
+			mockError:          models.NotFoundError("Not Found", errors.New("something went wrong")),
 			expectedStatusCode: http.StatusNotFound,
 		},
 		{
@@ -261,6 +764,29 @@ func TestUpdateToDo(t *testing.T) {
 			mockError:          errors.New("something went wrong"),
 			expectedStatusCode: http.StatusInternalServerError,
 		},
+		{
+			name:               "UpdateToDo returns BadRequest with invalid force",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?force=maybe",
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "UpdateToDo returns Conflict for blocked item",
+			requestParam:       strconv.Itoa(DummyId),
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			mockError:          models.ConflictError("Item with id 1 is blocked by unfinished items: 2", nil),
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "UpdateToDo returns OK for forced update",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?force=true",
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+			expectedForce:      true,
+		},
 		{
 			name:               "UpdateToDo returns OK",
 			requestParam:       strconv.Itoa(DummyId),
@@ -269,29 +795,280 @@ func TestUpdateToDo(t *testing.T) {
 			returnValue:        models.ToDo{ID: DummyId},
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "UpdateToDo passes version from If-Match",
+			requestParam:       strconv.Itoa(DummyId),
+			ifMatch:            itemETag(models.ToDo{ID: DummyId, Version: 3}),
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			returnValue:        models.ToDo{ID: DummyId, Version: 4},
+			expectedStatusCode: http.StatusOK,
+			expectedVersion:    3,
+		},
+		{
+			name:               "UpdateToDo accepts any version with If-Match *",
+			requestParam:       strconv.Itoa(DummyId),
+			ifMatch:            "*",
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			returnValue:        models.ToDo{ID: DummyId},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "UpdateToDo returns PreconditionFailed for changed item",
+			requestParam:       strconv.Itoa(DummyId),
+			ifMatch:            `"3"`,
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			mockError:          models.PreconditionFailedError("Item with id 1 was changed, its current version is 4", nil),
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedVersion:    3,
+		},
+		{
+			name:               "UpdateToDo returns PreconditionFailed for weak If-Match",
+			requestParam:       strconv.Itoa(DummyId),
+			ifMatch:            "W/" + itemETag(models.ToDo{ID: DummyId, Version: 3}),
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "UpdateToDo returns BadRequest for several ETags in If-Match",
+			requestParam:       strconv.Itoa(DummyId),
+			ifMatch:            `"3-a", "4-b"`,
+			requestBody:        `{"description": "Description", "status": "DONE"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
-			c.Request, _ = http.NewRequest(http.MethodPut, "/todos/"+test.requestParam, strings.NewReader(test.requestBody))
+			c.Request, _ = http.NewRequest(http.MethodPut, "/todos/"+test.requestParam+test.query, strings.NewReader(test.requestBody))
+			c.Request.Header.Set("If-Match", test.ifMatch)
 			c.Params = gin.Params{
 				gin.Param{Key: "id", Value: test.requestParam},
 			}
 
-			createHandlerMethod := createHandler
-			createHandler = func() TodoHandler {
-				return TodoHandler{repo: &mockRepo{Error: test.mockError, ReturnValue: test.returnValue}}
+			repo := &mockRepo{Error: test.mockError, ReturnValue: test.returnValue}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.UpdateToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedForce, repo.Force)
+			assert.Equal(t, test.expectedVersion, repo.Version)
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, itemETag(test.returnValue), w.Header().Get("ETag"))
 			}
-			t.Cleanup(func() {
-				createHandler = createHandlerMethod
-			})
+		})
+	}
+}
 
-			UpdateToDo(c)
+// TestPatchToDo covers all possible cases of patching single to-do with respective return statuses.
+func TestPatchToDo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name                string
+		requestParam        string
+		query               string
+		contentType         string
+		requestBody         string
+		ifMatch             string
+		mockError           error
+		returnValue         models.ToDo
+		expectedStatusCode  int
+		expectedDescription string
+		expectedForce       bool
+		expectedVersion     int64
+	}{
+		{
+			name:               "PatchToDo returns BadRequest with invalid ID",
+			requestParam:       "0",
+			contentType:        models.MergePatchType,
+			requestBody:        `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDo returns UnsupportedMediaType for plain JSON",
+			requestParam:       strconv.Itoa(DummyId),
+			contentType:        "application/json",
+			requestBody:        `{"description": "Description"}`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:               "PatchToDo returns BadRequest with invalid merge patch",
+			requestParam:       strconv.Itoa(DummyId),
+			contentType:        models.MergePatchType,
+			requestBody:        `["description"]`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDo returns BadRequest with invalid JSON patch",
+			requestParam:       strconv.Itoa(DummyId),
+			contentType:        models.JSONPatchType,
+			requestBody:        `[{"op": "rename", "path": "/description"}]`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDo returns BadRequest with invalid force",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?force=maybe",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"status": "DONE"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PatchToDo returns NotFound",
+			requestParam:       strconv.Itoa(DummyId),
+			contentType:        models.MergePatchType,
+			requestBody:        `{"status": "DONE"}`,
+			mockError:          models.NotFoundError("Not Found", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "PatchToDo returns Conflict for failed test",
+			requestParam:       strconv.Itoa(DummyId),
+			contentType:        models.JSONPatchType,
+			requestBody:        `[{"op": "test", "path": "/status", "value": "DONE"}]`,
+			mockError:          models.ConflictError("test operation failed", models.ErrPatchTest),
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:                "PatchToDo returns OK for merge patch",
+			requestParam:        strconv.Itoa(DummyId),
+			query:               "?force=true",
+			contentType:         models.MergePatchType + "; charset=utf-8",
+			requestBody:         `{"description": "Patched"}`,
+			returnValue:         models.ToDo{ID: DummyId, Description: "Description", Status: models.DefaultStatus},
+			expectedStatusCode:  http.StatusOK,
+			expectedDescription: "Patched",
+			expectedForce:       true,
+		},
+		{
+			name:                "PatchToDo returns OK for JSON patch",
+			requestParam:        strconv.Itoa(DummyId),
+			contentType:         models.JSONPatchType,
+			requestBody:         `[{"op": "replace", "path": "/description", "value": "Patched"}]`,
+			ifMatch:             itemETag(models.ToDo{ID: DummyId, Version: 3}),
+			returnValue:         models.ToDo{ID: DummyId, Description: "Description", Status: models.DefaultStatus, Version: 3},
+			expectedStatusCode:  http.StatusOK,
+			expectedDescription: "Patched",
+			expectedVersion:     3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPatch, "/todos/"+test.requestParam+test.query, strings.NewReader(test.requestBody))
+			c.Request.Header.Set("Content-Type", test.contentType)
+			c.Request.Header.Set("If-Match", test.ifMatch)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			repo := &mockRepo{Error: test.mockError, ReturnValue: test.returnValue}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.PatchToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedForce, repo.Force)
+			assert.Equal(t, test.expectedVersion, repo.Version)
+			if test.expectedStatusCode == http.StatusUnsupportedMediaType {
+				assert.Equal(t, models.MergePatchType+", "+models.JSONPatchType, w.Header().Get("Accept-Patch"))
+			}
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"description":"`+test.expectedDescription+`"`)
+				assert.NotEmpty(t, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+// TestPatchValidation checks that items changed by patches are validated like request bodies,
+// using real repository, as patches are applied by it.
+func TestPatchValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	longDescription := strings.Repeat("a", models.MaxDescriptionLength+1)
+	tests := []struct {
+		name               string
+		contentType        string
+		requestBody        string
+		expectedStatusCode int
+		expectedErrors     []models.FieldError
+	}{
+		{
+			name:               "PatchToDo returns BadRequest for cleared description",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"description": null}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors:     []models.FieldError{{Field: "description", Message: "is required"}},
+		},
+		{
+			name:               "PatchToDo returns BadRequest for too long description",
+			contentType:        models.JSONPatchType,
+			requestBody:        `[{"op": "replace", "path": "/description", "value": "` + longDescription + `"}]`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors:     []models.FieldError{{Field: "description", Message: "must be at most 255 characters"}},
+		},
+		{
+			name:               "PatchToDo returns BadRequest with all violations",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"status": "Pending", "start_at": 200, "due_at": 100, "recurrence": "FREQ=HOURLY"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []models.FieldError{
+				{Field: "status", Message: "must be one of: TO DO, IN PROGRESS, DONE"},
+				{Field: "due_at", Message: "must be after start_at"},
+				{Field: "recurrence", Message: "unsupported frequency: HOURLY"},
+			},
+		},
+		{
+			name:               "PatchToDo returns UnprocessableEntity for disallowed transition",
+			contentType:        models.MergePatchType,
+			requestBody:        `{"status": "IN PROGRESS"}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepo()
+			_, err := repo.CreateToDo(context.Background(), &models.ToDo{Description: "Description", Status: "DONE"}, "tester")
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPatch, "/todos/1", strings.NewReader(test.requestBody))
+			c.Request.Header.Set("Content-Type", test.contentType)
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "1"}}
+
+			h := NewTodoHandler(repo)
+
+			serve(c, h.PatchToDo)
 			assert.Equal(t, test.expectedStatusCode, w.Code)
+			var problem Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, test.expectedErrors, problem.Errors)
+			item, err := repo.GetToDo(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, "Description", item.Description, "invalid patch must not change the item")
 		})
 	}
+
+	t.Run("PatchToDos returns BadRequest for cleared description", func(t *testing.T) {
+		repo := repository.NewMemoryRepo()
+		_, err := repo.CreateToDo(context.Background(), &models.ToDo{Description: "Description"}, "tester")
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/todos?status=TO%20DO", strings.NewReader(`{"description": null}`))
+		c.Request.Header.Set("Content-Type", models.MergePatchType)
+
+		h := NewTodoHandler(repo)
+
+		serve(c, h.PatchToDos)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, []models.FieldError{{Field: "description", Message: "is required"}}, problem.Errors)
+	})
 }
 
 // TestDeleteToDo covers all possible cases of deleting single to-do with respective return statuses.
@@ -300,8 +1077,10 @@ func TestDeleteToDo(t *testing.T) {
 	tests := []struct {
 		name               string
 		requestParam       string
+		query              string
 		mockError          error
 		expectedStatusCode int
+		expectedSubtasks   models.SubtaskPolicy
 	}{
 		{
 			name:               "DeleteToDo returns BadRequest with invalid ID type",
@@ -318,7 +1097,7 @@ func TestDeleteToDo(t *testing.T) {
 		{
 			name:               "DeleteToDo returns NotFound",
 			requestParam:       strconv.Itoa(DummyId),
-			mockError:          models.NewDBError("Not Found", http.StatusNotFound, errors.New("something went wrong")),
+			mockError:          models.NotFoundError("Not Found", errors.New("something went wrong")),
 			expectedStatusCode: http.StatusNotFound,
 		},
 		{
@@ -327,11 +1106,31 @@ func TestDeleteToDo(t *testing.T) {
 			mockError:          errors.New("something went wrong"),
 			expectedStatusCode: http.StatusInternalServerError,
 		},
+		{
+			name:               "DeleteToDo returns BadRequest with unknown subtasks policy",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?subtasks=orphan",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "DeleteToDo returns Conflict for item with subtasks",
+			requestParam:       strconv.Itoa(DummyId),
+			mockError:          models.ConflictError("Item with id 1 has subtasks", nil),
+			expectedStatusCode: http.StatusConflict,
+		},
 		{
 			name:               "DeleteToDo returns OK",
 			requestParam:       strconv.Itoa(DummyId),
 			mockError:          nil,
 			expectedStatusCode: http.StatusOK,
+			expectedSubtasks:   models.SubtasksRestrict,
+		},
+		{
+			name:               "DeleteToDo returns OK with cascade",
+			requestParam:       strconv.Itoa(DummyId),
+			query:              "?subtasks=cascade",
+			expectedStatusCode: http.StatusOK,
+			expectedSubtasks:   models.SubtasksCascade,
 		},
 	}
 
@@ -339,21 +1138,19 @@ func TestDeleteToDo(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
-			c.Request, _ = http.NewRequest(http.MethodDelete, "/todos/"+test.requestParam, nil)
+			c.Request, _ = http.NewRequest(http.MethodDelete, "/todos/"+test.requestParam+test.query, nil)
 			c.Params = gin.Params{
 				gin.Param{Key: "id", Value: test.requestParam},
 			}
 
-			createHandlerMethod := createHandler
-			createHandler = func() TodoHandler {
-				return TodoHandler{repo: &mockRepo{Error: test.mockError}}
-			}
-			t.Cleanup(func() {
-				createHandler = createHandlerMethod
-			})
+			repo := &mockRepo{Error: test.mockError}
+			h := NewTodoHandler(repo)
 
-			DeleteToDo(c)
+			serve(c, h.DeleteToDo)
 			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, test.expectedSubtasks, repo.Subtasks)
+			}
 		})
 	}
 }
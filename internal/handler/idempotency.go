@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// idempotencyKeyHeader names request, so its retries get response of the first request instead of repeating it.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayedHeader marks responses replayed for retries.
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// replayedHeaders are headers of stored responses replayed with them besides Content-Type.
+var replayedHeaders = []string{"Location", "ETag"}
+
+// Idempotent makes requests with Idempotency-Key header safe to retry within window: the first request is processed
+// and its response stored, retries with the same key and request get the stored response. Reusing the key for
+// a different request fails with 422 and retries of request still being processed fail with 409.
+// Replayed responses keep their Location and ETag headers. Server errors and panics aren't stored, the key is released,
+// so the request can be retried. Requests without the key pass through.
+func (h TodoHandler) Idempotent(window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(idempotencyKeyHeader)
+		if len(value) == 0 {
+			c.Next()
+			return
+		}
+		key, err := models.ParseIdempotencyKey(value)
+		if err != nil {
+			invalidParam(c, idempotencyKeyHeader, err.Error())
+			c.Abort()
+			return
+		}
+		body := readRequestBody(c)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := models.IdempotencyRecord{Key: key, RequestHash: requestHash(c.Request, body), Created: now.Unix()}
+		stored, reserved, err := h.repo.ReserveIdempotencyKey(c.Request.Context(), record, now.Add(-window).Unix())
+		if err != nil {
+			respondWithError(c, err, "Failed to process idempotency key")
+			c.Abort()
+			return
+		}
+		if !reserved {
+			replayResponse(c, record, stored)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		// The key must be released or completed even if the client has gone away meanwhile.
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		// Deferred, so the key is released also when a handler panics and Problems recovers it.
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.repo.ReleaseIdempotencyKey(ctx, key); err != nil {
+				log.Printf("Failed to release idempotency key %q: %v", key, err)
+			}
+		}()
+		c.Next()
+		// Problem is rendered here instead of by Problems, so it's stored as the response.
+		renderProblem(c)
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		record.Status, record.ContentType, record.Body = recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()
+		record.Headers = storedHeaders(recorder.Header())
+		if err := h.repo.SaveIdempotencyResponse(ctx, record); err != nil {
+			log.Printf("Failed to save response for idempotency key %q: %v", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// storedHeaders picks headers of response, which are replayed with it.
+func storedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range replayedHeaders {
+		if value := header.Get(name); len(value) > 0 {
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// replayResponse responds to retry of request with stored response of the first one.
+func replayResponse(c *gin.Context, record, stored models.IdempotencyRecord) {
+	if stored.RequestHash != record.RequestHash {
+		_ = c.Error(models.UnprocessableError("Idempotency key "+record.Key+" was already used for a different request", nil).
+			WithCode(models.CodeIdempotencyKeyReused))
+		return
+	}
+	if !stored.Completed() {
+		_ = c.Error(models.ConflictError("Request with idempotency key "+record.Key+" is still being processed, retry it later", nil).
+			WithCode(models.CodeRequestInProgress))
+		return
+	}
+	for name, value := range stored.Headers {
+		c.Header(name, value)
+	}
+	c.Header(idempotentReplayedHeader, "true")
+	c.Data(stored.Status, stored.ContentType, stored.Body)
+}
+
+// requestHash identifies request by its method, path, actor and body, so retry by another actor is a different request.
+func requestHash(r *http.Request, body []byte) string {
+	actor, err := models.NormalizeActor(r.Header.Get(actorHeader))
+	if err != nil {
+		// Invalid actor is rejected by the handler, it's hashed as is.
+		actor = r.Header.Get(actorHeader)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n" + actor + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps copy of response body written by handlers.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
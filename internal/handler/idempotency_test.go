@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const first, second = `{"description": "Write"}`, `{"description": "Read"}`
+	type request struct {
+		key   string
+		body  string
+		actor string
+	}
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/add", nil), []byte(first))
+	tests := []struct {
+		name                string
+		stored              map[string]models.IdempotencyRecord
+		mockError           error
+		handlerError        error
+		handlerPanics       bool
+		requests            []request
+		expectedStatusCodes []int
+		expectedCalls       int
+		expectedReplay      bool
+	}{
+		{
+			name:                "Idempotent replays response for retry",
+			requests:            []request{{"k1", first, ""}, {"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+			expectedCalls:       1,
+			expectedReplay:      true,
+		},
+		{
+			name:                "Idempotent returns UnprocessableEntity for key reused with different body",
+			requests:            []request{{"k1", first, ""}, {"k1", second, ""}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusUnprocessableEntity},
+			expectedCalls:       1,
+		},
+		{
+			name:                "Idempotent returns UnprocessableEntity for key reused by different actor",
+			requests:            []request{{"k1", first, "alice"}, {"k1", first, "bob"}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusUnprocessableEntity},
+			expectedCalls:       1,
+		},
+		{
+			name:                "Idempotent replays response for retry with normalized actor",
+			requests:            []request{{"k1", first, ""}, {"k1", first, " " + models.AnonymousActor}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+			expectedCalls:       1,
+			expectedReplay:      true,
+		},
+		{
+			name:                "Idempotent processes requests with different keys",
+			requests:            []request{{"k1", first, ""}, {"k2", first, ""}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+			expectedCalls:       2,
+		},
+		{
+			name:                "Idempotent passes through requests without key",
+			requests:            []request{{"", first, ""}, {"", first, ""}},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+			expectedCalls:       2,
+		},
+		{
+			name:                "Idempotent replays problems of client errors",
+			handlerError:        models.ValidationError("Invalid field description: too long", nil),
+			requests:            []request{{"k1", first, ""}, {"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusBadRequest, http.StatusBadRequest},
+			expectedCalls:       1,
+			expectedReplay:      true,
+		},
+		{
+			name:                "Idempotent releases key after server error",
+			handlerError:        errors.New("connection refused"),
+			requests:            []request{{"k1", first, ""}, {"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedCalls:       2,
+		},
+		{
+			name:                "Idempotent releases key after panic",
+			handlerPanics:       true,
+			requests:            []request{{"k1", first, ""}, {"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedCalls:       2,
+		},
+		{
+			name:                "Idempotent returns Conflict for request being processed",
+			stored:              map[string]models.IdempotencyRecord{"k1": {Key: "k1", RequestHash: hash, Created: time.Now().Unix()}},
+			requests:            []request{{"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusConflict},
+		},
+		{
+			name:                "Idempotent processes request with expired key",
+			stored:              map[string]models.IdempotencyRecord{"k1": {Key: "k1", RequestHash: hash, Status: http.StatusOK, Created: 1}},
+			requests:            []request{{"k1", second, ""}},
+			expectedStatusCodes: []int{http.StatusOK},
+			expectedCalls:       1,
+		},
+		{
+			name:                "Idempotent returns BadRequest for blank key",
+			requests:            []request{{"  ", first, ""}},
+			expectedStatusCodes: []int{http.StatusBadRequest},
+		},
+		{
+			name:                "Idempotent returns InternalServerError",
+			mockError:           errors.New("connection refused"),
+			requests:            []request{{"k1", first, ""}},
+			expectedStatusCodes: []int{http.StatusInternalServerError},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &mockRepo{Error: test.mockError, Idempotency: test.stored}
+			h := NewTodoHandler(repo)
+
+			calls := 0
+			r := gin.New()
+			r.Use(Problems)
+			r.POST("/add", h.Idempotent(time.Hour), func(c *gin.Context) {
+				calls++
+				body := readRequestBody(c)
+				if test.handlerPanics {
+					panic("nil map")
+				}
+				if test.handlerError != nil {
+					respondWithError(c, test.handlerError, "Failed adding item")
+					return
+				}
+				c.Header("Location", "/todos/1")
+				c.Header("ETag", `"1"`)
+				c.JSON(http.StatusOK, gin.H{"call": calls, "body": string(body)})
+			})
+
+			var responses []*httptest.ResponseRecorder
+			for i, request := range test.requests {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodPost, "/add", strings.NewReader(request.body))
+				if request.key != "" {
+					req.Header.Set(idempotencyKeyHeader, request.key)
+				}
+				if request.actor != "" {
+					req.Header.Set(actorHeader, request.actor)
+				}
+				r.ServeHTTP(w, req)
+				assert.Equal(t, test.expectedStatusCodes[i], w.Code)
+				if w.Code != http.StatusOK {
+					assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+				}
+				responses = append(responses, w)
+				if i > 0 && test.expectedReplay {
+					assert.Equal(t, "true", w.Header().Get(idempotentReplayedHeader))
+					assert.Equal(t, responses[0].Body.String(), w.Body.String())
+					for _, name := range replayedHeaders {
+						assert.Equal(t, responses[0].Header().Get(name), w.Header().Get(name))
+					}
+				}
+			}
+			assert.Equal(t, test.expectedCalls, calls)
+		})
+	}
+}
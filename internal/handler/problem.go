@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+)
+
+// problemContentType is media type of error responses, see RFC 7807.
+const problemContentType = "application/problem+json"
+
+// requestIDHeader carries correlation id of the request. Id sent by client is kept, otherwise new one is generated.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey stores correlation id in context of the request.
+const requestIDKey = "request_id"
+
+// validRequestID limits correlation ids accepted from clients, so they are safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Problem is body of error responses (RFC 7807) extended with stable machine-readable code of the error,
+// failures of single fields of invalid requests and correlation id of the request.
+type Problem struct {
+	Type          string              `json:"type"`
+	Title         string              `json:"title"`
+	Status        int                 `json:"status"`
+	Detail        string              `json:"detail"`
+	Instance      string              `json:"instance"`
+	Code          string              `json:"code"`
+	CorrelationID string              `json:"correlation_id"`
+	Errors        []models.FieldError `json:"errors,omitempty"`
+	// Results are outcomes of operations of rolled back bulk request.
+	Results []models.BulkResult `json:"results,omitempty"`
+}
+
+// bulkError is failure of atomic bulk request, its problem lists results of all operations.
+type bulkError struct {
+	err     *models.AppError
+	results []models.BulkResult
+}
+
+func (e bulkError) Error() string {
+	return e.err.Error()
+}
+
+func (e bulkError) Unwrap() error {
+	return e.err
+}
+
+// Problems assigns correlation id to the request and renders the last error added to context by handlers
+// (see respondWithError) as problem details. Panics of handlers are rendered as internal errors.
+func Problems(c *gin.Context) {
+	requestID := c.GetHeader(requestIDHeader)
+	if !validRequestID.MatchString(requestID) {
+		requestID = newRequestID()
+	}
+	c.Set(requestIDKey, requestID)
+	c.Header(requestIDHeader, requestID)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			log.Printf("[%s] Panic: %v\n%s", requestID, recovered, debug.Stack())
+			c.Abort()
+			_ = c.Error(models.InternalError("Unexpected failure", fmt.Errorf("panic: %v", recovered)))
+			renderProblem(c)
+		}
+	}()
+	c.Next()
+	renderProblem(c)
+}
+
+// respondWithError adds error to context, Problems renders it. Errors other than AppError are unexpected ones,
+// they are reported as internal errors with given message.
+func respondWithError(c *gin.Context, err error, message string) {
+	var appError *models.AppError
+	if !errors.As(err, &appError) {
+		err = models.InternalError(message, err)
+	}
+	_ = c.Error(err)
+}
+
+// renderProblem responds with the last error added to context, unless response is already written.
+// Causes of internal errors are logged with correlation id, clients get message of the error only.
+func renderProblem(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	err := c.Errors.Last().Err
+	var appError *models.AppError
+	if !errors.As(err, &appError) {
+		appError = models.InternalError("Unexpected failure", err)
+	}
+	requestID := c.GetString(requestIDKey)
+	if appError.Kind() == models.KindInternal {
+		log.Printf("[%s] %s %s: %s: %v", requestID, c.Request.Method, c.Request.URL.Path, appError.Error(), appError.Unwrap())
+	}
+	problem := Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(appError.Status()),
+		Status:        appError.Status(),
+		Detail:        appError.Error(),
+		Instance:      c.Request.URL.Path,
+		Code:          appError.Code(),
+		CorrelationID: requestID,
+		Errors:        appError.Fields(),
+	}
+	var failed bulkError
+	if errors.As(err, &failed) {
+		problem.Results = failed.results
+	}
+	c.Header("Content-Type", problemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+// newRequestID generates random correlation id.
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		log.Printf("Failed to generate request id: %v", err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// invalidParam reports invalid query or path parameter.
+func invalidParam(c *gin.Context, name, message string) {
+	_ = c.Error(models.ValidationError(fmt.Sprintf("Invalid parameter %s: %s", name, message), nil).
+		WithFields(models.FieldError{Field: name, Message: message}))
+}
+
+// invalidField reports invalid field of request body.
+func invalidField(c *gin.Context, name, message string) {
+	_ = c.Error(models.ValidationError(fmt.Sprintf("Invalid field %s: %s", name, message), nil).
+		WithFields(models.FieldError{Field: name, Message: message}))
+}
+
+// invalidParams reports query parameters, that can't be parsed.
+func invalidParams(c *gin.Context, err error) {
+	_ = c.Error(models.ValidationError("Invalid query parameters: "+err.Error(), err))
+}
+
+// invalidBody reports request body breaking validation rules of its fields with all the violations,
+// other errors as body, that can't be parsed.
+func invalidBody(c *gin.Context, err error) {
+	var violations models.Violations
+	if !errors.As(err, &violations) {
+		invalidJSON(c, err)
+		return
+	}
+	_ = c.Error(models.ValidationError("Invalid request body: "+violations.Error(), err).WithFields(violations...))
+}
+
+// invalidJSON reports request body, that can't be parsed.
+func invalidJSON(c *gin.Context, err error) {
+	_ = c.Error(models.ValidationError("Failed to process JSON: "+err.Error(), err))
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		handler            gin.HandlerFunc
+		requestID          string
+		expectedStatusCode int
+		expectedProblem    Problem
+		expectedRequestID  string
+	}{
+		{
+			name: "Problems renders error with code and fields",
+			handler: func(c *gin.Context) {
+				invalidParam(c, "limit", "must be positive integer")
+			},
+			requestID:          "req-42",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedProblem: Problem{Type: "about:blank", Title: "Bad Request", Status: http.StatusBadRequest,
+				Detail: "Invalid parameter limit: must be positive integer", Instance: "/todos", Code: "validation_failed",
+				CorrelationID: "req-42", Errors: []models.FieldError{{Field: "limit", Message: "must be positive integer"}}},
+			expectedRequestID: "req-42",
+		},
+		{
+			name: "Problems renders specific code",
+			handler: func(c *gin.Context) {
+				respondWithError(c, models.ConflictError("Item with id 1 has subtasks", nil).WithCode(models.CodeHasSubtasks), "Failed deleting item")
+			},
+			requestID:          "req-42",
+			expectedStatusCode: http.StatusConflict,
+			expectedProblem: Problem{Type: "about:blank", Title: "Conflict", Status: http.StatusConflict,
+				Detail: "Item with id 1 has subtasks", Instance: "/todos", Code: models.CodeHasSubtasks, CorrelationID: "req-42"},
+			expectedRequestID: "req-42",
+		},
+		{
+			name: "Problems hides cause of unexpected error",
+			handler: func(c *gin.Context) {
+				respondWithError(c, errors.New("pq: connection refused"), "Failed getting items")
+			},
+			requestID:          "req-42",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedProblem: Problem{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError,
+				Detail: "Failed getting items", Instance: "/todos", Code: "internal_error", CorrelationID: "req-42"},
+			expectedRequestID: "req-42",
+		},
+		{
+			name: "Problems renders panic as internal error",
+			handler: func(c *gin.Context) {
+				panic("nil map")
+			},
+			requestID:          "req-42",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedProblem: Problem{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError,
+				Detail: "Unexpected failure", Instance: "/todos", Code: "internal_error", CorrelationID: "req-42"},
+			expectedRequestID: "req-42",
+		},
+		{
+			name: "Problems replaces invalid request id",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "Got them all"})
+			},
+			requestID:          "bad id\n",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Problems)
+			r.GET("/todos", test.handler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			req.Header.Set(requestIDHeader, test.requestID)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			requestID := w.Header().Get(requestIDHeader)
+			if test.expectedRequestID == "" {
+				assert.Regexp(t, "^[0-9a-f]{32}$", requestID)
+				return
+			}
+			assert.Equal(t, test.expectedRequestID, requestID)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var problem Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, test.expectedProblem, problem)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetProjects processes request for getting active projects, or archived ones with "archived=true".
+func (h TodoHandler) GetProjects(c *gin.Context) {
+	archived, ok := queryBool(c, "archived")
+	if !ok {
+		return
+	}
+	projects, err := h.repo.GetProjects(c.Request.Context(), archived)
+	if err != nil {
+		respondWithError(c, err, "Failed getting projects")
+		return
+	}
+	if len(projects) == 0 {
+		_ = c.Error(models.NotFoundError("No projects found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": projects})
+}
+
+// GetProject processes request for getting single project by given id from params.
+func (h TodoHandler) GetProject(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	project, err := h.repo.GetProject(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err, "Failed getting project")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retrieved project", "project": project})
+}
+
+// AddProject processes request for creating project.
+func (h TodoHandler) AddProject(c *gin.Context) {
+	project, err := models.ProjectFromJson(readRequestBody(c))
+	if err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	project, err = h.repo.CreateProject(c.Request.Context(), &project)
+	if err != nil {
+		respondWithError(c, err, "Failed creating project")
+		return
+	}
+	respondCreated(c, fmt.Sprintf("%s/projects/%d", apiV1Prefix, project.ID), gin.H{"message": "Project added", "project": project})
+}
+
+// UpdateProject processes request for updating name, description and/or color of the project.
+func (h TodoHandler) UpdateProject(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	project, err := models.ProjectFromJson(readRequestBody(c))
+	if err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	project, err = h.repo.UpdateProject(c.Request.Context(), &project, id)
+	if err != nil {
+		respondWithError(c, err, "Failed updating project")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Updated project", "project": project})
+}
+
+// ArchiveProject processes request for archiving project.
+func (h TodoHandler) ArchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, true)
+}
+
+// UnarchiveProject processes request for restoring archived project.
+func (h TodoHandler) UnarchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, false)
+}
+
+func (h TodoHandler) setProjectArchived(c *gin.Context, archived bool) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	project, err := h.repo.ArchiveProject(c.Request.Context(), id, archived)
+	if err != nil {
+		respondWithError(c, err, "Failed updating project")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Updated project", "project": project})
+}
+
+// DeleteProject processes request for deleting project, its items are kept outside of projects.
+func (h TodoHandler) DeleteProject(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteProject(c.Request.Context(), id, actor); err != nil {
+		respondWithError(c, err, "Failed deleting project")
+		return
+	}
+	respondDeleted(c, gin.H{"message": "Project deleted", "ID": id})
+}
+
+// GetProjectToDos processes request for getting to-do items of the project.
+// Supports the same sorting, filtering and pagination as GetAllToDos.
+func (h TodoHandler) GetProjectToDos(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	params, err := aggregateParams(c, h.repo.Workflow())
+	if err != nil {
+		invalidParams(c, err)
+		return
+	}
+	if _, err := h.repo.GetProject(c.Request.Context(), id); err != nil {
+		respondWithError(c, err, "Failed getting project")
+		return
+	}
+	params.Filter.Filters = append(params.Filter.Filters, models.Filter{
+		Field:    "project_id",
+		Operator: models.OpEq,
+		Value:    strconv.FormatInt(id, 10),
+	})
+	h.respondWithToDos(c, params)
+}
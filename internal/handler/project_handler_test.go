@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGetProjectToDos covers all possible cases of getting to-dos of the project with respective return statuses.
+func TestGetProjectToDos(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		query              string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "GetProjectToDos returns BadRequest for invalid id",
+			requestParam:       "team",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetProjectToDos returns BadRequest for malformed filter",
+			requestParam:       "3",
+			query:              "?filter=status",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetProjectToDos returns NotFound for unknown project",
+			requestParam:       "3",
+			mockError:          models.NotFoundError("Unable to find project with id 3", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetProjectToDos returns OK",
+			requestParam:       "3",
+			query:              "?status=DONE&orderBy=due_at",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/projects/"+test.requestParam+"/todos"+test.query, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			repo := &mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: DummyId}}
+			h := NewTodoHandler(repo)
+
+			serve(c, h.GetProjectToDos)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, "due_at", repo.Params.Sort.Field)
+				assert.Contains(t, repo.Params.Filter.Filters, models.Filter{Field: "project_id", Operator: models.OpEq, Value: "3"})
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultOccurrences is number of previewed occurrences, when count isn't given.
+const defaultOccurrences = 10
+
+// GetToDoOccurrences processes request for previewing due dates of the next occurrences of recurring item.
+func (h TodoHandler) GetToDoOccurrences(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultOccurrences)))
+	if err != nil || count < 1 || count > models.MaxOccurrences {
+		invalidParam(c, "count", fmt.Sprintf("must be between 1 and %d", models.MaxOccurrences))
+		return
+	}
+
+	item, err := h.repo.GetToDo(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err, "Failed getting To-Do item")
+		return
+	}
+	var occurrences []int64
+	if item.Recurrence != nil && item.DueAt != nil {
+		rule, err := models.ParseRRule(*item.Recurrence)
+		if err != nil {
+			respondWithError(c, err, "Failed parsing recurrence rule")
+			return
+		}
+		for _, occurrence := range rule.Occurrences(time.Unix(*item.DueAt, 0), count) {
+			occurrences = append(occurrences, occurrence.Unix())
+		}
+	}
+	if len(occurrences) == 0 {
+		_ = c.Error(models.NotFoundError("No occurrences found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retrieved occurrences", "recurrence": item.Recurrence, "occurrences": occurrences})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetToDoOccurrences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dueAt := int64(1704204000)
+	rule := "FREQ=DAILY;COUNT=3"
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		query              string
+		mockError          error
+		returnValue        models.ToDo
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "GetToDoOccurrences returns BadRequest for invalid id",
+			requestParam:       "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoOccurrences returns BadRequest for invalid count",
+			requestParam:       "1",
+			query:              "?count=1000",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "GetToDoOccurrences returns NotFound for unknown item",
+			requestParam:       "1",
+			mockError:          models.NotFoundError("Unable to find item with id 1", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetToDoOccurrences returns NotFound for item without recurrence",
+			requestParam:       "1",
+			returnValue:        models.ToDo{ID: 1, DueAt: &dueAt},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetToDoOccurrences returns OK",
+			requestParam:       "1",
+			query:              "?count=5",
+			returnValue:        models.ToDo{ID: 1, DueAt: &dueAt, Recurrence: &rule},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"occurrences":[1704290400,1704376800]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/todos/"+test.requestParam+"/occurrences"+test.query, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: test.returnValue})
+
+			serve(c, h.GetToDoOccurrences)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Body.String(), test.expectedBody)
+		})
+	}
+}
@@ -2,19 +2,133 @@ package handler
 
 import (
 	_ "LazyToDo/cmd/todo/docs"
+	"LazyToDo/internal/models"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// Route endpoints to handler.
-func Route(r *gin.Engine) {
-	r.POST("/add", AddToDo)
-	r.GET("/todos", GetAllToDos)
-	r.GET("/todos/:id", GetSingleToDo)
-	r.PUT("/todos/:id", UpdateToDo)
-	r.DELETE("/todos/:id", DeleteToDo)
+// apiV1Prefix is path prefix of version 1 of the API.
+const apiV1Prefix = "/api/v1"
 
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/static/swagger.yaml")))
-	r.Static("/static", "/app/cmd/todo/docs")
+// apiVersionKey is set in context of requests to versioned API, legacy routes don't have it.
+const apiVersionKey = "api_version"
+
+// Legacy routes without version prefix are deprecated in favor of API v1 and are removed after sunset.
+var (
+	legacyDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacySunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Options select optional routes and configure middlewares of the API.
+type Options struct {
+	// IdempotencyWindow is how long responses of requests with Idempotency-Key are replayed for retries.
+	IdempotencyWindow time.Duration
+	// LegacyRoutes keeps routes without version prefix as deprecated aliases of API v1.
+	LegacyRoutes bool
+	// Swagger serves Swagger UI with specification from DocsDir.
+	Swagger bool
+	DocsDir string
+}
+
+// Route endpoints to methods of given handler.
+// API v1 is served under apiV1Prefix, legacy routes and Swagger UI are served when enabled in options.
+// Errors of all routes are rendered as problem details.
+func Route(r *gin.Engine, h TodoHandler, options Options) {
+	idempotent := h.Idempotent(options.IdempotencyWindow)
+	r.Use(Problems)
+	r.NoRoute(func(c *gin.Context) {
+		_ = c.Error(models.NotFoundError(fmt.Sprintf("No route for %s %s", c.Request.Method, c.Request.URL.Path), nil))
+	})
+
+	v1 := r.Group(apiV1Prefix, apiVersion(1))
+	v1.POST("/todos", idempotent, h.AddToDo)
+	routeResources(v1, h)
+
+	if options.LegacyRoutes {
+		legacy := r.Group("", deprecated)
+		legacy.POST("/add", idempotent, h.AddToDo)
+		routeResources(legacy, h)
+	}
+
+	if options.Swagger {
+		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/static/swagger.yaml")))
+		r.Static("/static", options.DocsDir)
+	}
+}
+
+// routeResources routes endpoints shared by API v1 and legacy routes, they differ in creating of items only.
+func routeResources(r *gin.RouterGroup, h TodoHandler) {
+	r.GET("/todos", h.GetAllToDos)
+	r.PATCH("/todos", h.PatchToDos)
+	r.POST("/todos/bulk", h.BulkToDos)
+	r.GET("/todos/search", h.SearchToDos)
+	r.GET("/todos/:id", h.GetSingleToDo)
+	r.GET("/todos/:id/children", h.GetToDoChildren)
+	r.GET("/todos/:id/occurrences", h.GetToDoOccurrences)
+	r.GET("/todos/:id/dependencies", h.GetToDoDependencies)
+	r.GET("/todos/:id/history", h.GetToDoHistory)
+	r.POST("/todos/:id/dependencies", h.AddToDoDependency)
+	r.DELETE("/todos/:id/dependencies/:blocker_id", h.RemoveToDoDependency)
+	r.PUT("/todos/:id", h.UpdateToDo)
+	r.PATCH("/todos/:id", h.PatchToDo)
+	r.DELETE("/todos/:id", h.DeleteToDo)
+
+	r.GET("/tags", h.GetTags)
+	r.POST("/tags", h.AddTag)
+	r.GET("/tags/:id", h.GetTag)
+	r.PUT("/tags/:id", h.UpdateTag)
+	r.DELETE("/tags/:id", h.DeleteTag)
+	r.POST("/tags/:id/merge", h.MergeTags)
+
+	r.GET("/projects", h.GetProjects)
+	r.POST("/projects", h.AddProject)
+	r.GET("/projects/:id", h.GetProject)
+	r.PUT("/projects/:id", h.UpdateProject)
+	r.DELETE("/projects/:id", h.DeleteProject)
+	r.POST("/projects/:id/archive", h.ArchiveProject)
+	r.POST("/projects/:id/unarchive", h.UnarchiveProject)
+	r.GET("/projects/:id/todos", h.GetProjectToDos)
+
+	r.GET("/trash", h.GetTrash)
+	r.POST("/trash/:id/restore", h.RestoreToDo)
+	r.DELETE("/trash/:id", h.PurgeToDo)
+
+	r.GET("/audit", h.GetAuditLog)
+
+	r.GET("/workflow", h.GetWorkflow)
+}
+
+// apiVersion marks requests to given version of the API.
+func apiVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// versioned tells if request was made to versioned API, which uses 201 for created and 204 for deleted resources.
+func versioned(c *gin.Context) bool {
+	return c.GetInt(apiVersionKey) > 0
+}
+
+// deprecated marks responses of legacy routes with deprecation date, sunset date and link to the route of API v1.
+func deprecated(c *gin.Context) {
+	c.Header("Deprecation", "@"+strconv.FormatInt(legacyDeprecation.Unix(), 10))
+	c.Header("Sunset", legacySunset.Format(http.TimeFormat))
+	c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successorPath(c.Request.URL.Path)))
+	c.Next()
+}
+
+// successorPath returns path of API v1 replacing legacy path.
+func successorPath(path string) string {
+	if path == "/add" {
+		return apiV1Prefix + "/todos"
+	}
+	return apiV1Prefix + "/" + strings.TrimPrefix(path, "/")
 }
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		requestBody        string
+		expectedStatusCode int
+		expectedLocation   string
+		expectedSuccessor  string
+		expectedProblem    bool
+		withoutLegacy      bool
+	}{
+		{
+			name:               "API v1 creates item with Created and Location",
+			method:             http.MethodPost,
+			path:               "/api/v1/todos",
+			requestBody:        `{"description": "Write"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedLocation:   "/api/v1/todos/0",
+		},
+		{
+			name:               "API v1 creates tag with Created and Location",
+			method:             http.MethodPost,
+			path:               "/api/v1/tags",
+			requestBody:        `{"name": "home"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedLocation:   "/api/v1/tags/0",
+		},
+		{
+			name:               "API v1 deletes item with NoContent",
+			method:             http.MethodDelete,
+			path:               "/api/v1/todos/1",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "API v1 doesn't have legacy create route",
+			method:             http.MethodPost,
+			path:               "/api/v1/add",
+			requestBody:        `{"description": "Write"}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedProblem:    true,
+		},
+		{
+			name:               "API v1 renders invalid id as problem",
+			method:             http.MethodGet,
+			path:               "/api/v1/todos/abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedProblem:    true,
+		},
+		{
+			name:               "Legacy route creates item with OK and deprecation headers",
+			method:             http.MethodPost,
+			path:               "/add",
+			requestBody:        `{"description": "Write"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedSuccessor:  "/api/v1/todos",
+		},
+		{
+			name:               "Legacy route deletes item with OK and deprecation headers",
+			method:             http.MethodDelete,
+			path:               "/todos/1",
+			expectedStatusCode: http.StatusOK,
+			expectedSuccessor:  "/api/v1/todos/1",
+		},
+		{
+			name:               "Legacy route isn't served when disabled",
+			method:             http.MethodDelete,
+			path:               "/todos/1",
+			withoutLegacy:      true,
+			expectedStatusCode: http.StatusNotFound,
+			expectedProblem:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := gin.New()
+			Route(r, NewTodoHandler(&mockRepo{}), Options{IdempotencyWindow: time.Hour, LegacyRoutes: !test.withoutLegacy})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(test.method, test.path, strings.NewReader(test.requestBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedLocation, w.Header().Get("Location"))
+			assert.NotEmpty(t, w.Header().Get(requestIDHeader))
+			if test.expectedProblem {
+				assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			}
+			if test.expectedStatusCode == http.StatusNoContent {
+				assert.Empty(t, w.Body.String())
+			}
+			if test.expectedSuccessor == "" {
+				assert.Empty(t, w.Header().Get("Deprecation"))
+				return
+			}
+			assert.Equal(t, "@1792281600", w.Header().Get("Deprecation"))
+			assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+			assert.Equal(t, `<`+test.expectedSuccessor+`>; rel="successor-version"`, w.Header().Get("Link"))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetTags processes request for getting all tags with numbers of tagged items.
+func (h TodoHandler) GetTags(c *gin.Context) {
+	tags, err := h.repo.GetTags(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err, "Failed getting tags")
+		return
+	}
+	if len(tags) == 0 {
+		_ = c.Error(models.NotFoundError("No tags found", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": tags})
+}
+
+// GetTag processes request for getting single tag by given id from params.
+func (h TodoHandler) GetTag(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	tag, err := h.repo.GetTag(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err, "Failed getting tag")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retrieved tag", "tag": tag})
+}
+
+// AddTag processes request for creating tag. Tags are also created implicitly, when items are tagged.
+func (h TodoHandler) AddTag(c *gin.Context) {
+	tag, err := models.TagFromJson(readRequestBody(c))
+	if err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	tag, err = h.repo.CreateTag(c.Request.Context(), &tag)
+	if err != nil {
+		respondWithError(c, err, "Failed creating tag")
+		return
+	}
+	respondCreated(c, fmt.Sprintf("%s/tags/%d", apiV1Prefix, tag.ID), gin.H{"message": "Tag added", "tag": tag})
+}
+
+// UpdateTag processes request for renaming tag and/or changing its color.
+func (h TodoHandler) UpdateTag(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	tag, err := models.TagFromJson(readRequestBody(c))
+	if err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	tag, err = h.repo.UpdateTag(c.Request.Context(), &tag, id)
+	if err != nil {
+		respondWithError(c, err, "Failed updating tag")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Updated tag", "tag": tag})
+}
+
+// DeleteTag processes request for deleting tag, tagged items lose the tag.
+func (h TodoHandler) DeleteTag(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteTag(c.Request.Context(), id, actor); err != nil {
+		respondWithError(c, err, "Failed deleting tag")
+		return
+	}
+	respondDeleted(c, gin.H{"message": "Tag deleted", "ID": id})
+}
+
+// MergeTags processes request for merging tag from params into tag given as "into" in JSON body.
+// Items of the merged tag get the target tag, merged tag is deleted.
+func (h TodoHandler) MergeTags(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Into int64 `json:"into"`
+	}
+	if err := json.Unmarshal(readRequestBody(c), &body); err != nil {
+		invalidJSON(c, err)
+		return
+	}
+	if body.Into < 1 {
+		invalidField(c, "into", "must be positive integer")
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	tag, err := h.repo.MergeTags(c.Request.Context(), id, body.Into, actor)
+	if err != nil {
+		respondWithError(c, err, "Failed merging tags")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tags merged", "tag": tag})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAddTag covers all possible cases of adding tag with respective return statuses.
+func TestAddTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestBody        string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "CreateTag returns BadRequest",
+			requestBody:        `{"name"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "CreateTag returns Conflict",
+			requestBody:        `{"name": "work"}`,
+			mockError:          models.ConflictError("Tag work already exists", nil),
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "CreateTag returns OK",
+			requestBody:        `{"name": "work", "color": "#00ff00"}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/tags", strings.NewReader(test.requestBody))
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError})
+
+			serve(c, h.AddTag)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+// TestMergeTags covers all possible cases of merging tags with respective return statuses.
+func TestMergeTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		requestBody        string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "MergeTags returns BadRequest for invalid id",
+			requestParam:       "-1",
+			requestBody:        `{"into": 2}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "MergeTags returns BadRequest without target",
+			requestParam:       "1",
+			requestBody:        `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "MergeTags returns NotFound",
+			requestParam:       "1",
+			requestBody:        `{"into": 2}`,
+			mockError:          models.NotFoundError("Unable to find tag with id 2", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "MergeTags returns InternalServerError",
+			requestParam:       "1",
+			requestBody:        `{"into": 2}`,
+			mockError:          errors.New("something went wrong"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "MergeTags returns OK",
+			requestParam:       "1",
+			requestBody:        `{"into": 2}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/tags/"+test.requestParam+"/merge", strings.NewReader(test.requestBody))
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError})
+
+			serve(c, h.MergeTags)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetTrash processes request for getting deleted items, most recently deleted first.
+func (h TodoHandler) GetTrash(c *gin.Context) {
+	items, err := h.repo.GetTrash(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err, "Failed getting trash")
+		return
+	}
+	if len(items) == 0 {
+		_ = c.Error(models.NotFoundError("Trash is empty", nil))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Got them all", "items": items})
+}
+
+// RestoreToDo processes request for moving item by given id from params out of trash.
+func (h TodoHandler) RestoreToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	item, err := h.repo.RestoreToDo(c.Request.Context(), id, actor)
+	if err != nil {
+		respondWithError(c, err, "Failed restoring To-Do item")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Item restored", "item": item})
+}
+
+// PurgeToDo processes request for permanent deleting of item by given id from params from trash.
+func (h TodoHandler) PurgeToDo(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	actor, ok := requestActor(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.PurgeToDo(c.Request.Context(), id, actor); err != nil {
+		respondWithError(c, err, "Failed deleting To-Do item")
+		return
+	}
+	respondDeleted(c, gin.H{"message": "Item deleted permanently", "ID": id})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"LazyToDo/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTrash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deletedAt := int64(1700000000)
+	tests := []struct {
+		name               string
+		mockReturn         models.ToDo
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "GetTrash returns NotFound for empty trash",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "GetTrash returns InternalServerError",
+			mockError:          models.InternalError("Unable to get trash", nil),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "GetTrash returns OK",
+			mockReturn:         models.ToDo{ID: 1, DeletedAt: &deletedAt},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/trash", nil)
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: test.mockReturn})
+
+			serve(c, h.GetTrash)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestRestoreToDo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "RestoreToDo returns BadRequest for invalid id",
+			requestParam:       "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "RestoreToDo returns NotFound for item outside of trash",
+			requestParam:       "1",
+			mockError:          models.NotFoundError("Unable to find item with id 1 in trash", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "RestoreToDo returns OK",
+			requestParam:       "1",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/trash/"+test.requestParam+"/restore", nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError, ReturnValue: models.ToDo{ID: 1}})
+
+			serve(c, h.RestoreToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestPurgeToDo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestParam       string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "PurgeToDo returns BadRequest for invalid id",
+			requestParam:       "0",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "PurgeToDo returns NotFound for item outside of trash",
+			requestParam:       "1",
+			mockError:          models.NotFoundError("Unable to find item with id 1 in trash", nil),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "PurgeToDo returns OK",
+			requestParam:       "1",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodDelete, "/trash/"+test.requestParam, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "id", Value: test.requestParam},
+			}
+
+			h := NewTodoHandler(&mockRepo{Error: test.mockError})
+
+			serve(c, h.PurgeToDo)
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetWorkflow processes request for getting statuses of to-do items and transitions allowed between them.
+func (h TodoHandler) GetWorkflow(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "Retrieved workflow", "workflow": h.repo.Workflow()})
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Operations of bulk requests.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// Modes of bulk requests.
+const (
+	// BulkAtomic executes all operations or none of them.
+	BulkAtomic = "atomic"
+	// BulkBestEffort executes every operation, that succeeds, failed ones don't change anything.
+	BulkBestEffort = "best_effort"
+)
+
+// MaxBulkOperations limits number of operations in single bulk request.
+const MaxBulkOperations = 1000
+
+// BulkRequest is list of operations executed in order within single transaction.
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is single operation of bulk request. Create has Item, update has ID and either Item
+// (full replacement like PUT) or Patch (JSON Merge Patch like PATCH), delete has ID and Subtasks policy.
+// Version and Force of update work the same way as If-Match and force of single item updates.
+// Violations are failures of validation of Item (see ParseToDoRequest), such operation is never executed.
+type BulkOperation struct {
+	Op         string        `json:"op"`
+	ID         int64         `json:"id,omitempty"`
+	Item       *ToDo         `json:"item,omitempty"`
+	Patch      MergePatch    `json:"patch,omitempty"`
+	Version    int64         `json:"version,omitempty"`
+	Force      bool          `json:"force,omitempty"`
+	Subtasks   SubtaskPolicy `json:"subtasks,omitempty"`
+	Violations Violations    `json:"-"`
+}
+
+// bulkOperationBody is operation as sent by clients, its item is parsed as body of item request.
+type bulkOperationBody struct {
+	BulkOperation
+	Item json.RawMessage `json:"item"`
+}
+
+// BulkResult is outcome of single operation: HTTP status code and resulting item or code and message of error.
+// Errors are violations of validation rules by the item of failed operation.
+type BulkResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Item   *ToDo        `json:"item,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// ParseBulkRequest reads bulk request and validates its operations. Blank mode means BulkAtomic.
+// Items are parsed like bodies of POST (create) and PUT (update) requests with the workflow: malformed ones fail
+// the whole request, ones breaking validation rules have their Violations set.
+func ParseBulkRequest(data []byte, workflow Workflow) (BulkRequest, error) {
+	var body struct {
+		Mode       string              `json:"mode"`
+		Operations []bulkOperationBody `json:"operations"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return BulkRequest{}, err
+	}
+	request := BulkRequest{Mode: body.Mode}
+	for i, operation := range body.Operations {
+		op := operation.BulkOperation
+		if len(operation.Item) != 0 && string(operation.Item) != "null" {
+			item, err := ParseToDoRequest(operation.Item, workflow, op.Op == BulkCreate)
+			var violations Violations
+			if errors.As(err, &violations) {
+				op.Violations = violations
+			} else if err != nil {
+				return BulkRequest{}, fmt.Errorf("operation %d: item: %w", i, err)
+			}
+			op.Item = &item
+		}
+		request.Operations = append(request.Operations, op)
+	}
+	switch request.Mode {
+	case "":
+		request.Mode = BulkAtomic
+	case BulkAtomic, BulkBestEffort:
+	default:
+		return BulkRequest{}, fmt.Errorf("invalid mode: %s", request.Mode)
+	}
+	if len(request.Operations) == 0 {
+		return BulkRequest{}, errors.New("operations are required")
+	}
+	if len(request.Operations) > MaxBulkOperations {
+		return BulkRequest{}, fmt.Errorf("too many operations, up to %d are allowed", MaxBulkOperations)
+	}
+	for i := range request.Operations {
+		if err := request.Operations[i].validate(); err != nil {
+			return BulkRequest{}, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return request, nil
+}
+
+func (o *BulkOperation) validate() error {
+	switch o.Op {
+	case BulkCreate:
+		if o.Item == nil {
+			return errors.New("item is required for create")
+		}
+		if o.ID != 0 || o.Patch != nil {
+			return errors.New("create accepts only item")
+		}
+		return nil
+	case BulkUpdate:
+		if (o.Item == nil) == (o.Patch == nil) {
+			return errors.New("update requires either item or patch")
+		}
+	case BulkDelete:
+		if o.Item != nil || o.Patch != nil {
+			return errors.New("delete accepts only id and subtasks")
+		}
+		policy, err := ParseSubtaskPolicy(string(o.Subtasks))
+		if err != nil {
+			return err
+		}
+		o.Subtasks = policy
+	default:
+		return fmt.Errorf("unknown op %q", o.Op)
+	}
+	if o.ID < 1 {
+		return fmt.Errorf("invalid id: %d", o.ID)
+	}
+	return nil
+}
+
+// Split separates operations with invalid items, which are never executed, from the others. Returns request with valid
+// operations only, indexes of these operations in r and results of invalid ones: 400 listing their violations.
+func (r BulkRequest) Split() (BulkRequest, []int, []BulkResult) {
+	valid := BulkRequest{Mode: r.Mode}
+	var indexes []int
+	var rejected []BulkResult
+	for i, op := range r.Operations {
+		if len(op.Violations) == 0 {
+			valid.Operations = append(valid.Operations, op)
+			indexes = append(indexes, i)
+			continue
+		}
+		err := ValidationError("Invalid item: "+op.Violations.Error(), op.Violations)
+		rejected = append(rejected, BulkResult{Index: i, Status: err.Status(), Code: err.Code(), Error: err.Error(), Errors: op.Violations})
+	}
+	return valid, indexes, rejected
+}
+
+// NotApplied returns result of operation of atomic request, which is rolled back or not executed, as another one failed.
+func NotApplied(index, failed int) BulkResult {
+	return BulkResult{
+		Index:  index,
+		Status: http.StatusFailedDependency,
+		Code:   CodeNotApplied,
+		Error:  fmt.Sprintf("Not applied, operation %d failed", failed),
+	}
+}
+
+// Atomic tells if all operations of the request must succeed.
+func (r BulkRequest) Atomic() bool {
+	return r.Mode != BulkBestEffort
+}
+
+// Change returns patch applied by update operation.
+func (o BulkOperation) Change() ToDoPatch {
+	if o.Item != nil {
+		return Replacement(*o.Item)
+	}
+	return o.Patch
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBulkRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectError bool
+	}{
+		{name: "atomic by default", body: `{"operations": [{"op": "create", "item": {"description": "Write"}}]}`},
+		{name: "all operations", body: `{"mode": "best_effort", "operations": [
+			{"op": "create", "item": {"description": "Write"}},
+			{"op": "update", "id": 1, "item": {"description": "Read", "status": "DONE"}},
+			{"op": "update", "id": 1, "patch": {"due_at": null}, "version": 2, "force": true},
+			{"op": "delete", "id": 2, "subtasks": "Cascade"}
+		]}`},
+		{name: "invalid mode", body: `{"mode": "eventually", "operations": [{"op": "delete", "id": 1}]}`, expectError: true},
+		{name: "no operations", body: `{"operations": []}`, expectError: true},
+		{name: "unknown op", body: `{"operations": [{"op": "rename", "id": 1}]}`, expectError: true},
+		{name: "create without item", body: `{"operations": [{"op": "create"}]}`, expectError: true},
+		{name: "create with id", body: `{"operations": [{"op": "create", "id": 1, "item": {}}]}`, expectError: true},
+		{name: "update without id", body: `{"operations": [{"op": "update", "patch": {}}]}`, expectError: true},
+		{name: "update with item and patch", body: `{"operations": [{"op": "update", "id": 1, "item": {}, "patch": {}}]}`, expectError: true},
+		{name: "delete with invalid policy", body: `{"operations": [{"op": "delete", "id": 1, "subtasks": "orphan"}]}`, expectError: true},
+		{name: "invalid JSON", body: `{"operations": `, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := ParseBulkRequest([]byte(test.body), DefaultWorkflow())
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, request.Mode)
+			for _, op := range request.Operations {
+				if op.Op == BulkDelete {
+					assert.Equal(t, SubtasksCascade, op.Subtasks)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkOperationChange(t *testing.T) {
+	item := ToDo{Description: "Write", Status: DefaultStatus, Tags: []string{"work"}}
+	replaced, err := BulkOperation{Op: BulkUpdate, ID: 1, Item: &ToDo{Description: "Read", Status: StatusDone}}.Change().Apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, ToDo{Description: "Read", Status: StatusDone}, replaced)
+	patched, err := BulkOperation{Op: BulkUpdate, ID: 1, Patch: MergePatch{"status": StatusDone}}.Change().Apply(item)
+	require.NoError(t, err)
+	assert.Equal(t, ToDo{Description: "Write", Status: StatusDone, Tags: []string{"work"}}, patched)
+}
+
+func TestParseBulkRequestItems(t *testing.T) {
+	request, err := ParseBulkRequest([]byte(`{"operations": [
+		{"op": "create", "item": {"description": "Write", "tags": ["Work"]}},
+		{"op": "create", "item": {"id": 7, "description": "Copy", "version": 3}},
+		{"op": "update", "id": 1, "item": {"description": "Read"}},
+		{"op": "update", "id": 1, "item": {"description": "Read", "status": "DONE"}}
+	]}`), DefaultWorkflow())
+	require.NoError(t, err)
+	assert.Equal(t, &ToDo{Description: "Write", Tags: []string{"Work"}}, request.Operations[0].Item)
+	assert.Empty(t, request.Operations[0].Violations)
+	assert.Equal(t, Violations{{Field: "id", Message: "is unknown field"}, {Field: "version", Message: "is unknown field"}},
+		request.Operations[1].Violations)
+	assert.Equal(t, Violations{{Field: "status", Message: "is required"}}, request.Operations[2].Violations)
+	assert.Empty(t, request.Operations[3].Violations)
+
+	valid, indexes, rejected := request.Split()
+	assert.Equal(t, []BulkOperation{request.Operations[0], request.Operations[3]}, valid.Operations)
+	assert.Equal(t, []int{0, 3}, indexes)
+	if assert.Len(t, rejected, 2) {
+		assert.Equal(t, 1, rejected[0].Index)
+		assert.Equal(t, 2, rejected[1].Index)
+		assert.Equal(t, 400, rejected[1].Status)
+		assert.Equal(t, []FieldError{{Field: "status", Message: "is required"}}, rejected[1].Errors)
+	}
+
+	_, err = ParseBulkRequest([]byte(`{"operations": [{"op": "create", "item": "Write"}]}`), DefaultWorkflow())
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+package models
+
+// Dependencies of to-do item: items blocking it and items it blocks.
+// Item is blocked while any of its blockers isn't in terminal status of the workflow.
+type Dependencies struct {
+	BlockedBy []ToDo `json:"blocked_by"`
+	Blocks    []ToDo `json:"blocks"`
+}
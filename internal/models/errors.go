@@ -1,28 +1,147 @@
 package models
 
-// DBError defines custom error for specific handling and chaining.
-type DBError struct {
+import "net/http"
+
+// ErrorKind classifies application errors. Kind selects HTTP status of the error and its default code.
+type ErrorKind int
+
+const (
+	// KindInternal is unexpected failure, its cause is never shown to clients.
+	KindInternal ErrorKind = iota
+	// KindValidation is malformed request: invalid JSON, fields or parameters.
+	KindValidation
+	// KindNotFound is missing resource.
+	KindNotFound
+	// KindConflict is request conflicting with current state of resources.
+	KindConflict
+	// KindPreconditionFailed is conditional request, whose condition doesn't hold.
+	KindPreconditionFailed
+	// KindUnprocessable is well-formed request, that can't be applied, e.g. status change the workflow doesn't allow.
+	KindUnprocessable
+	// KindUnsupportedMediaType is request body of media type the endpoint doesn't accept.
+	KindUnsupportedMediaType
+)
+
+// kinds maps kinds of errors to their HTTP statuses and default codes.
+var kinds = map[ErrorKind]struct {
+	status int
+	code   string
+}{
+	KindInternal:             {http.StatusInternalServerError, "internal_error"},
+	KindValidation:           {http.StatusBadRequest, "validation_failed"},
+	KindNotFound:             {http.StatusNotFound, "not_found"},
+	KindConflict:             {http.StatusConflict, "conflict"},
+	KindPreconditionFailed:   {http.StatusPreconditionFailed, "precondition_failed"},
+	KindUnprocessable:        {http.StatusUnprocessableEntity, "unprocessable"},
+	KindUnsupportedMediaType: {http.StatusUnsupportedMediaType, "unsupported_media_type"},
+}
+
+// Stable codes of errors, which clients may need to tell apart from others of the same kind.
+const (
+	CodeVersionMismatch      = "version_mismatch"
+	CodeConcurrentUpdate     = "concurrent_update"
+	CodeHasSubtasks          = "has_subtasks"
+	CodeBlocked              = "blocked"
+	CodeDependencyCycle      = "dependency_cycle"
+	CodeTagExists            = "tag_exists"
+	CodePatchTestFailed      = "patch_test_failed"
+	CodeTransitionNotAllowed = "transition_not_allowed"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeRequestInProgress    = "request_in_progress"
+	CodeNotApplied           = "not_applied"
+)
+
+// FieldError is validation failure of single field of request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AppError is error of the application with message safe to show to clients. Its cause (e.g. driver error)
+// is kept for logging and errors.Is/As only.
+type AppError struct {
+	kind    ErrorKind
+	code    string
 	message string
-	code    int
+	fields  []FieldError
 	err     error
 }
 
-func NewDBError(msg string, code int, err error) *DBError {
-	return &DBError{
-		message: msg,
-		code:    code,
-		err:     err,
-	}
+func newAppError(kind ErrorKind, message string, err error) *AppError {
+	return &AppError{kind: kind, code: kinds[kind].code, message: message, err: err}
+}
+
+// InternalError reports unexpected failure with message describing what failed.
+func InternalError(message string, err error) *AppError {
+	return newAppError(KindInternal, message, err)
+}
+
+// ValidationError reports malformed request, WithFields adds failures of single fields.
+func ValidationError(message string, err error) *AppError {
+	return newAppError(KindValidation, message, err)
+}
+
+// NotFoundError reports missing resource.
+func NotFoundError(message string, err error) *AppError {
+	return newAppError(KindNotFound, message, err)
+}
+
+// ConflictError reports request conflicting with current state of resources.
+func ConflictError(message string, err error) *AppError {
+	return newAppError(KindConflict, message, err)
 }
 
-func (e *DBError) Unwrap() error {
+// PreconditionFailedError reports conditional request, whose condition doesn't hold.
+func PreconditionFailedError(message string, err error) *AppError {
+	return newAppError(KindPreconditionFailed, message, err)
+}
+
+// UnprocessableError reports request, that is well-formed, but can't be applied.
+func UnprocessableError(message string, err error) *AppError {
+	return newAppError(KindUnprocessable, message, err)
+}
+
+// UnsupportedMediaTypeError reports request body of unsupported media type.
+func UnsupportedMediaTypeError(message string, err error) *AppError {
+	return newAppError(KindUnsupportedMediaType, message, err)
+}
+
+// WithCode replaces default code of the kind with more specific one.
+func (e *AppError) WithCode(code string) *AppError {
+	e.code = code
+	return e
+}
+
+// WithFields adds failures of single fields of request.
+func (e *AppError) WithFields(fields ...FieldError) *AppError {
+	e.fields = append(e.fields, fields...)
+	return e
+}
+
+func (e *AppError) Unwrap() error {
 	return e.err
 }
 
-func (e *DBError) Error() string {
+func (e *AppError) Error() string {
 	return e.message
 }
 
-func (e *DBError) Code() int {
+// Kind returns kind of the error.
+func (e *AppError) Kind() ErrorKind {
+	return e.kind
+}
+
+// Status returns HTTP status of the error.
+func (e *AppError) Status() int {
+	return kinds[e.kind].status
+}
+
+// Code returns stable machine-readable code of the error.
+func (e *AppError) Code() string {
 	return e.code
 }
+
+// Fields returns failures of single fields of request.
+func (e *AppError) Fields() []FieldError {
+	return e.fields
+}
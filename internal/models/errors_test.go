@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppError(t *testing.T) {
+	cause := errors.New("connection refused")
+	tests := []struct {
+		name           string
+		err            *AppError
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "internal", err: InternalError("Unable to get item", cause),
+			expectedStatus: http.StatusInternalServerError, expectedCode: "internal_error"},
+		{name: "validation", err: ValidationError("Invalid field title: too long", nil),
+			expectedStatus: http.StatusBadRequest, expectedCode: "validation_failed"},
+		{name: "not found", err: NotFoundError("Unable to find item with id 1", nil),
+			expectedStatus: http.StatusNotFound, expectedCode: "not_found"},
+		{name: "conflict with code", err: ConflictError("Item with id 1 has subtasks", nil).WithCode(CodeHasSubtasks),
+			expectedStatus: http.StatusConflict, expectedCode: CodeHasSubtasks},
+		{name: "precondition failed", err: PreconditionFailedError("Item with id 1 was changed", nil),
+			expectedStatus: http.StatusPreconditionFailed, expectedCode: "precondition_failed"},
+		{name: "unprocessable", err: UnprocessableError("Status archived is unknown", nil),
+			expectedStatus: http.StatusUnprocessableEntity, expectedCode: "unprocessable"},
+		{name: "unsupported media type", err: UnsupportedMediaTypeError("Unsupported patch", nil),
+			expectedStatus: http.StatusUnsupportedMediaType, expectedCode: "unsupported_media_type"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedStatus, test.err.Status())
+			assert.Equal(t, test.expectedCode, test.err.Code())
+		})
+	}
+
+	err := InternalError("Unable to get item", cause)
+	assert.Equal(t, "Unable to get item", err.Error())
+	assert.ErrorIs(t, err, cause)
+	field := FieldError{Field: "title", Message: "too long"}
+	assert.Equal(t, []FieldError{field}, ValidationError("Invalid field title: too long", nil).WithFields(field).Fields())
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EventAction is kind of change recorded in audit log.
+type EventAction string
+
+const (
+	EventCreated  EventAction = "created"
+	EventUpdated  EventAction = "updated"
+	EventDeleted  EventAction = "deleted"
+	EventRestored EventAction = "restored"
+	EventPurged   EventAction = "purged"
+)
+
+// Actors recorded when request doesn't name one and for changes made by the service itself.
+const (
+	AnonymousActor = "anonymous"
+	SystemActor    = "system"
+)
+
+// maxActorLength matches "actor" column of "todo_events".
+const maxActorLength = 255
+
+// Event is single change of to-do item in audit log. Events are never changed or deleted,
+// so history of an item is kept even after the item is purged from trash.
+type Event struct {
+	ID      int64         `json:"id"`
+	TodoID  int64         `json:"todo_id"`
+	Action  EventAction   `json:"action"`
+	Actor   string        `json:"actor"`
+	Changes []FieldChange `json:"changes"`
+	Created int64         `json:"created"`
+}
+
+// FieldChange is value of single field before and after the change, nil stands for missing value.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// EventFilter selects events of audit log. Zero fields don't filter, Since and Until are unix timestamps (inclusive).
+type EventFilter struct {
+	TodoID int64
+	Actor  string
+	Action EventAction
+	Since  *int64
+	Until  *int64
+	Limit  int
+	Offset int
+}
+
+// ParseEventAction parses action name, blank name is returned as is and matches any action.
+func ParseEventAction(value string) (EventAction, error) {
+	switch action := EventAction(strings.ToLower(strings.TrimSpace(value))); action {
+	case "", EventCreated, EventUpdated, EventDeleted, EventRestored, EventPurged:
+		return action, nil
+	}
+	return "", fmt.Errorf("invalid action: %s", value)
+}
+
+// NormalizeActor trims actor name, blank one is replaced with AnonymousActor.
+func NormalizeActor(actor string) (string, error) {
+	actor = strings.TrimSpace(actor)
+	if len(actor) == 0 {
+		return AnonymousActor, nil
+	}
+	if len(actor) > maxActorLength {
+		return "", fmt.Errorf("actor must be at most %d characters long", maxActorLength)
+	}
+	return actor, nil
+}
+
+// DiffToDo lists fields of to-do item, that differ between two versions. Diff with zero ToDo lists all set fields.
+// Computed fields (progress, blocked flag, timestamps of the item) aren't compared.
+func DiffToDo(before, after ToDo) []FieldChange {
+	fields := []FieldChange{
+		{"description", before.Description, after.Description},
+		{"status", before.Status, after.Status},
+		{"start_at", before.StartAt, after.StartAt},
+		{"due_at", before.DueAt, after.DueAt},
+		{"priority", before.Priority, after.Priority},
+		{"tags", before.Tags, after.Tags},
+		{"project_id", before.ProjectID, after.ProjectID},
+		{"parent_id", before.ParentID, after.ParentID},
+		{"recurrence", before.Recurrence, after.Recurrence},
+		{"deleted_at", before.DeletedAt, after.DeletedAt},
+	}
+	changes := []FieldChange{}
+	for _, field := range fields {
+		beforeValue, afterValue := fieldValue(field.Before), fieldValue(field.After)
+		if !sameJSON(beforeValue, afterValue) {
+			changes = append(changes, FieldChange{Field: field.Field, Before: beforeValue, After: afterValue})
+		}
+	}
+	return changes
+}
+
+// fieldValue converts field of to-do item for diff: blank strings, nil pointers and empty tags are missing values.
+func fieldValue(value any) any {
+	switch value := value.(type) {
+	case string:
+		if len(value) == 0 {
+			return nil
+		}
+	case []string:
+		if len(value) == 0 {
+			return nil
+		}
+	case *string:
+		if value == nil {
+			return nil
+		}
+		return *value
+	case *int64:
+		if value == nil {
+			return nil
+		}
+		return *value
+	case *Priority:
+		if value == nil {
+			return nil
+		}
+		return *value
+	}
+	return value
+}
+
+func sameJSON(a, b any) bool {
+	first, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	second, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(first, second)
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventAction(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    EventAction
+		expectError bool
+	}{
+		{value: "", expected: ""},
+		{value: "created", expected: EventCreated},
+		{value: " Deleted ", expected: EventDeleted},
+		{value: "purged", expected: EventPurged},
+		{value: "renamed", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			action, err := ParseEventAction(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, action)
+		})
+	}
+}
+
+func TestNormalizeActor(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{name: "blank", value: "  ", expected: AnonymousActor},
+		{name: "trimmed", value: " alice ", expected: "alice"},
+		{name: "too long", value: strings.Repeat("a", maxActorLength+1), expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actor, err := NormalizeActor(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actor)
+		})
+	}
+}
+
+func TestDiffToDo(t *testing.T) {
+	high := PriorityHigh
+	due := int64(1700000000)
+	projectID := int64(3)
+	tests := []struct {
+		name     string
+		before   ToDo
+		after    ToDo
+		expected []FieldChange
+	}{
+		{
+			name:     "same items",
+			before:   ToDo{ID: 1, Description: "Write", Tags: []string{"work"}, Created: 1},
+			after:    ToDo{ID: 1, Description: "Write", Tags: []string{"work"}, Created: 1, Updated: 2, Blocked: true},
+			expected: []FieldChange{},
+		},
+		{
+			name:     "missing values are equal",
+			before:   ToDo{Tags: []string{}},
+			after:    ToDo{},
+			expected: []FieldChange{},
+		},
+		{
+			name:   "created item",
+			before: ToDo{},
+			after:  ToDo{Description: "Write", Status: "TODO", Priority: &high},
+			expected: []FieldChange{
+				{Field: "description", After: "Write"},
+				{Field: "status", After: "TODO"},
+				{Field: "priority", After: high},
+			},
+		},
+		{
+			name:   "changed fields",
+			before: ToDo{Description: "Write", Tags: []string{"work"}, ProjectID: &projectID},
+			after:  ToDo{Description: "Write", Tags: []string{"home", "work"}, DueAt: &due},
+			expected: []FieldChange{
+				{Field: "due_at", After: due},
+				{Field: "tags", Before: []string{"work"}, After: []string{"home", "work"}},
+				{Field: "project_id", Before: projectID},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, DiffToDo(test.before, test.after))
+		})
+	}
+}
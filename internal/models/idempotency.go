@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxIdempotencyKeyLength matches "key" column of "idempotency_keys".
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyRecord is request made with Idempotency-Key header and its response, which is replayed
+// for retries of the request. Zero Status means the request is still being processed.
+type IdempotencyRecord struct {
+	Key string
+	// RequestHash identifies the request, the key can't be reused for a different one.
+	RequestHash string
+	Status      int
+	ContentType string
+	// Headers of the response replayed with it, like Location and ETag.
+	Headers map[string]string
+	Body    []byte
+	Created int64
+}
+
+// Completed tells if response of the request is stored.
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+// ParseIdempotencyKey validates value of Idempotency-Key header, surrounding spaces are trimmed.
+func ParseIdempotencyKey(value string) (string, error) {
+	key := strings.TrimSpace(value)
+	if len(key) == 0 {
+		return "", errors.New("idempotency key must not be blank")
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("idempotency key must be at most %d characters long", maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
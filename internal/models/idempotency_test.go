@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{name: "trimmed", value: " 5f1c-42 ", expected: "5f1c-42"},
+		{name: "blank", value: "  ", expectError: true},
+		{name: "too long", value: strings.Repeat("a", maxIdempotencyKeyLength+1), expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := ParseIdempotencyKey(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, key)
+		})
+	}
+}
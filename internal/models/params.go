@@ -1,15 +1,123 @@
 package models
 
-// SortParams handle sorting.
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortParams handle sorting. Empty Field means default ordering: priority DESC, due date ASC, id ASC.
 type SortParams struct {
 	Field string
 	ASC   bool
 }
 
-// Filter defines filter structure. Operators are not yet supported.
+// Operator defines how filter value is compared with the field.
+type Operator string
+
+// Supported filter operators.
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpIn   Operator = "in"
+	OpLike Operator = "like"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+)
+
+var operators = map[Operator]bool{
+	OpEq: true, OpNe: true, OpIn: true, OpLike: true,
+	OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+}
+
+// Filter defines filter structure. Empty operator means OpEq.
+// Value is used by all operators except OpIn, which uses Values.
+// OpLike matches items, whose field contains Value (case-insensitive).
 type Filter struct {
-	Field string
-	Value string
+	Field    string
+	Operator Operator
+	Value    string
+	Values   []string
+}
+
+// ParseFilters parses filter expression: comma separated list of "field:operator:value".
+// Values of "in" operator are separated with "|", e.g. "updated:gte:1700000000,status:in:TO DO|DONE".
+// "\", "," and "|" are taken literally in values when escaped with "\", e.g. "description:like:milk\, eggs";
+// any other escape is rejected. Fields are not validated here, it's up to the repository.
+func ParseFilters(expression string) ([]Filter, error) {
+	var filters []Filter
+	if len(strings.TrimSpace(expression)) == 0 {
+		return filters, nil
+	}
+	for _, part := range splitUnescaped(expression, ',') {
+		tokens := strings.SplitN(part, ":", 3)
+		if len(tokens) != 3 || len(strings.TrimSpace(tokens[0])) == 0 {
+			return nil, fmt.Errorf("invalid filter %q, expected field:operator:value", part)
+		}
+		filter := Filter{Field: strings.TrimSpace(tokens[0]), Operator: Operator(strings.ToLower(tokens[1]))}
+		if !operators[filter.Operator] {
+			return nil, fmt.Errorf("unknown filter operator %q", tokens[1])
+		}
+		if filter.Operator == OpIn {
+			for _, value := range splitUnescaped(tokens[2], '|') {
+				value, err := unescapeFilterValue(value)
+				if err != nil {
+					return nil, err
+				}
+				filter.Values = append(filter.Values, value)
+			}
+		} else {
+			value, err := unescapeFilterValue(tokens[2])
+			if err != nil {
+				return nil, err
+			}
+			filter.Value = value
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// splitUnescaped splits s around separators not escaped with "\". Escapes are kept, see unescapeFilterValue.
+func splitUnescaped(s string, separator byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case separator:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeFilterValue removes "\" before escaped characters of filter value.
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			unescaped.WriteByte(value[i])
+			continue
+		}
+		i++
+		if i == len(value) || !strings.ContainsRune(`\,|`, rune(value[i])) {
+			return "", fmt.Errorf(`invalid escape in filter value %q, only "\", "\," and "\|" can be escaped`, value)
+		}
+		unescaped.WriteByte(value[i])
+	}
+	return unescaped.String(), nil
 }
 
 // FilterParams contains all filters.
@@ -17,15 +125,86 @@ type FilterParams struct {
 	Filters []Filter
 }
 
-// PaginationParams include limit (items per page) and offset (how many items to skip).
+// PaginationParams include limit (items per page) and either offset (how many items to skip)
+// or cursor (item after which the page starts).
 type PaginationParams struct {
 	Limit  int
 	Offset int
+	Cursor *Cursor
+}
+
+// Cursor points at the item, after which (or before which, if Backward) the page starts.
+// It's bound to sorting: Field and ASC must match sort params of the request, empty Field means default ordering.
+// Values are the item's values of sort keys preceding id.
+type Cursor struct {
+	Field    string   `json:"f,omitempty"`
+	ASC      bool     `json:"a"`
+	Values   []string `json:"v,omitempty"`
+	ID       int64    `json:"i"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// Encode returns opaque string representation of the cursor.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses string produced by Cursor.Encode.
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+		return nil, errors.New("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+// Due date shortcuts.
+const (
+	DueOverdue  = "overdue"
+	DueToday    = "today"
+	DueThisWeek = "this_week"
+)
+
+// DueFilters translates due date shortcut into filters on due_at.
+// Day and week boundaries are computed in location of now, weeks start on Monday.
+// Overdue items are only the open ones, items in terminal statuses of workflow aren't overdue.
+func DueFilters(shortcut string, now time.Time, workflow Workflow) ([]Filter, error) {
+	var from, to time.Time
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch shortcut {
+	case DueOverdue:
+		return []Filter{
+			{Field: "due_at", Operator: OpLt, Value: strconv.FormatInt(now.Unix(), 10)},
+			{Field: "status", Operator: OpIn, Values: workflow.Open()},
+		}, nil
+	case DueToday:
+		from, to = startOfDay, startOfDay.AddDate(0, 0, 1)
+	case DueThisWeek:
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		from = startOfDay.AddDate(0, 0, -daysSinceMonday)
+		to = from.AddDate(0, 0, 7)
+	default:
+		return nil, fmt.Errorf("unknown due shortcut %q, expected one of: %s, %s, %s", shortcut, DueOverdue, DueToday, DueThisWeek)
+	}
+	return []Filter{
+		{Field: "due_at", Operator: OpGte, Value: strconv.FormatInt(from.Unix(), 10)},
+		{Field: "due_at", Operator: OpLt, Value: strconv.FormatInt(to.Unix(), 10)},
+	}, nil
 }
 
 // ParamsBag is used for query parameters handling.
+// Search is full-text query, all its words must be present in description.
+// Blocked selects only blocked (true) or only actionable (false) items, nil means both.
 type ParamsBag struct {
-	Sort   SortParams
-	Filter FilterParams
-	Paging PaginationParams
+	Sort    SortParams
+	Filter  FilterParams
+	Paging  PaginationParams
+	Search  string
+	Tags    TagFilter
+	Blocked *bool
 }
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseFilters covers filter expression grammar.
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		expression  string
+		expected    []Filter
+		expectError bool
+	}{
+		{
+			name:       "Empty expression",
+			expression: "",
+		},
+		{
+			name:       "Multiple filters",
+			expression: "updated:gte:1700000000,status:in:TO DO|DONE",
+			expected: []Filter{
+				{Field: "updated", Operator: OpGte, Value: "1700000000"},
+				{Field: "status", Operator: OpIn, Values: []string{"TO DO", "DONE"}},
+			},
+		},
+		{
+			name:       "Value with colon",
+			expression: "description:LIKE:note: call",
+			expected:   []Filter{{Field: "description", Operator: OpLike, Value: "note: call"}},
+		},
+		{
+			name:       "Escaped separators",
+			expression: `description:like:milk\, eggs,status:in:A\|B|C\\,id:gt:1`,
+			expected: []Filter{
+				{Field: "description", Operator: OpLike, Value: "milk, eggs"},
+				{Field: "status", Operator: OpIn, Values: []string{"A|B", `C\`}},
+				{Field: "id", Operator: OpGt, Value: "1"},
+			},
+		},
+		{
+			name:        "Unknown escape",
+			expression:  `description:like:C:\Users`,
+			expectError: true,
+		},
+		{
+			name:        "Trailing backslash",
+			expression:  `description:like:milk\`,
+			expectError: true,
+		},
+		{
+			name:        "Missing value",
+			expression:  "status:eq",
+			expectError: true,
+		},
+		{
+			name:        "Unknown operator",
+			expression:  "status:regex:.*",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters, err := ParseFilters(test.expression)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, filters)
+		})
+	}
+}
+
+// TestDueFilters checks day and week boundaries in caller's timezone.
+func TestDueFilters(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Kyiv")
+	assert.NoError(t, err)
+	// Wednesday.
+	now := time.Date(2024, time.March, 13, 15, 30, 0, 0, location)
+	unix := func(year int, month time.Month, day int) string {
+		return strconv.FormatInt(time.Date(year, month, day, 0, 0, 0, 0, location).Unix(), 10)
+	}
+
+	workflow := DefaultWorkflow()
+
+	// Finished items aren't overdue.
+	filters, err := DueFilters(DueOverdue, now, workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, []Filter{
+		{Field: "due_at", Operator: OpLt, Value: strconv.FormatInt(now.Unix(), 10)},
+		{Field: "status", Operator: OpIn, Values: []string{DefaultStatus, "IN PROGRESS"}},
+	}, filters)
+
+	filters, err = DueFilters(DueToday, now, workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, []Filter{
+		{Field: "due_at", Operator: OpGte, Value: unix(2024, time.March, 13)},
+		{Field: "due_at", Operator: OpLt, Value: unix(2024, time.March, 14)},
+	}, filters)
+
+	filters, err = DueFilters(DueThisWeek, now, workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, []Filter{
+		{Field: "due_at", Operator: OpGte, Value: unix(2024, time.March, 11)},
+		{Field: "due_at", Operator: OpLt, Value: unix(2024, time.March, 18)},
+	}, filters)
+
+	_, err = DueFilters("someday", now, workflow)
+	assert.Error(t, err)
+}
@@ -0,0 +1,476 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Media types of PATCH requests.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ErrPatchTest is returned when "test" operation of JSON Patch doesn't match the item.
+var ErrPatchTest = errors.New("test operation failed")
+
+// ToDoPatch changes fields of to-do item. It's applied to the current state of the item within the update,
+// so concurrent changes can't slip in between reading the item and writing the result.
+type ToDoPatch interface {
+	Apply(item ToDo) (ToDo, error)
+}
+
+// editableFields of ToDo can be changed by patches, missing ones are cleared. Other fields are read-only,
+// JSON Patch can still test them (e.g. "version").
+var editableFields = []string{"description", "status", "start_at", "due_at", "priority", "tags", "project_id", "parent_id", "recurrence"}
+
+// readOnlyFields of ToDo are computed or maintained by storage.
+var readOnlyFields = []string{"id", "created", "updated", "blocked", "deleted_at", "version", "progress", "children"}
+
+// Replacement replaces all editable fields of the item (PUT semantics), fields missing in it are cleared.
+type Replacement ToDo
+
+// Apply replaces fields of the item.
+func (r Replacement) Apply(item ToDo) (ToDo, error) {
+	item.Description, item.Status, item.StartAt, item.DueAt, item.Priority = r.Description, r.Status, r.StartAt, r.DueAt, r.Priority
+	item.Tags, item.ProjectID, item.ParentID, item.Recurrence = r.Tags, r.ProjectID, r.ParentID, r.Recurrence
+	return item, nil
+}
+
+// MergePatch is JSON Merge Patch (RFC 7396): members of the object replace fields of the item, null removes them.
+type MergePatch map[string]any
+
+// ParseMergePatch reads JSON Merge Patch, which must be an object.
+func ParseMergePatch(data []byte) (MergePatch, error) {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	patch, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("merge patch must be JSON object")
+	}
+	return patch, nil
+}
+
+// Apply merges the patch into the item.
+func (p MergePatch) Apply(item ToDo) (ToDo, error) {
+	return patchItem(item, func(doc any) (any, error) {
+		return mergePatch(doc, map[string]any(p)), nil
+	})
+}
+
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// JSONPatch is JSON Patch (RFC 6902): operations are applied in order and the patch fails as a whole,
+// if any of them fails.
+type JSONPatch []PatchOperation
+
+// PatchOperation is single operation of JSON Patch. Path and From are JSON Pointers (RFC 6901).
+type PatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+}
+
+// ParseJSONPatch reads JSON Patch and validates its operations.
+func ParseJSONPatch(data []byte) (JSONPatch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.New("json patch must be array of operations")
+	}
+	patch := make(JSONPatch, len(raw))
+	for i, fields := range raw {
+		operation := &patch[i]
+		if err := json.Unmarshal(fields["op"], &operation.Op); err != nil {
+			return nil, fmt.Errorf("operation %d: invalid op", i)
+		}
+		if err := json.Unmarshal(fields["path"], &operation.Path); err != nil {
+			return nil, fmt.Errorf("operation %d: invalid path", i)
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		switch operation.Op {
+		case "add", "replace", "test":
+			rawValue, ok := fields["value"]
+			if !ok {
+				return nil, fmt.Errorf("operation %d: value is required for %s", i, operation.Op)
+			}
+			value, err := decodeJSON(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: invalid value", i)
+			}
+			operation.Value = value
+		case "move", "copy":
+			if err := json.Unmarshal(fields["from"], &operation.From); err != nil {
+				return nil, fmt.Errorf("operation %d: from is required for %s", i, operation.Op)
+			}
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, operation.Op)
+		}
+	}
+	return patch, nil
+}
+
+// Apply applies operations of the patch to the item.
+func (p JSONPatch) Apply(item ToDo) (ToDo, error) {
+	return patchItem(item, func(doc any) (any, error) {
+		var err error
+		for i, operation := range p {
+			if doc, err = operation.apply(doc); err != nil {
+				return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+			}
+		}
+		return doc, nil
+	})
+}
+
+func (o PatchOperation) apply(doc any) (any, error) {
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "add":
+		return addValue(doc, path, deepCopy(o.Value))
+	case "remove":
+		return removeValue(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return deepCopy(o.Value), nil
+		}
+		return modify(doc, path, func(container any, key string) (any, error) {
+			switch container := container.(type) {
+			case map[string]any:
+				if _, ok := container[key]; !ok {
+					return nil, fmt.Errorf("path %s doesn't exist", o.Path)
+				}
+				container[key] = deepCopy(o.Value)
+				return container, nil
+			case []any:
+				index, err := arrayIndex(key, len(container)-1)
+				if err != nil {
+					return nil, err
+				}
+				container[index] = deepCopy(o.Value)
+				return container, nil
+			}
+			return nil, fmt.Errorf("path %s doesn't exist", o.Path)
+		})
+	case "move", "copy":
+		from, _ := parsePointer(o.From)
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "copy" {
+			return addValue(doc, path, deepCopy(value))
+		}
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, errors.New("value can't be moved into itself")
+		}
+		if doc, err = removeValue(doc, from); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "test":
+		value, err := getValue(doc, path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPatchTest, err)
+		}
+		if !equalJSON(value, o.Value) {
+			return nil, ErrPatchTest
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", o.Op)
+}
+
+// patchItem applies patch to JSON representation of the item. Read-only fields must stay the same,
+// editable fields are decoded the same way as request bodies.
+func patchItem(item ToDo, apply func(doc any) (any, error)) (ToDo, error) {
+	doc, err := itemDocument(item)
+	if err != nil {
+		return ToDo{}, err
+	}
+	patchedDoc, err := apply(doc)
+	if err != nil {
+		return ToDo{}, err
+	}
+	// Patches built in code may hold Go values, decode them the same way as parsed ones.
+	data, err := json.Marshal(patchedDoc)
+	if err != nil {
+		return ToDo{}, err
+	}
+	if patchedDoc, err = decodeJSON(data); err != nil {
+		return ToDo{}, err
+	}
+	patched, ok := patchedDoc.(map[string]any)
+	if !ok {
+		return ToDo{}, errors.New("patched item must be JSON object")
+	}
+	original, err := itemDocument(item)
+	if err != nil {
+		return ToDo{}, err
+	}
+	for key := range patched {
+		if !slices.Contains(editableFields, key) && !slices.Contains(readOnlyFields, key) {
+			return ToDo{}, fmt.Errorf("unknown field %s", key)
+		}
+	}
+	for _, key := range readOnlyFields {
+		if !equalJSON(original[key], patched[key]) {
+			return ToDo{}, fmt.Errorf("field %s is read-only", key)
+		}
+	}
+	editable := map[string]any{}
+	for _, key := range editableFields {
+		if value, ok := patched[key]; ok {
+			editable[key] = value
+		}
+	}
+	data, err = json.Marshal(editable)
+	if err != nil {
+		return ToDo{}, err
+	}
+	result := item
+	result.Description, result.Status, result.StartAt, result.DueAt, result.Priority = "", "", nil, nil, nil
+	result.Tags, result.ProjectID, result.ParentID, result.Recurrence = nil, nil, nil, nil
+	if err := json.Unmarshal(data, &result); err != nil {
+		return ToDo{}, fmt.Errorf("invalid patched item: %w", err)
+	}
+	return result, nil
+}
+
+// itemDocument returns generic JSON representation of the item, missing tags are an empty array.
+func itemDocument(item ToDo) (map[string]any, error) {
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.(map[string]any), nil
+}
+
+// decodeJSON decodes single JSON value keeping numbers exact.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// parsePointer splits JSON Pointer into unescaped reference tokens, empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses index of array element, which must not exceed max.
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %s", token)
+	}
+	return index, nil
+}
+
+func getValue(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %s doesn't exist", token)
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("member %s doesn't exist", token)
+		}
+	}
+	return doc, nil
+}
+
+// modify finds container of the last token of the path and replaces it with the one returned by fn.
+func modify(doc any, path []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := getValue(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = modify(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		index, _ := arrayIndex(path[0], len(node)-1)
+		node[index] = child
+	}
+	return doc, nil
+}
+
+func addValue(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, func(container any, key string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[key] = value
+			return container, nil
+		case []any:
+			if key == "-" {
+				return append(container, value), nil
+			}
+			index, err := arrayIndex(key, len(container))
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(container, index, value), nil
+		}
+		return nil, fmt.Errorf("member %s can't be added", key)
+	})
+}
+
+func removeValue(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("whole item can't be removed")
+	}
+	return modify(doc, path, func(container any, key string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			if _, ok := container[key]; !ok {
+				return nil, fmt.Errorf("member %s doesn't exist", key)
+			}
+			delete(container, key)
+			return container, nil
+		case []any:
+			index, err := arrayIndex(key, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(container, index, index+1), nil
+		}
+		return nil, fmt.Errorf("member %s doesn't exist", key)
+	})
+}
+
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, item := range value {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, item := range value {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// equalJSON compares decoded JSON values, numbers are equal if their values are (1 equals 1.0).
+func equalJSON(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		first, _, err := big.ParseFloat(a.String(), 10, 256, big.ToNearestEven)
+		if err != nil {
+			return false
+		}
+		second, _, err := big.ParseFloat(b.String(), 10, 256, big.ToNearestEven)
+		return err == nil && first.Cmp(second) == 0
+	}
+	return a == b
+}
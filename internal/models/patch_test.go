@@ -0,0 +1,144 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchedItem() ToDo {
+	high := PriorityHigh
+	due := int64(1700000000)
+	return ToDo{ID: 1, Description: "Write", Status: DefaultStatus, DueAt: &due, Priority: &high, Tags: []string{"home", "work"}, Version: 2}
+}
+
+func TestMergePatch(t *testing.T) {
+	low := PriorityLow
+	tests := []struct {
+		name        string
+		patch       string
+		expected    func(item *ToDo)
+		expectError bool
+	}{
+		{name: "empty patch", patch: `{}`, expected: func(item *ToDo) {}},
+		{
+			name:  "changed fields",
+			patch: `{"description": "Read", "priority": "low", "tags": ["books"]}`,
+			expected: func(item *ToDo) {
+				item.Description, item.Priority, item.Tags = "Read", &low, []string{"books"}
+			},
+		},
+		{name: "null removes field", patch: `{"due_at": null}`, expected: func(item *ToDo) { item.DueAt = nil }},
+		{name: "unchanged read-only field", patch: `{"id": 1, "version": 2.0}`, expected: func(item *ToDo) {}},
+		{name: "changed read-only field", patch: `{"version": 3}`, expectError: true},
+		{name: "unknown field", patch: `{"title": "Read"}`, expectError: true},
+		{name: "invalid value", patch: `{"due_at": "tomorrow"}`, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := ParseMergePatch([]byte(test.patch))
+			require.NoError(t, err)
+			patched, err := patch.Apply(patchedItem())
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expected := patchedItem()
+			test.expected(&expected)
+			assert.Equal(t, expected, patched)
+		})
+	}
+
+	_, err := ParseMergePatch([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       string
+		expected    func(item *ToDo)
+		expectError error
+	}{
+		{
+			name:     "add to end of array",
+			patch:    `[{"op": "add", "path": "/tags/-", "value": "urgent"}]`,
+			expected: func(item *ToDo) { item.Tags = []string{"home", "work", "urgent"} },
+		},
+		{
+			name:     "add into array",
+			patch:    `[{"op": "add", "path": "/tags/0", "value": "urgent"}]`,
+			expected: func(item *ToDo) { item.Tags = []string{"urgent", "home", "work"} },
+		},
+		{
+			name:     "remove and replace",
+			patch:    `[{"op": "remove", "path": "/tags/1"}, {"op": "replace", "path": "/status", "value": "DONE"}]`,
+			expected: func(item *ToDo) { item.Tags, item.Status = []string{"home"}, StatusDone },
+		},
+		{
+			name:     "move and copy",
+			patch:    `[{"op": "move", "from": "/due_at", "path": "/start_at"}, {"op": "copy", "from": "/tags/0", "path": "/description"}]`,
+			expected: func(item *ToDo) { item.StartAt, item.DueAt, item.Description = item.DueAt, nil, "home" },
+		},
+		{
+			name:     "passed test",
+			patch:    `[{"op": "test", "path": "/version", "value": 2}, {"op": "test", "path": "/tags", "value": ["home", "work"]}]`,
+			expected: func(item *ToDo) {},
+		},
+		{
+			name:        "failed test",
+			patch:       `[{"op": "test", "path": "/description", "value": "Read"}, {"op": "remove", "path": "/due_at"}]`,
+			expectError: ErrPatchTest,
+		},
+		{
+			name:        "test of empty field",
+			patch:       `[{"op": "test", "path": "/recurrence", "value": "FREQ=DAILY"}]`,
+			expectError: ErrPatchTest,
+		},
+		{name: "replace missing member", patch: `[{"op": "replace", "path": "/title", "value": "Read"}]`, expectError: assert.AnError},
+		{name: "index out of range", patch: `[{"op": "remove", "path": "/tags/2"}]`, expectError: assert.AnError},
+		{name: "read-only field", patch: `[{"op": "replace", "path": "/version", "value": 3}]`, expectError: assert.AnError},
+		{name: "escaped unknown field", patch: `[{"op": "add", "path": "/a~1b", "value": 1}]`, expectError: assert.AnError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := ParseJSONPatch([]byte(test.patch))
+			require.NoError(t, err)
+			patched, err := patch.Apply(patchedItem())
+			if test.expectError != nil {
+				require.Error(t, err)
+				if test.expectError == ErrPatchTest {
+					assert.ErrorIs(t, err, ErrPatchTest)
+				} else {
+					assert.NotErrorIs(t, err, ErrPatchTest)
+				}
+				return
+			}
+			require.NoError(t, err)
+			expected := patchedItem()
+			test.expected(&expected)
+			assert.Equal(t, expected, patched)
+		})
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{name: "not array", patch: `{"op": "remove", "path": "/tags"}`},
+		{name: "unknown op", patch: `[{"op": "rename", "path": "/tags"}]`},
+		{name: "missing value", patch: `[{"op": "add", "path": "/tags/-"}]`},
+		{name: "missing from", patch: `[{"op": "move", "path": "/tags"}]`},
+		{name: "invalid pointer", patch: `[{"op": "remove", "path": "tags"}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseJSONPatch([]byte(test.patch))
+			assert.Error(t, err)
+		})
+	}
+}
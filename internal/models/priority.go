@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Priority of to-do item. Stored as ordinal, the higher is more important.
+type Priority int16
+
+// Supported priorities.
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority accepts priority name (case-insensitive) or its ordinal.
+func ParsePriority(value string) (Priority, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for i, name := range priorityNames {
+		if value == name {
+			return Priority(i), nil
+		}
+	}
+	ordinal, err := strconv.Atoi(value)
+	if err != nil || ordinal < int(PriorityNone) || ordinal > int(PriorityUrgent) {
+		return PriorityNone, fmt.Errorf("invalid priority %q, expected one of: %s", value, strings.Join(priorityNames, ", "))
+	}
+	return Priority(ordinal), nil
+}
+
+// String returns priority name.
+func (p Priority) String() string {
+	if p < PriorityNone || p > PriorityUrgent {
+		return strconv.Itoa(int(p))
+	}
+	return priorityNames[p]
+}
+
+// MarshalJSON writes priority as its name.
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON reads priority name or ordinal.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParsePriority(fmt.Sprint(raw))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    Priority
+		expectError bool
+	}{
+		{value: "urgent", expected: PriorityUrgent},
+		{value: " Medium ", expected: PriorityMedium},
+		{value: "1", expected: PriorityLow},
+		{value: "0", expected: PriorityNone},
+		{value: "5", expectError: true},
+		{value: "asap", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			priority, err := ParsePriority(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, priority)
+		})
+	}
+}
+
+// TestPriorityJSON checks that priority is written as name and read from name or ordinal.
+func TestPriorityJSON(t *testing.T) {
+	var item ToDo
+	require.NoError(t, json.Unmarshal([]byte(`{"priority": 3}`), &item))
+	if assert.NotNil(t, item.Priority) {
+		assert.Equal(t, PriorityHigh, *item.Priority)
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"priority": "low"}`), &item))
+	assert.Equal(t, PriorityLow, *item.Priority)
+	assert.Error(t, json.Unmarshal([]byte(`{"priority": "asap"}`), &item))
+
+	data, err := json.Marshal(item)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"priority":"low"`)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// MaxProjectNameLength is maximum length of project name.
+const MaxProjectNameLength = 255
+
+// Project is a list of to-do items, e.g. of a team. Archived projects are hidden from the project list.
+type Project struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Color       string `json:"color,omitempty"`
+	Archived    bool   `json:"archived"`
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+	// Count is number of items in the project, items in trash aren't counted.
+	Count int64 `json:"count"`
+}
+
+// NormalizeProject trims project name and validates name and color. Blank name is allowed only for updates.
+func NormalizeProject(project *Project, update bool) error {
+	project.Name = strings.TrimSpace(project.Name)
+	if len(project.Name) == 0 && !update {
+		return errors.New("project name can't be blank")
+	}
+	if len(project.Name) > MaxProjectNameLength {
+		return errors.New("project name is too long")
+	}
+	color, err := NormalizeColor(project.Color)
+	if err != nil {
+		return err
+	}
+	project.Color = color
+	return nil
+}
+
+// ProjectFromJson creates Project object from JSON byte array.
+func ProjectFromJson(data []byte) (Project, error) {
+	var project Project
+	err := json.Unmarshal(data, &project)
+	return project, err
+}
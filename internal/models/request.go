@@ -0,0 +1,193 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDescriptionLength is maximum length of item description, the same as of its column.
+const MaxDescriptionLength = 255
+
+// MaxTimestamp is the last second of year 9999, dates of items must be between 0 and it.
+const MaxTimestamp = 253402300799
+
+// ToDoRequest is body of requests creating or replacing to-do item. It has editable fields of ToDo only,
+// fields set by server (id, created, version, ...) are rejected as any other unknown field.
+type ToDoRequest struct {
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	StartAt     *int64    `json:"start_at"`
+	DueAt       *int64    `json:"due_at"`
+	Priority    *Priority `json:"priority"`
+	Tags        []string  `json:"tags"`
+	ProjectID   *int64    `json:"project_id"`
+	ParentID    *int64    `json:"parent_id"`
+	Recurrence  *string   `json:"recurrence"`
+}
+
+// Violations are failures of validation rules of request fields, listed in order of the fields.
+type Violations []FieldError
+
+func (v Violations) Error() string {
+	messages := make([]string, len(v))
+	for i, violation := range v {
+		messages[i] = violation.Field + " " + violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// requestField is field of request body with JSON type it must have.
+type requestField struct {
+	name   string
+	target any
+	kind   string
+}
+
+func (r *ToDoRequest) fields() []requestField {
+	return []requestField{
+		{"description", &r.Description, "string"},
+		{"status", &r.Status, "string"},
+		{"start_at", &r.StartAt, "integer"},
+		{"due_at", &r.DueAt, "integer"},
+		{"priority", &r.Priority, "priority name or ordinal"},
+		{"tags", &r.Tags, "array of strings"},
+		{"project_id", &r.ProjectID, "integer"},
+		{"parent_id", &r.ParentID, "integer"},
+		{"recurrence", &r.Recurrence, "string"},
+	}
+}
+
+// ParseToDoRequest reads body of request creating (create is true) or replacing to-do item and validates it.
+// Status must be known to the workflow, it may be blank for new items only, they get the initial one.
+// Malformed JSON is returned as is, unknown fields, fields of wrong type and broken rules are all returned
+// together as Violations.
+func ParseToDoRequest(data []byte, workflow Workflow, create bool) (ToDo, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return ToDo{}, err
+	}
+	var request ToDoRequest
+	failed := make(map[string]string)
+	fields := request.fields()
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field.name] = true
+		raw, ok := document[field.name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, field.target); err != nil {
+			failed[field.name] = decodeMessage(field, err)
+		}
+	}
+	broken := make(map[string]Violations)
+	for _, violation := range request.ToDo().Validate(workflow, create) {
+		name, _, _ := strings.Cut(violation.Field, "[")
+		broken[name] = append(broken[name], violation)
+	}
+	var violations Violations
+	for _, field := range fields {
+		if message, ok := failed[field.name]; ok {
+			violations = append(violations, FieldError{Field: field.name, Message: message})
+			continue
+		}
+		violations = append(violations, broken[field.name]...)
+	}
+	var unknown []string
+	for name := range document {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		violations = append(violations, FieldError{Field: name, Message: "is unknown field"})
+	}
+	if len(violations) > 0 {
+		return ToDo{}, violations
+	}
+	return request.ToDo(), nil
+}
+
+// decodeMessage describes why value of the field can't be decoded.
+func decodeMessage(field requestField, err error) string {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return "must be " + field.kind
+	}
+	return err.Error()
+}
+
+// Validate checks rules of editable fields of new (create is true) or updated item and returns all violations.
+// Rules are the same for request bodies and items changed by patches or bulk operations.
+// Status must be known to the workflow, it may be blank for new items only.
+func (t ToDo) Validate(workflow Workflow, create bool) Violations {
+	var violations Violations
+	add := func(field, message string) {
+		violations = append(violations, FieldError{Field: field, Message: message})
+	}
+	if len(strings.TrimSpace(t.Description)) == 0 {
+		add("description", "is required")
+	} else if utf8.RuneCountInString(t.Description) > MaxDescriptionLength {
+		add("description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLength))
+	}
+	if len(strings.TrimSpace(t.Status)) == 0 {
+		if !create {
+			add("status", "is required")
+		}
+	} else if _, ok := workflow.Resolve(t.Status); !ok {
+		add("status", "must be one of: "+strings.Join(workflow.Names(), ", "))
+	}
+	validStart := validTimestamp(t.StartAt)
+	if !validStart {
+		add("start_at", fmt.Sprintf("must be unix timestamp between 0 and %d", MaxTimestamp))
+	}
+	if !validTimestamp(t.DueAt) {
+		add("due_at", fmt.Sprintf("must be unix timestamp between 0 and %d", MaxTimestamp))
+	} else if validStart && ValidateSchedule(t.StartAt, t.DueAt) != nil {
+		add("due_at", "must be after start_at")
+	}
+	for i, tag := range t.Tags {
+		if _, err := NormalizeTagName(tag); err != nil {
+			add(fmt.Sprintf("tags[%d]", i), err.Error())
+		}
+	}
+	if t.ProjectID != nil && *t.ProjectID < 0 {
+		add("project_id", "must not be negative")
+	}
+	if t.ParentID != nil && *t.ParentID < 0 {
+		add("parent_id", "must not be negative")
+	}
+	if t.Recurrence != nil && len(strings.TrimSpace(*t.Recurrence)) != 0 {
+		if _, err := ParseRRule(*t.Recurrence); err != nil {
+			add("recurrence", err.Error())
+		} else if t.DueAt == nil {
+			add("recurrence", "requires due_at")
+		}
+	}
+	return violations
+}
+
+// validTimestamp checks that optional timestamp is between 0 and MaxTimestamp.
+func validTimestamp(value *int64) bool {
+	return value == nil || (*value >= 0 && *value <= MaxTimestamp)
+}
+
+// ToDo returns item with fields of the request.
+func (r ToDoRequest) ToDo() ToDo {
+	return ToDo{
+		Description: r.Description,
+		Status:      r.Status,
+		StartAt:     r.StartAt,
+		DueAt:       r.DueAt,
+		Priority:    r.Priority,
+		Tags:        r.Tags,
+		ProjectID:   r.ProjectID,
+		ParentID:    r.ParentID,
+		Recurrence:  r.Recurrence,
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToDoRequest(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		create             bool
+		expectedViolations Violations
+		expectError        bool
+	}{
+		{name: "new item without status", body: `{"description": "Write", "priority": "high", "tags": ["Work"]}`, create: true},
+		{name: "all fields", body: `{"description": "Write", "status": "done", "start_at": 1, "due_at": 2, "priority": 3,
+			"tags": [], "project_id": 0, "parent_id": 1, "recurrence": "FREQ=DAILY"}`},
+		{name: "replaced item without status", body: `{"description": "Write"}`,
+			expectedViolations: Violations{{Field: "status", Message: "is required"}}},
+		{name: "blank description", body: `{"description": "  "}`, create: true,
+			expectedViolations: Violations{{Field: "description", Message: "is required"}}},
+		{name: "too long description", body: `{"description": "` + strings.Repeat("ž", MaxDescriptionLength+1) + `"}`, create: true,
+			expectedViolations: Violations{{Field: "description", Message: "must be at most 255 characters"}}},
+		{name: "unknown status", body: `{"description": "Write", "status": "LATER"}`, create: true,
+			expectedViolations: Violations{{Field: "status", Message: "must be one of: TO DO, IN PROGRESS, DONE"}}},
+		{name: "due before start", body: `{"description": "Write", "start_at": 5, "due_at": 5}`, create: true,
+			expectedViolations: Violations{{Field: "due_at", Message: "must be after start_at"}}},
+		{name: "negative dates", body: `{"description": "Write", "start_at": -1, "due_at": -1}`, create: true,
+			expectedViolations: Violations{
+				{Field: "start_at", Message: "must be unix timestamp between 0 and 253402300799"},
+				{Field: "due_at", Message: "must be unix timestamp between 0 and 253402300799"},
+			}},
+		{name: "recurrence without due date", body: `{"description": "Write", "recurrence": "FREQ=DAILY"}`, create: true,
+			expectedViolations: Violations{{Field: "recurrence", Message: "requires due_at"}}},
+		{name: "all violations together", body: `{"id": 1, "created": 2, "description": 3, "tags": ["ok", ""], "parent_id": -1}`, create: true,
+			expectedViolations: Violations{
+				{Field: "description", Message: "must be string"},
+				{Field: "tags[1]", Message: "tag name can't be blank"},
+				{Field: "parent_id", Message: "must not be negative"},
+				{Field: "created", Message: "is unknown field"},
+				{Field: "id", Message: "is unknown field"},
+			}},
+		{name: "invalid JSON", body: `{"description": `, create: true, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item, err := ParseToDoRequest([]byte(test.body), DefaultWorkflow(), test.create)
+			if test.expectError {
+				assert.Error(t, err)
+				assert.NotErrorAs(t, err, new(Violations))
+				return
+			}
+			if test.expectedViolations != nil {
+				assert.Equal(t, test.expectedViolations, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Write", item.Description)
+			assert.Zero(t, item.ID)
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is FREQ part of recurrence rule.
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// MaxOccurrences limits number of occurrences, that can be previewed at once.
+const MaxOccurrences = 100
+
+// maxPeriods limits number of periods (days, weeks, months or years) scanned for occurrences,
+// so rules, that never match (e.g. February 30th), don't loop forever.
+const maxPeriods = 50000
+
+// untilLayout is UTC date-time format of UNTIL, date-only values are accepted as well.
+const untilLayout = "20060102T150405Z"
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// WeekdayNum is BYDAY entry: weekday with optional ordinal within the month, e.g. -1FR is the last Friday.
+// N is 0 for every such weekday.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// String formats entry as in RFC 5545.
+func (w WeekdayNum) String() string {
+	code := strings.ToUpper(w.Weekday.String()[:2])
+	if w.N == 0 {
+		return code
+	}
+	return strconv.Itoa(w.N) + code
+}
+
+// RRule is recurrence rule of RFC 5545. Supported parts: FREQ (DAILY, WEEKLY, MONTHLY or YEARLY),
+// INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH. Occurrences are computed in UTC, weeks start on Monday.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// ParseRRule parses recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO,WE" or "RRULE:FREQ=MONTHLY;BYDAY=-1FR".
+func ParseRRule(value string) (RRule, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	if len(value) == 0 {
+		return RRule{}, errors.New("recurrence rule can't be blank")
+	}
+	rule := RRule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, values, ok := strings.Cut(part, "=")
+		if !ok || len(values) == 0 {
+			return RRule{}, fmt.Errorf("invalid recurrence rule part: %s", part)
+		}
+		if seen[name] {
+			return RRule{}, fmt.Errorf("duplicate recurrence rule part: %s", name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(values)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly && rule.Freq != Yearly {
+				err = fmt.Errorf("unsupported frequency: %s", values)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseRuleInt(name, values, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseRuleInt(name, values, 1, 10000)
+		case "UNTIL":
+			rule.Until, err = parseUntil(values)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(values)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRuleInts(name, values, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseRuleInts(name, values, 1, 12)
+			sort.Ints(months)
+			for _, month := range months {
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		default:
+			err = fmt.Errorf("unsupported recurrence rule part: %s", name)
+		}
+		if err != nil {
+			return RRule{}, err
+		}
+	}
+	if len(rule.Freq) == 0 {
+		return RRule{}, errors.New("recurrence rule must have FREQ")
+	}
+	if rule.Count != 0 && rule.Until != nil {
+		return RRule{}, errors.New("COUNT and UNTIL can't be used together")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && (rule.Freq != Yearly || len(rule.ByMonth) == 0) {
+			return RRule{}, errors.New("BYDAY ordinals are supported only with FREQ=MONTHLY or FREQ=YEARLY with BYMONTH")
+		}
+	}
+	return rule, nil
+}
+
+// String formats rule with parts in fixed order, so equal rules are stored the same way.
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if len(r.ByMonth) != 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) != 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) != 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns up to n occurrences following start. Start is the first occurrence of the rule,
+// so it's counted by COUNT, but never returned.
+func (r RRule) Occurrences(start time.Time, n int) []time.Time {
+	start = start.UTC()
+	if r.Count != 0 && r.Count-1 < n {
+		n = r.Count - 1
+	}
+	var occurrences []time.Time
+	for period := 0; period < maxPeriods && len(occurrences) < n; period++ {
+		for _, occurrence := range r.candidates(start, period*r.Interval) {
+			if !occurrence.After(start) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return occurrences
+			}
+			occurrences = append(occurrences, occurrence)
+			if len(occurrences) == n {
+				return occurrences
+			}
+		}
+	}
+	return occurrences
+}
+
+// Next returns the rule for occurrence following start: COUNT is decreased, as start is used up.
+// Returns false, if start is the last occurrence.
+func (r RRule) Next(start time.Time) (time.Time, RRule, bool) {
+	occurrences := r.Occurrences(start, 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, RRule{}, false
+	}
+	if r.Count != 0 {
+		r.Count--
+	}
+	return occurrences[0], r, true
+}
+
+// candidates returns ordered occurrences within period, which is offset (in frequency units) from the period of start.
+// Time of day is taken from start.
+func (r RRule) candidates(start time.Time, offset int) []time.Time {
+	date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{date.AddDate(0, 0, offset)}
+	case Weekly:
+		monday := date.AddDate(0, 0, 7*offset-weekdayIndex(date))
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && i == weekdayIndex(date) || r.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		days = r.monthDays(time.Date(date.Year(), date.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC), date.Day())
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{date.Month()}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(time.Date(date.Year()+offset, month, 1, 0, 0, 0, 0, time.UTC), date.Day())...)
+		}
+	}
+
+	clock := start.Sub(date)
+	var occurrences []time.Time
+	for _, day := range days {
+		if r.Freq != Yearly && len(r.ByMonth) != 0 && !containsMonth(r.ByMonth, day.Month()) {
+			continue
+		}
+		if (r.Freq == Daily || r.Freq == Weekly) && len(r.ByMonthDay) != 0 && !r.matchesMonthDay(day) {
+			continue
+		}
+		if r.Freq == Daily && len(r.ByDay) != 0 && !r.matchesWeekday(day) {
+			continue
+		}
+		occurrences = append(occurrences, day.Add(clock))
+	}
+	return occurrences
+}
+
+// monthDays returns ordered days of the month selected by BYMONTHDAY and/or BYDAY, by default the same day as start.
+func (r RRule) monthDays(first time.Time, startDay int) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) != 0:
+		for day := 1; day <= last; day++ {
+			date := first.AddDate(0, 0, day-1)
+			if r.matchesMonthDay(date) && (len(r.ByDay) == 0 || r.matchesDayInMonth(date, last)) {
+				days = append(days, date)
+			}
+		}
+	case len(r.ByDay) != 0:
+		for day := 1; day <= last; day++ {
+			if date := first.AddDate(0, 0, day-1); r.matchesDayInMonth(date, last) {
+				days = append(days, date)
+			}
+		}
+	case startDay <= last:
+		days = append(days, first.AddDate(0, 0, startDay-1))
+	}
+	return days
+}
+
+func (r RRule) matchesWeekday(day time.Time) bool {
+	for _, weekday := range r.ByDay {
+		if weekday.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDayInMonth checks day against BYDAY with ordinals counted from start (1) or end (-1) of the month.
+func (r RRule) matchesDayInMonth(day time.Time, last int) bool {
+	fromStart := (day.Day()-1)/7 + 1
+	fromEnd := -((last-day.Day())/7 + 1)
+	for _, weekday := range r.ByDay {
+		if weekday.Weekday == day.Weekday() && (weekday.N == 0 || weekday.N == fromStart || weekday.N == fromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RRule) matchesMonthDay(day time.Time) bool {
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == day.Day() || monthDay < 0 && last+monthDay+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayIndex returns position of the day in the week starting on Monday.
+func weekdayIndex(day time.Time) int {
+	return (int(day.Weekday()) + 6) % 7
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, candidate := range months {
+		if candidate == month {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRuleInt(name, value string, min, max int) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return number, nil
+}
+
+// parseRuleInts parses comma separated list of non-zero numbers.
+func parseRuleInts(name, values string, min, max int) ([]int, error) {
+	var numbers []int
+	for _, value := range strings.Split(values, ",") {
+		number, err := parseRuleInt(name, value, min, max)
+		if err != nil || number == 0 {
+			return nil, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func parseByDay(values string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, value := range strings.Split(values, ",") {
+		if len(value) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY: %s", value)
+		}
+		weekday, ok := weekdayCodes[value[len(value)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY: %s", value)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := value[:len(value)-2]; len(ordinal) != 0 {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY: %s", value)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseUntil parses UTC date-time, date-only value means the end of that day.
+func parseUntil(value string) (*time.Time, error) {
+	until, err := time.Parse(untilLayout, value)
+	if err != nil {
+		date, dateErr := time.Parse("20060102", value)
+		if dateErr != nil {
+			return nil, fmt.Errorf("invalid UNTIL: %s", value)
+		}
+		until = date.Add(24*time.Hour - time.Second)
+	}
+	return &until, nil
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{value: "FREQ=DAILY", expected: "FREQ=DAILY"},
+		{value: "rrule:freq=weekly;byday=we,mo;interval=2", expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=WE,MO"},
+		{value: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", expected: "FREQ=MONTHLY;COUNT=3;BYDAY=-1FR"},
+		{value: "FREQ=YEARLY;BYMONTH=12,6;BYMONTHDAY=-1;UNTIL=20301231", expected: "FREQ=YEARLY;UNTIL=20301231T235959Z;BYMONTH=6,12;BYMONTHDAY=-1"},
+		{value: "", expectError: true},
+		{value: "INTERVAL=2", expectError: true},
+		{value: "FREQ=HOURLY", expectError: true},
+		{value: "FREQ=DAILY;FREQ=WEEKLY", expectError: true},
+		{value: "FREQ=DAILY;INTERVAL=0", expectError: true},
+		{value: "FREQ=DAILY;COUNT=2;UNTIL=20300101", expectError: true},
+		{value: "FREQ=WEEKLY;BYDAY=1MO", expectError: true},
+		{value: "FREQ=MONTHLY;BYDAY=XX", expectError: true},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=0", expectError: true},
+		{value: "FREQ=DAILY;WKST=SU", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			rule, err := ParseRRule(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, rule.String())
+		})
+	}
+}
+
+func TestRRuleOccurrences(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		require.NoError(t, err)
+		return parsed
+	}
+	tests := []struct {
+		rule     string
+		start    string
+		count    int
+		expected []string
+	}{
+		{
+			rule: "FREQ=DAILY;INTERVAL=2", start: "2024-02-27 09:30", count: 3,
+			expected: []string{"2024-02-29 09:30", "2024-03-02 09:30", "2024-03-04 09:30"},
+		},
+		{
+			rule: "FREQ=WEEKLY;BYDAY=MO,WE", start: "2024-01-03 18:00", count: 3,
+			expected: []string{"2024-01-08 18:00", "2024-01-10 18:00", "2024-01-15 18:00"},
+		},
+		{
+			rule: "FREQ=WEEKLY;INTERVAL=2", start: "2024-01-03 18:00", count: 2,
+			expected: []string{"2024-01-17 18:00", "2024-01-31 18:00"},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYDAY=-1FR", start: "2024-01-26 12:00", count: 3,
+			expected: []string{"2024-02-23 12:00", "2024-03-29 12:00", "2024-04-26 12:00"},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYDAY=2TU", start: "2024-01-01 08:00", count: 2,
+			expected: []string{"2024-01-09 08:00", "2024-02-13 08:00"},
+		},
+		{
+			// Months without the day are skipped.
+			rule: "FREQ=MONTHLY", start: "2024-01-31 10:00", count: 2,
+			expected: []string{"2024-03-31 10:00", "2024-05-31 10:00"},
+		},
+		{
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1", start: "2024-01-31 10:00", count: 2,
+			expected: []string{"2024-02-29 10:00", "2024-03-31 10:00"},
+		},
+		{
+			rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", start: "2024-02-29 00:00", count: 1,
+			expected: []string{"2028-02-29 00:00"},
+		},
+		{
+			// Start is the first of three occurrences.
+			rule: "FREQ=DAILY;COUNT=3", start: "2024-01-01 07:00", count: 5,
+			expected: []string{"2024-01-02 07:00", "2024-01-03 07:00"},
+		},
+		{
+			rule: "FREQ=WEEKLY;UNTIL=20240115", start: "2024-01-01 07:00", count: 5,
+			expected: []string{"2024-01-08 07:00", "2024-01-15 07:00"},
+		},
+		{
+			rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", start: "2024-01-01 07:00", count: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			rule, err := ParseRRule(test.rule)
+			require.NoError(t, err)
+			var occurrences []string
+			for _, occurrence := range rule.Occurrences(date(test.start), test.count) {
+				occurrences = append(occurrences, occurrence.Format("2006-01-02 15:04"))
+			}
+			assert.Equal(t, test.expected, occurrences)
+		})
+	}
+}
+
+func TestRRuleNext(t *testing.T) {
+	rule, err := ParseRRule("FREQ=DAILY;COUNT=2")
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	next, rule, ok := rule.Next(start)
+	require.True(t, ok)
+	assert.Equal(t, start.AddDate(0, 0, 1), next)
+	assert.Equal(t, "FREQ=DAILY;COUNT=1", rule.String())
+
+	_, _, ok = rule.Next(next)
+	assert.False(t, ok)
+}
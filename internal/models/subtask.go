@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Progress is completion of all subtasks of the item, including nested ones.
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// SubtaskPolicy defines what happens to subtasks, when their parent is deleted.
+type SubtaskPolicy string
+
+const (
+	// SubtasksRestrict refuses to delete items having subtasks.
+	SubtasksRestrict SubtaskPolicy = "restrict"
+	// SubtasksCascade deletes the item with all its subtasks, including nested ones.
+	SubtasksCascade SubtaskPolicy = "cascade"
+	// SubtasksDetach deletes only the item, its direct subtasks become top-level items.
+	SubtasksDetach SubtaskPolicy = "detach"
+)
+
+// ParseSubtaskPolicy parses policy name, blank name means SubtasksRestrict.
+func ParseSubtaskPolicy(value string) (SubtaskPolicy, error) {
+	switch policy := SubtaskPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return SubtasksRestrict, nil
+	case SubtasksRestrict, SubtasksCascade, SubtasksDetach:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid subtasks policy: %s", value)
+}
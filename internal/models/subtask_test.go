@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubtaskPolicy(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    SubtaskPolicy
+		expectError bool
+	}{
+		{value: "", expected: SubtasksRestrict},
+		{value: "restrict", expected: SubtasksRestrict},
+		{value: " Cascade ", expected: SubtasksCascade},
+		{value: "detach", expected: SubtasksDetach},
+		{value: "orphan", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			policy, err := ParseSubtaskPolicy(test.value)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, policy)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxTagLength is maximum length of tag name.
+const MaxTagLength = 64
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Tag is label, that groups to-do items. Items may have many tags, tags are shared by items.
+type Tag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	// Count is number of items with the tag, items in trash aren't counted.
+	Count int64 `json:"count"`
+}
+
+// TagFilter selects items having any (or all, when MatchAll is set) of the tags.
+type TagFilter struct {
+	Names    []string
+	MatchAll bool
+}
+
+// NormalizeTagName returns tag name trimmed and lowercased, so "Work" and "work " are the same tag.
+func NormalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 {
+		return "", fmt.Errorf("tag name can't be blank")
+	}
+	if len(name) > MaxTagLength {
+		return "", fmt.Errorf("tag name %q is longer than %d characters", name, MaxTagLength)
+	}
+	if strings.Contains(name, ",") {
+		return "", fmt.Errorf("tag name %q can't contain commas", name)
+	}
+	return name, nil
+}
+
+// NormalizeTags normalizes tag names, removes duplicates and sorts them.
+// Nil stays nil, so it can mean "not given".
+func NormalizeTags(names []string) ([]string, error) {
+	if names == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// NormalizeColor checks that color is "#rrggbb" hex code and lowercases it. Blank color is allowed.
+func NormalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if len(color) != 0 && !colorPattern.MatchString(color) {
+		return "", fmt.Errorf("invalid color %q, expected #rrggbb", color)
+	}
+	return color, nil
+}
+
+// TagFromJson creates Tag object from JSON byte array.
+func TagFromJson(data []byte) (Tag, error) {
+	var tag Tag
+	err := json.Unmarshal(data, &tag)
+	return tag, err
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"Work", " urgent ", "work"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"urgent", "work"}, tags)
+
+	tags, err = NormalizeTags(nil)
+	require.NoError(t, err)
+	assert.Nil(t, tags)
+
+	_, err = NormalizeTags([]string{"a,b"})
+	assert.Error(t, err)
+	_, err = NormalizeTags([]string{""})
+	assert.Error(t, err)
+}
+
+func TestNormalizeColor(t *testing.T) {
+	color, err := NormalizeColor("#A0B1C2")
+	require.NoError(t, err)
+	assert.Equal(t, "#a0b1c2", color)
+
+	_, err = NormalizeColor("red")
+	assert.Error(t, err)
+}
@@ -1,26 +1,73 @@
 package models
 
-import (
-	"encoding/json"
-)
+import "errors"
 
 const DefaultStatus = "TO DO"
 
+// StatusDone is terminal status of the default workflow (see DefaultWorkflow).
+const StatusDone = "DONE"
+
 // ToDo defines to-do item structure.
+// StartAt and DueAt are optional unix timestamps.
+// Priority is always set on stored items, nil in requests means PriorityNone.
+// Tags are sorted names, nil and empty list in requests both mean no tags.
+// ProjectID is nil for items outside of projects, in requests 0 means no project as well.
+// ParentID is nil for top-level items, 0 in requests means top-level item as well.
+// Recurrence is RFC 5545 rule (see RRule) of recurring items, which must have DueAt. Empty rule in requests
+// means no recurrence.
+// Updates replace all of these fields, fields missing in update requests are cleared (see ToDoPatch for patches).
+// DeletedAt is set on items in trash, it's the unix timestamp of deleting.
+// Version is incremented on every change of stored item, it's ignored in requests (see ETags of item endpoints).
+// Blocked is set, when any item blocking this one (see Dependencies) isn't finished yet.
+// Progress is set on items having subtasks, Children only on items retrieved as tree.
 type ToDo struct {
-	ID          int64  `json:"id" gorm:"primaryKey"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
-	Created     int64  `json:"created"`
-	Updated     int64  `json:"updated"`
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	Created     int64     `json:"created"`
+	Updated     int64     `json:"updated"`
+	StartAt     *int64    `json:"start_at"`
+	DueAt       *int64    `json:"due_at"`
+	Priority    *Priority `json:"priority"`
+	Tags        []string  `json:"tags"`
+	ProjectID   *int64    `json:"project_id"`
+	ParentID    *int64    `json:"parent_id"`
+	Recurrence  *string   `json:"recurrence"`
+	Blocked     bool      `json:"blocked"`
+	DeletedAt   *int64    `json:"deleted_at,omitempty"`
+	Version     int64     `json:"version"`
+	Progress    *Progress `json:"progress,omitempty"`
+	Children    []ToDo    `json:"children,omitempty"`
 }
 
-// FromJson creates ToDo object from JSON byte array.
-func FromJson(data []byte) (ToDo, error) {
-	var item ToDo
-	err := json.Unmarshal(data, &item)
-	if err != nil {
-		return item, err
+// ValidateSchedule checks that start precedes due, when both are set.
+func ValidateSchedule(startAt, dueAt *int64) error {
+	if startAt != nil && dueAt != nil && *startAt >= *dueAt {
+		return errors.New("start_at must precede due_at")
 	}
-	return item, nil
+	return nil
+}
+
+// ToDoPage is a single page of to-do items with navigation info.
+// Total is number of items matching filters regardless of pagination.
+type ToDoPage struct {
+	Items      []ToDo `json:"items"`
+	Total      int64  `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// SearchResult is to-do item matching search query. Snippet is description with matched words highlighted.
+type SearchResult struct {
+	ToDo
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchPage is a single page of search results ordered by relevance.
+type SearchPage struct {
+	Items   []SearchResult `json:"items"`
+	Total   int64          `json:"total"`
+	HasMore bool           `json:"has_more"`
 }
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxStatusLength is maximum length of status name.
+const MaxStatusLength = 255
+
+// Workflow defines statuses of to-do items and transitions allowed between them.
+// Initial status is given to new items without status.
+type Workflow struct {
+	Initial  string           `json:"initial"`
+	Statuses []WorkflowStatus `json:"statuses"`
+}
+
+// WorkflowStatus is a single status of the workflow with statuses reachable from it.
+// Terminal statuses finish the item, such items count as done in progress of their parents.
+type WorkflowStatus struct {
+	Name        string   `json:"name"`
+	Transitions []string `json:"transitions"`
+	Terminal    bool     `json:"terminal"`
+}
+
+// DefaultWorkflow is used when configuration doesn't provide one: items go from "TO DO" through
+// "IN PROGRESS" to "DONE" and may be reopened.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Initial: DefaultStatus,
+		Statuses: []WorkflowStatus{
+			{Name: DefaultStatus, Transitions: []string{"IN PROGRESS", StatusDone}},
+			{Name: "IN PROGRESS", Transitions: []string{DefaultStatus, StatusDone}},
+			{Name: StatusDone, Transitions: []string{DefaultStatus}, Terminal: true},
+		},
+	}
+}
+
+// ParseWorkflow reads workflow from JSON and validates it.
+func ParseWorkflow(data []byte) (Workflow, error) {
+	var workflow Workflow
+	if err := json.Unmarshal(data, &workflow); err != nil {
+		return Workflow{}, err
+	}
+	if err := workflow.normalize(); err != nil {
+		return Workflow{}, err
+	}
+	return workflow, nil
+}
+
+// normalize trims names and checks that statuses are unique and transitions lead to known statuses.
+// Blank initial status means the first one.
+func (w *Workflow) normalize() error {
+	if len(w.Statuses) == 0 {
+		return errors.New("workflow must have statuses")
+	}
+	seen := make(map[string]bool, len(w.Statuses))
+	for i := range w.Statuses {
+		status := &w.Statuses[i]
+		status.Name = strings.TrimSpace(status.Name)
+		if len(status.Name) == 0 || len(status.Name) > MaxStatusLength {
+			return fmt.Errorf("status name must have 1 to %d characters", MaxStatusLength)
+		}
+		if seen[strings.ToLower(status.Name)] {
+			return fmt.Errorf("duplicate status: %s", status.Name)
+		}
+		seen[strings.ToLower(status.Name)] = true
+	}
+	for i := range w.Statuses {
+		status := &w.Statuses[i]
+		transitions := make([]string, len(status.Transitions))
+		for j, name := range status.Transitions {
+			target, ok := w.Resolve(name)
+			if !ok {
+				return fmt.Errorf("unknown status %q in transitions of %q", name, status.Name)
+			}
+			transitions[j] = target
+		}
+		status.Transitions = transitions
+	}
+	if len(strings.TrimSpace(w.Initial)) == 0 {
+		w.Initial = w.Statuses[0].Name
+		return nil
+	}
+	initial, ok := w.Resolve(w.Initial)
+	if !ok {
+		return fmt.Errorf("unknown initial status: %s", w.Initial)
+	}
+	w.Initial = initial
+	return nil
+}
+
+// Resolve returns name of the status matching given one, ignoring case and surrounding spaces.
+func (w Workflow) Resolve(name string) (string, bool) {
+	status, ok := w.status(name)
+	return status.Name, ok
+}
+
+// Names returns names of all statuses in workflow order.
+func (w Workflow) Names() []string {
+	names := make([]string, len(w.Statuses))
+	for i, status := range w.Statuses {
+		names[i] = status.Name
+	}
+	return names
+}
+
+// Transitions returns statuses reachable from given one. Any status is reachable from statuses unknown
+// to the workflow, so items created before the workflow was configured can be moved into it.
+func (w Workflow) Transitions(from string) []string {
+	status, ok := w.status(from)
+	if !ok {
+		return w.Names()
+	}
+	return status.Transitions
+}
+
+// IsTerminal checks that status finishes the item.
+func (w Workflow) IsTerminal(name string) bool {
+	status, ok := w.status(name)
+	return ok && status.Terminal
+}
+
+// Terminal returns names of terminal statuses.
+func (w Workflow) Terminal() []string {
+	var names []string
+	for _, status := range w.Statuses {
+		if status.Terminal {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+// Open returns names of statuses, which aren't terminal.
+func (w Workflow) Open() []string {
+	var names []string
+	for _, status := range w.Statuses {
+		if !status.Terminal {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+func (w Workflow) status(name string) (WorkflowStatus, bool) {
+	name = strings.TrimSpace(name)
+	for _, status := range w.Statuses {
+		if strings.EqualFold(status.Name, name) {
+			return status, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            string
+		expectedInitial string
+		expectError     bool
+	}{
+		{
+			name:            "initial defaults to the first status",
+			data:            `{"statuses": [{"name": " Open ", "transitions": ["closed"]}, {"name": "Closed", "terminal": true}]}`,
+			expectedInitial: "Open",
+		},
+		{
+			name:            "initial is matched ignoring case",
+			data:            `{"initial": "closed", "statuses": [{"name": "Open"}, {"name": "Closed"}]}`,
+			expectedInitial: "Closed",
+		},
+		{name: "no statuses", data: `{"statuses": []}`, expectError: true},
+		{name: "blank status", data: `{"statuses": [{"name": " "}]}`, expectError: true},
+		{name: "duplicate status", data: `{"statuses": [{"name": "Open"}, {"name": "OPEN"}]}`, expectError: true},
+		{name: "unknown transition", data: `{"statuses": [{"name": "Open", "transitions": ["Closed"]}]}`, expectError: true},
+		{name: "unknown initial", data: `{"initial": "New", "statuses": [{"name": "Open"}]}`, expectError: true},
+		{name: "malformed JSON", data: `{"statuses": `, expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workflow, err := ParseWorkflow([]byte(test.data))
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedInitial, workflow.Initial)
+		})
+	}
+}
+
+// TestWorkflowTransitions checks lookups of the default workflow.
+func TestWorkflowTransitions(t *testing.T) {
+	workflow := DefaultWorkflow()
+
+	status, ok := workflow.Resolve(" done")
+	assert.True(t, ok)
+	assert.Equal(t, StatusDone, status)
+	_, ok = workflow.Resolve("Finished")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{DefaultStatus}, workflow.Transitions(StatusDone))
+	assert.Equal(t, workflow.Names(), workflow.Transitions("Legacy status"))
+	assert.True(t, workflow.IsTerminal("Done"))
+	assert.False(t, workflow.IsTerminal(DefaultStatus))
+	assert.Equal(t, []string{StatusDone}, workflow.Terminal())
+	assert.Equal(t, []string{DefaultStatus, "IN PROGRESS"}, workflow.Open())
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// todoWriter changes to-do items within transaction, implemented by TodoRepo and SQLiteRepo.
+type todoWriter interface {
+	createToDo(ctx context.Context, tx *sql.Tx, item *models.ToDo, actor string) (models.ToDo, error)
+	patchToDo(ctx context.Context, tx *sql.Tx, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error)
+}
+
+// errBulkFailed rolls back transaction of atomic bulk request, whose operation failed.
+var errBulkFailed = errors.New("bulk operation failed")
+
+// BulkToDos executes operations of the request in order within single transaction.
+// Atomic request is rolled back, if any operation fails; best effort request runs every operation
+// in its own savepoint, so only failed operations are rolled back. Changes are recorded in audit log with actor.
+func (r TodoRepo) BulkToDos(ctx context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	return r.bulkToDos(ctx, r, request, actor)
+}
+
+// BulkToDos executes operations of the request in order within single transaction, see TodoRepo.BulkToDos.
+func (r *SQLiteRepo) BulkToDos(ctx context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	return r.bulkToDos(ctx, r, request, actor)
+}
+
+// PatchToDos applies patch to every item matching filters of params within single transaction.
+// Sorting and pagination are ignored. Either all items are patched or none of them.
+func (r TodoRepo) PatchToDos(ctx context.Context, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error) {
+	return r.patchToDos(ctx, r, params, patch, force, actor)
+}
+
+// PatchToDos applies patch to every item matching filters of params, see TodoRepo.PatchToDos.
+func (r *SQLiteRepo) PatchToDos(ctx context.Context, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error) {
+	return r.patchToDos(ctx, r, params, patch, force, actor)
+}
+
+func (s sqlStore) bulkToDos(ctx context.Context, w todoWriter, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	var results []models.BulkResult
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var ok bool
+		results, ok = runBulk(request, func(op models.BulkOperation) (*models.ToDo, error) {
+			if request.Atomic() {
+				return s.bulkOperation(ctx, tx, w, op, actor)
+			}
+			return withSavepoint(ctx, tx, func() (*models.ToDo, error) {
+				return s.bulkOperation(ctx, tx, w, op, actor)
+			})
+		})
+		if !ok {
+			return errBulkFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkFailed) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, wrapError(err, "Unable to execute bulk operations")
+	}
+	var items []*models.ToDo
+	for _, result := range results {
+		if result.Item != nil {
+			items = append(items, result.Item)
+		}
+	}
+	if err := loadDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal()); err != nil {
+		return nil, models.InternalError("Unable to get details of items", err)
+	}
+	return results, nil
+}
+
+func (s sqlStore) bulkOperation(ctx context.Context, tx *sql.Tx, w todoWriter, op models.BulkOperation, actor string) (*models.ToDo, error) {
+	var item models.ToDo
+	var err error
+	switch op.Op {
+	case models.BulkCreate:
+		item, err = w.createToDo(ctx, tx, op.Item, actor)
+	case models.BulkUpdate:
+		item, err = w.patchToDo(ctx, tx, op.ID, op.Change(), op.Version, op.Force, actor)
+	default:
+		return nil, s.deleteToDo(ctx, tx, op.ID, op.Subtasks, actor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// withSavepoint runs fn in savepoint of transaction, changes made by fn are rolled back if it fails.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() (*models.ToDo, error)) (*models.ToDo, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_operation"); err != nil {
+		return nil, err
+	}
+	item, err := fn()
+	if err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_operation"); rollbackErr != nil {
+			log.Println(rollbackErr)
+		}
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_operation"); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s sqlStore) patchToDos(ctx context.Context, w todoWriter, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error) {
+	query, args, err := buildTodoIDsQuery(s.dialect, params, s.workflow.Terminal())
+	if err != nil {
+		return nil, err
+	}
+	var items []models.ToDo
+	err = withTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids, err := queryIDs(ctx, tx, query, args...)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			item, err := w.patchToDo(ctx, tx, id, patch, 0, force, actor)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapError(err, "Unable to update items")
+	}
+	if err := loadItemDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal()); err != nil {
+		return nil, models.InternalError("Unable to get details of items", err)
+	}
+	return items, nil
+}
+
+// runBulk executes operations of the request with exec, which returns resulting item (nil for delete).
+// Failed operation of atomic request stops execution and the result isn't ok: other operations get 424,
+// as they are rolled back or not executed at all.
+func runBulk(request models.BulkRequest, exec func(op models.BulkOperation) (*models.ToDo, error)) ([]models.BulkResult, bool) {
+	results := make([]models.BulkResult, len(request.Operations))
+	for i, op := range request.Operations {
+		results[i] = models.BulkResult{Index: i, Status: bulkStatus(op)}
+		item, err := exec(op)
+		if err == nil {
+			results[i].Item = item
+			continue
+		}
+		results[i] = failedResult(i, err)
+		if !request.Atomic() {
+			continue
+		}
+		for j := range results {
+			if j != i {
+				results[j] = models.NotApplied(j, i)
+			}
+		}
+		return results, false
+	}
+	return results, true
+}
+
+// bulkStatus returns status code of successful operation.
+func bulkStatus(op models.BulkOperation) int {
+	switch op.Op {
+	case models.BulkCreate:
+		return http.StatusCreated
+	case models.BulkDelete:
+		return http.StatusNoContent
+	}
+	return http.StatusOK
+}
+
+// failedResult returns result of operation failed with error, errors other than AppError are internal ones.
+func failedResult(index int, err error) models.BulkResult {
+	var appError *models.AppError
+	if !errors.As(err, &appError) {
+		log.Printf("Bulk operation %d failed: %v", index, err)
+		appError = models.InternalError("Unable to execute operation", err)
+	}
+	return models.BulkResult{Index: index, Status: appError.Status(), Code: appError.Code(), Error: appError.Error(), Errors: appError.Fields()}
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// blockerIDs selects ids of all items blocking the item ($1), directly or through other blockers.
+// UNION drops repeated rows, so recursion ends even if links formed a cycle.
+const blockerIDs = `WITH RECURSIVE blockers(id) AS (
+		SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1
+		UNION
+		SELECT todo_dependencies.blocker_id FROM todo_dependencies JOIN blockers ON todo_dependencies.todo_id = blockers.id
+	) SELECT id FROM blockers`
+
+// GetDependencies retrieves items blocking the item with given id and items it blocks.
+func (s sqlStore) GetDependencies(ctx context.Context, id int64) (models.Dependencies, error) {
+	if err := checkItemExists(ctx, s.db, s.dialect, id); err != nil {
+		return models.Dependencies{}, err
+	}
+	dependencies, err := s.queryDependencies(ctx, id)
+	if err != nil {
+		return models.Dependencies{}, models.InternalError(fmt.Sprintf("Unable to get dependencies of item with id %d", id), err)
+	}
+	return dependencies, nil
+}
+
+// AddDependency marks the item with given id as blocked by another item. Adding existing link changes nothing.
+// Links can't form cycles, otherwise items would block each other forever. Items in trash can't block.
+// The cycle check reads links of many items, so it runs in serializable transaction: concurrent requests can't
+// add links closing a cycle together.
+func (s sqlStore) AddDependency(ctx context.Context, id, blockerID int64) (models.Dependencies, error) {
+	err := withSerializableTx(ctx, s.db, s.dialect, func(tx *sql.Tx) error {
+		if err := checkItemExists(ctx, tx, s.dialect, id); err != nil {
+			return err
+		}
+		if id == blockerID {
+			return models.ValidationError("Item can't block itself", nil)
+		}
+		var existing int64
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT id FROM todos WHERE id = $1 AND deleted_at IS NULL"), blockerID).Scan(&existing)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ValidationError(fmt.Sprintf("Unable to find blocking item with id %d", blockerID), err)
+		}
+		if err != nil {
+			return err
+		}
+		var links, cycles int64
+		if err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			"SELECT COUNT(*) FROM todo_dependencies WHERE todo_id = $1 AND blocker_id = $2"), id, blockerID).Scan(&links); err != nil {
+			return err
+		}
+		if links > 0 {
+			return nil
+		}
+		if err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			"SELECT COUNT(*) FROM todos WHERE id = $2 AND id IN ("+blockerIDs+")"), blockerID, id).Scan(&cycles); err != nil {
+			return err
+		}
+		if cycles > 0 {
+			return models.ValidationError(fmt.Sprintf("Item with id %d is already blocked by item with id %d", blockerID, id), nil).
+				WithCode(models.CodeDependencyCycle)
+		}
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(
+			"INSERT INTO todo_dependencies (todo_id, blocker_id) VALUES ($1, $2)"), id, blockerID)
+		return err
+	})
+	if err != nil {
+		return models.Dependencies{}, wrapError(err, fmt.Sprintf("Unable to add dependency of item with id %d", id))
+	}
+	return s.GetDependencies(ctx, id)
+}
+
+// RemoveDependency unblocks the item with given id from another item.
+func (s sqlStore) RemoveDependency(ctx context.Context, id, blockerID int64) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"DELETE FROM todo_dependencies WHERE todo_id = $1 AND blocker_id = $2"), id, blockerID)
+	if err != nil {
+		return models.InternalError(fmt.Sprintf("Unable to remove dependency of item with id %d", id), err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return models.NotFoundError(fmt.Sprintf("Item with id %d isn't blocked by item with id %d", id, blockerID), nil)
+	}
+	return nil
+}
+
+func (s sqlStore) queryDependencies(ctx context.Context, id int64) (models.Dependencies, error) {
+	var dependencies models.Dependencies
+	for _, list := range []struct {
+		items *[]models.ToDo
+		query string
+	}{
+		{&dependencies.BlockedBy, "SELECT blocker_id FROM todo_dependencies WHERE todo_id = $1"},
+		{&dependencies.Blocks, "SELECT todo_id FROM todo_dependencies WHERE blocker_id = $1"},
+	} {
+		items, err := queryTodos(ctx, s.db, s.dialect.rebind(
+			"SELECT "+strings.Join(todoSelectColumns, ", ")+" FROM todos WHERE deleted_at IS NULL AND id IN ("+list.query+") ORDER BY id"), id)
+		if err != nil {
+			return models.Dependencies{}, err
+		}
+		if err := loadItemDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal()); err != nil {
+			return models.Dependencies{}, err
+		}
+		*list.items = append([]models.ToDo{}, items...)
+	}
+	return dependencies, nil
+}
+
+// checkItemExists returns 404 AppError, if there's no item with given id outside of trash.
+func checkItemExists(ctx context.Context, db DBTX, d dialect, id int64) error {
+	var existing int64
+	if err := db.QueryRowContext(ctx, d.rebind("SELECT id FROM todos WHERE id = $1 AND deleted_at IS NULL"), id).Scan(&existing); err != nil {
+		return lookupError(err, fmt.Sprintf("Unable to find item with id %d", id))
+	}
+	return nil
+}
+
+// checkItemBlockers refuses finishing item with given id (moving it from non-terminal status into terminal one),
+// while it's blocked by unfinished items. Items in trash don't block, force skips the check.
+func checkItemBlockers(ctx context.Context, db DBTX, d dialect, workflow models.Workflow, from string, item *models.ToDo, id int64, force bool) error {
+	if force || workflow.IsTerminal(from) || !workflow.IsTerminal(item.Status) {
+		return nil
+	}
+	args := []any{id}
+	query := "SELECT blocker_id FROM todo_dependencies JOIN todos ON todos.id = todo_dependencies.blocker_id " +
+		"WHERE todo_id = $1 AND deleted_at IS NULL"
+	if done := workflow.Terminal(); len(done) != 0 {
+		statuses := make([]string, len(done))
+		for i, status := range done {
+			args = append(args, status)
+			statuses[i] = d.placeholder(len(args))
+		}
+		query += " AND status NOT IN (" + strings.Join(statuses, ", ") + ")"
+	}
+	blockers, err := queryIDs(ctx, db, d.rebind(query+" ORDER BY blocker_id"), args...)
+	if err != nil {
+		return err
+	}
+	return blockedError(id, blockers)
+}
+
+// blockedError returns 409 AppError listing unfinished blockers of the item, nil if there are none.
+func blockedError(id int64, blockers []int64) error {
+	if len(blockers) == 0 {
+		return nil
+	}
+	ids := make([]string, len(blockers))
+	for i, blocker := range blockers {
+		ids[i] = strconv.FormatInt(blocker, 10)
+	}
+	return models.ConflictError(fmt.Sprintf("Item with id %d is blocked by unfinished items: %s", id, strings.Join(ids, ", ")), nil).
+		WithCode(models.CodeBlocked)
+}
+
+// loadBlocked sets Blocked flag of given items with single query. Blockers with done statuses or in trash don't block.
+func loadBlocked(ctx context.Context, db DBTX, d dialect, items []*models.ToDo, done []string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.ToDo, len(items))
+	placeholders := make([]string, len(items))
+	args := make([]any, len(items), len(items)+len(done))
+	for i, item := range items {
+		item.Blocked = false
+		byID[item.ID] = item
+		placeholders[i] = d.placeholder(i + 1)
+		args[i] = item.ID
+	}
+	query := "SELECT DISTINCT todo_id FROM todo_dependencies JOIN todos ON todos.id = todo_dependencies.blocker_id " +
+		"WHERE deleted_at IS NULL AND todo_id IN (" + strings.Join(placeholders, ", ") + ")"
+	if len(done) != 0 {
+		statuses := make([]string, len(done))
+		for i, status := range done {
+			args = append(args, status)
+			statuses[i] = d.placeholder(len(args))
+		}
+		query += " AND status NOT IN (" + strings.Join(statuses, ", ") + ")"
+	}
+	ids, err := queryIDs(ctx, db, query, args...)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		byID[id].Blocked = true
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const eventColumns = "id, todo_id, action, actor, changes, created"
+
+// GetHistory retrieves all changes of the item with given id in chronological order.
+// History is kept after the item is purged from trash.
+func (s sqlStore) GetHistory(ctx context.Context, id int64) ([]models.Event, error) {
+	events, err := queryEvents(ctx, s.db, s.dialect.rebind("SELECT "+eventColumns+" FROM todo_events WHERE todo_id = $1 ORDER BY id"), id)
+	if err != nil {
+		return nil, models.InternalError(fmt.Sprintf("Unable to get history of item with id %d", id), err)
+	}
+	return events, nil
+}
+
+// GetAuditLog retrieves changes of all items matching the filter, most recent first.
+func (s sqlStore) GetAuditLog(ctx context.Context, filter models.EventFilter) ([]models.Event, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, condition+" "+s.dialect.placeholder(len(args)))
+	}
+	if filter.TodoID != 0 {
+		where("todo_id =", filter.TodoID)
+	}
+	if len(filter.Actor) != 0 {
+		where("actor =", filter.Actor)
+	}
+	if len(filter.Action) != 0 {
+		where("action =", string(filter.Action))
+	}
+	if filter.Since != nil {
+		where("created >=", *filter.Since)
+	}
+	if filter.Until != nil {
+		where("created <=", *filter.Until)
+	}
+	query := "SELECT " + eventColumns + " FROM todo_events"
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += " LIMIT " + s.dialect.placeholder(len(args)-1) + " OFFSET " + s.dialect.placeholder(len(args))
+	}
+	events, err := queryEvents(ctx, s.db, query, args...)
+	if err != nil {
+		return nil, models.InternalError("Unable to get audit log", err)
+	}
+	return events, nil
+}
+
+// recordEvents appends events to audit log within transaction of the change.
+func recordEvents(ctx context.Context, tx *sql.Tx, d dialect, events ...models.Event) error {
+	for _, event := range events {
+		changes, err := json.Marshal(event.Changes)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, d.rebind(
+			"INSERT INTO todo_events (todo_id, action, actor, changes, created) VALUES ($1, $2, $3, $4, $5)"),
+			event.TodoID, string(event.Action), event.Actor, string(changes), event.Created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordUpdate appends event with fields changed by update, nothing is recorded if no field has changed.
+func recordUpdate(ctx context.Context, tx *sql.Tx, d dialect, before, after models.ToDo, actor string) error {
+	changes := models.DiffToDo(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return recordEvents(ctx, tx, d, newEvent(after.ID, models.EventUpdated, actor, changes))
+}
+
+// newEvent returns event of the change made right now.
+func newEvent(id int64, action models.EventAction, actor string, changes []models.FieldChange) models.Event {
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+	return models.Event{TodoID: id, Action: action, Actor: actor, Changes: changes, Created: time.Now().Unix()}
+}
+
+// trashEvents returns events of moving items with given ids into trash or out of it, deleted_at changes from before to after.
+func trashEvents(ids []int64, action models.EventAction, actor string, before, after *int64) []models.Event {
+	events := make([]models.Event, len(ids))
+	for i, id := range ids {
+		events[i] = newEvent(id, action, actor, models.DiffToDo(models.ToDo{DeletedAt: before}, models.ToDo{DeletedAt: after}))
+	}
+	return events
+}
+
+// detachEvents returns events of clearing reference of items selected by query as id and referenced id,
+// e.g. parent_id of subtasks. field returns item with the reference set.
+func detachEvents(ctx context.Context, tx *sql.Tx, query string, args []any, actor string, field func(*int64) models.ToDo) ([]models.Event, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+	var events []models.Event
+	for rows.Next() {
+		var id, referenced int64
+		if err := rows.Scan(&id, &referenced); err != nil {
+			return nil, err
+		}
+		events = append(events, newEvent(id, models.EventUpdated, actor, models.DiffToDo(field(&referenced), models.ToDo{})))
+	}
+	return events, rows.Err()
+}
+
+// queryEvents executes query and parses all returned "todo_events" rows.
+func queryEvents(ctx context.Context, db DBTX, query string, args ...any) ([]models.Event, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	events := []models.Event{}
+	for rows.Next() {
+		var event models.Event
+		var action, changes string
+		if err := rows.Scan(&event.ID, &event.TodoID, &action, &event.Actor, &changes, &event.Created); err != nil {
+			return nil, err
+		}
+		event.Action = models.EventAction(action)
+		if err := json.Unmarshal([]byte(changes), &event.Changes); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
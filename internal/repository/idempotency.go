@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// ReserveIdempotencyKey stores key of the record for a new request. If the key is already stored and was created
+// since given unix timestamp, stored record is returned and the key isn't reserved. Expired key is reserved anew.
+func (s sqlStore) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord, since int64) (models.IdempotencyRecord, bool, error) {
+	stored := record
+	reserved := false
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"DELETE FROM idempotency_keys WHERE key = $1 AND created < $2"), record.Key, since); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"INSERT INTO idempotency_keys (key, request_hash, created) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING"),
+			record.Key, record.RequestHash, record.Created)
+		if err != nil {
+			return err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted == 1 {
+			reserved = true
+			return nil
+		}
+		var body, headers string
+		err = tx.QueryRowContext(ctx, s.dialect.rebind(
+			"SELECT key, request_hash, status, content_type, headers, body, created FROM idempotency_keys WHERE key = $1"), record.Key).
+			Scan(&stored.Key, &stored.RequestHash, &stored.Status, &stored.ContentType, &headers, &body, &stored.Created)
+		if err != nil {
+			return err
+		}
+		stored.Body = []byte(body)
+		return json.Unmarshal([]byte(headers), &stored.Headers)
+	})
+	if err != nil {
+		return models.IdempotencyRecord{}, false, models.InternalError("Unable to reserve idempotency key", err)
+	}
+	return stored, reserved, nil
+}
+
+// SaveIdempotencyResponse stores response of the request with reserved key of the record.
+func (s sqlStore) SaveIdempotencyResponse(ctx context.Context, record models.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return models.InternalError("Unable to save idempotent response", err)
+	}
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE idempotency_keys SET status = $1, content_type = $2, headers = $3, body = $4 WHERE key = $5"),
+		record.Status, record.ContentType, string(headers), string(record.Body), record.Key)
+	if err != nil {
+		return models.InternalError("Unable to save idempotent response", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes reserved key without stored response, so the request can be retried.
+func (s sqlStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"DELETE FROM idempotency_keys WHERE key = $1 AND status = 0"), key)
+	if err != nil {
+		return models.InternalError("Unable to release idempotency key", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes keys created before given unix timestamp and returns their number.
+func (s sqlStore) PurgeIdempotencyKeys(ctx context.Context, before int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"DELETE FROM idempotency_keys WHERE created < $1"), before)
+	if err != nil {
+		return 0, models.InternalError("Unable to purge idempotency keys", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.InternalError("Unable to purge idempotency keys", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,1376 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepo keeps to-do items in memory. Safe for concurrent use.
+// Intended for local development and tests: all data is lost on restart.
+// Items keep names of their tags, so tag changes are applied to every tagged item.
+// Progress and blocked flag of items aren't stored, they are computed on every read.
+// Deleted items are moved from items to trash, which all reads except GetTrash ignore.
+type MemoryRepo struct {
+	mu            sync.RWMutex
+	items         map[int64]models.ToDo
+	trash         map[int64]models.ToDo
+	nextID        int64
+	tags          map[int64]models.Tag
+	nextTagID     int64
+	projects      map[int64]models.Project
+	nextProjectID int64
+	// dependencies map ids of items to sets of ids of items blocking them.
+	dependencies map[int64]map[int64]bool
+	// events is audit log in order of recording.
+	events      []models.Event
+	nextEventID int64
+	// idempotencyKeys are requests made with Idempotency-Key header by their keys.
+	idempotencyKeys map[string]models.IdempotencyRecord
+	workflow        models.Workflow
+}
+
+// NewMemoryRepo constructs empty MemoryRepo object.
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		items:           make(map[int64]models.ToDo),
+		trash:           make(map[int64]models.ToDo),
+		nextID:          1,
+		tags:            make(map[int64]models.Tag),
+		nextTagID:       1,
+		projects:        make(map[int64]models.Project),
+		nextProjectID:   1,
+		dependencies:    make(map[int64]map[int64]bool),
+		nextEventID:     1,
+		idempotencyKeys: make(map[string]models.IdempotencyRecord),
+		workflow:        models.DefaultWorkflow(),
+	}
+}
+
+// Workflow returns statuses of to-do items and transitions allowed between them.
+func (r *MemoryRepo) Workflow() models.Workflow {
+	return r.workflow
+}
+
+// Close does nothing, memory storage has no connections to release.
+func (r *MemoryRepo) Close() error {
+	return nil
+}
+
+// GetToDos retrieves all to-dos within given parameters.
+// Mirrors TodoRepo.GetToDos: fields are validated against the same allow-list as SQL backends.
+func (r *MemoryRepo) GetToDos(_ context.Context, params *models.ParamsBag) (models.ToDoPage, error) {
+	order, err := preparePaging(params)
+	if err != nil {
+		return models.ToDoPage{}, err
+	}
+	items, err := r.filter(params)
+	if err != nil {
+		return models.ToDoPage{}, err
+	}
+	total := int64(len(items))
+
+	sort.Slice(items, func(i, j int) bool {
+		return order.compare(items[i], items[j]) < 0
+	})
+
+	// Apply pagination.
+	if cursor := params.Paging.Cursor; cursor != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			return order.compareTo(items[i], order.values, cursor.ID) > 0
+		})
+		items = items[start:]
+	}
+	if params.Paging.Offset > 0 {
+		if params.Paging.Offset >= len(items) {
+			return newPage(nil, total, params), nil
+		}
+		items = items[params.Paging.Offset:]
+	}
+	if limit := fetchLimit(params.Paging); limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return newPage(items, total, params), nil
+}
+
+// SearchToDos finds to-dos by words of the query. Mirrors TodoRepo.SearchToDos.
+func (r *MemoryRepo) SearchToDos(_ context.Context, query string, params *models.ParamsBag) (models.SearchPage, error) {
+	if params.Paging.Cursor != nil {
+		return models.SearchPage{}, models.ValidationError("Cursor pagination isn't supported for search", nil)
+	}
+	terms, err := searchTerms(query)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	items, err := r.filter(params)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	var results []models.SearchResult
+	for _, item := range items {
+		if rank, ok := matchText(item.Description, terms); ok {
+			results = append(results, models.SearchResult{ToDo: item, Rank: rank, Snippet: renderSnippet(highlight(item.Description, terms))})
+		}
+	}
+	total := int64(len(results))
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID < results[j].ID
+	})
+	if params.Paging.Offset > 0 {
+		if params.Paging.Offset >= len(results) {
+			return newSearchPage(nil, total, params.Paging), nil
+		}
+		results = results[params.Paging.Offset:]
+	}
+	if limit := fetchLimit(params.Paging); limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return newSearchPage(results, total, params.Paging), nil
+}
+
+// filter returns unordered items matching filters and search query of params.
+func (r *MemoryRepo) filter(params *models.ParamsBag) ([]models.ToDo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.matching(params)
+}
+
+// matching returns unordered items matching params. Caller must hold lock.
+func (r *MemoryRepo) matching(params *models.ParamsBag) ([]models.ToDo, error) {
+	conditions := make([]condition, len(params.Filter.Filters))
+	for i, filter := range params.Filter.Filters {
+		cond, err := compileFilter(todoColumns, filter)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = cond
+	}
+	var terms []string
+	if len(params.Search) != 0 {
+		var err error
+		if terms, err = searchTerms(params.Search); err != nil {
+			return nil, err
+		}
+	}
+
+	subtasks := r.subtaskIndex()
+	var items []models.ToDo
+	for _, item := range r.items {
+		if !matchesConditions(item, conditions) || !matchesTags(item, params.Tags) {
+			continue
+		}
+		if terms != nil {
+			if _, ok := matchText(item.Description, terms); !ok {
+				continue
+			}
+		}
+		item = detach(item)
+		r.fillDetails(&item, subtasks)
+		if params.Blocked != nil && item.Blocked != *params.Blocked {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// CreateToDo stores to-do item in memory. Actor is recorded in audit log.
+func (r *MemoryRepo) CreateToDo(_ context.Context, item *models.ToDo, actor string) (models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createToDo(item, actor)
+}
+
+// createToDo validates and stores to-do item. Caller must hold lock.
+func (r *MemoryRepo) createToDo(item *models.ToDo, actor string) (models.ToDo, error) {
+	if err := prepareNewItem(r.workflow, item); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := r.checkItemProject(item); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := r.checkItemParent(item, 0); err != nil {
+		return models.ToDo{}, err
+	}
+	return detach(r.store(item, actor)), nil
+}
+
+// store adds validated to-do item to the storage and records its creation. Caller must hold lock.
+func (r *MemoryRepo) store(item *models.ToDo, actor string) models.ToDo {
+	now := time.Now().Unix()
+	priority := priorityOf(*item)
+	stored := models.ToDo{
+		ID:          r.nextID,
+		Description: item.Description,
+		Status:      item.Status,
+		Created:     now,
+		Updated:     now,
+		StartAt:     copyInt64(item.StartAt),
+		DueAt:       copyInt64(item.DueAt),
+		Priority:    &priority,
+		Tags:        append([]string{}, item.Tags...),
+		ProjectID:   optionalID(item.ProjectID),
+		ParentID:    optionalID(item.ParentID),
+		Recurrence:  copyString(item.Recurrence),
+		Version:     1,
+	}
+	r.ensureTags(stored.Tags)
+	r.items[stored.ID] = stored
+	r.nextID++
+	r.record(newEvent(stored.ID, models.EventCreated, actor, models.DiffToDo(models.ToDo{}, stored)))
+	return stored
+}
+
+// GetToDo retrieves single to-do item by given id.
+func (r *MemoryRepo) GetToDo(_ context.Context, id int64) (models.ToDo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.items[id]
+	if !ok {
+		return models.ToDo{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	item = detach(item)
+	r.fillDetails(&item, r.subtaskIndex())
+	return item, nil
+}
+
+// GetToDoTree retrieves single to-do item by given id with all its subtasks nested in Children.
+func (r *MemoryRepo) GetToDoTree(_ context.Context, id int64) (models.ToDo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.items[id]; !ok {
+		return models.ToDo{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	subtasks := r.subtaskIndex()
+	var items []models.ToDo
+	for _, itemID := range subtree(id, subtasks) {
+		item := detach(r.items[itemID])
+		r.fillDetails(&item, subtasks)
+		items = append(items, item)
+	}
+	return buildTree(items, id), nil
+}
+
+// UpdateToDo replaces all editable fields of single to-do item by given id, missing fields are cleared.
+// Non-zero version must match current version of the item.
+// Item blocked by unfinished items can be finished only with force. Changed fields are recorded in audit log with actor.
+func (r *MemoryRepo) UpdateToDo(ctx context.Context, updatedItem *models.ToDo, id, version int64, force bool, actor string) (models.ToDo, error) {
+	return r.PatchToDo(ctx, id, models.Replacement(*updatedItem), version, force, actor)
+}
+
+// PatchToDo applies patch to single to-do item by given id.
+// Version, force and actor work the same way as in UpdateToDo.
+func (r *MemoryRepo) PatchToDo(_ context.Context, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.patchToDo(id, patch, version, force, actor)
+}
+
+// patchToDo applies patch to to-do item. Caller must hold lock.
+func (r *MemoryRepo) patchToDo(id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error) {
+	item, ok := r.items[id]
+	if !ok {
+		return models.ToDo{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	if err := checkVersion(id, version, item.Version); err != nil {
+		return models.ToDo{}, err
+	}
+
+	before := detach(item)
+	r.fillDetails(&before, r.subtaskIndex())
+	updatedItem, err := applyPatch(patch, before)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if err := checkUpdate(r.workflow, before, &updatedItem); err != nil {
+		return models.ToDo{}, err
+	}
+	if projectChanged(before, updatedItem) {
+		if err := r.checkItemProject(&updatedItem); err != nil {
+			return models.ToDo{}, err
+		}
+	}
+	if err := r.checkItemParent(&updatedItem, id); err != nil {
+		return models.ToDo{}, err
+	}
+	from := item.Status
+	if !force && !r.workflow.IsTerminal(from) && r.workflow.IsTerminal(updatedItem.Status) {
+		if err := blockedError(id, r.openBlockers(id)); err != nil {
+			return models.ToDo{}, err
+		}
+	}
+	item.Description = updatedItem.Description
+	item.Status = updatedItem.Status
+	item.StartAt = copyInt64(updatedItem.StartAt)
+	item.DueAt = copyInt64(updatedItem.DueAt)
+	priority := priorityOf(updatedItem)
+	item.Priority = &priority
+	item.Tags = append([]string{}, updatedItem.Tags...)
+	r.ensureTags(item.Tags)
+	item.ProjectID = optionalID(updatedItem.ProjectID)
+	item.ParentID = optionalID(updatedItem.ParentID)
+	item.Recurrence = copyString(updatedItem.Recurrence)
+	item.Updated = time.Now().Unix()
+	item.Version++
+	r.items[id] = item
+	if changes := models.DiffToDo(before, item); len(changes) != 0 {
+		r.record(newEvent(id, models.EventUpdated, actor, changes))
+	}
+	// Finishing recurring item schedules its next occurrence.
+	if next := nextOccurrence(r.workflow, from, item); next != nil {
+		// Archived project doesn't get new items, like in dropArchivedProject.
+		if next.ProjectID != nil {
+			if project, ok := r.projects[*next.ProjectID]; !ok || project.Archived {
+				next.ProjectID = nil
+			}
+		}
+		r.store(next, actor)
+	}
+	item = detach(item)
+	r.fillDetails(&item, r.subtaskIndex())
+	return item, nil
+}
+
+// DeleteToDo moves single to-do item by given id to trash, policy defines what happens to its subtasks.
+// Items in trash keep their dependencies, like in SQL backends. Changes are recorded in audit log with actor.
+func (r *MemoryRepo) DeleteToDo(_ context.Context, id int64, policy models.SubtaskPolicy, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deleteToDo(id, policy, actor)
+}
+
+// deleteToDo moves to-do item to trash. Caller must hold lock.
+func (r *MemoryRepo) deleteToDo(id int64, policy models.SubtaskPolicy, actor string) error {
+	if _, ok := r.items[id]; !ok {
+		return models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	subtasks := r.subtaskIndex()
+	ids := []int64{id}
+	switch policy {
+	case models.SubtasksCascade:
+		ids = subtree(id, subtasks)
+	case models.SubtasksDetach:
+		detached := append([]int64{}, subtasks[id]...)
+		sort.Slice(detached, func(i, j int) bool {
+			return detached[i] < detached[j]
+		})
+		for _, itemID := range detached {
+			item := r.items[itemID]
+			item.ParentID = nil
+			item.Version++
+			r.items[itemID] = item
+			r.record(newEvent(itemID, models.EventUpdated, actor, models.DiffToDo(models.ToDo{ParentID: &id}, models.ToDo{})))
+		}
+	default:
+		if len(subtasks[id]) > 0 {
+			return models.ConflictError(fmt.Sprintf("Item with id %d has subtasks", id), nil).WithCode(models.CodeHasSubtasks)
+		}
+	}
+	now := time.Now().Unix()
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	for _, itemID := range ids {
+		item := r.items[itemID]
+		item.DeletedAt = &now
+		item.Version++
+		r.trash[itemID] = item
+		delete(r.items, itemID)
+	}
+	r.record(trashEvents(ids, models.EventDeleted, actor, nil, &now)...)
+	return nil
+}
+
+// BulkToDos executes operations of the request in order. Mirrors TodoRepo.BulkToDos:
+// atomic request is rolled back to the state before it, if any operation fails.
+func (r *MemoryRepo) BulkToDos(_ context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var snapshot memorySnapshot
+	if request.Atomic() {
+		snapshot = r.snapshot()
+	}
+	results, ok := runBulk(request, func(op models.BulkOperation) (*models.ToDo, error) {
+		var item models.ToDo
+		var err error
+		switch op.Op {
+		case models.BulkCreate:
+			item, err = r.createToDo(op.Item, actor)
+		case models.BulkUpdate:
+			item, err = r.patchToDo(op.ID, op.Change(), op.Version, op.Force, actor)
+		default:
+			return nil, r.deleteToDo(op.ID, op.Subtasks, actor)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &item, nil
+	})
+	if !ok {
+		r.restore(snapshot)
+		return results, nil
+	}
+	// Details are computed after all operations, like SQL backends load them after commit.
+	subtasks := r.subtaskIndex()
+	for _, result := range results {
+		if result.Item != nil {
+			r.fillDetails(result.Item, subtasks)
+		}
+	}
+	return results, nil
+}
+
+// PatchToDos applies patch to every item matching filters of params. Mirrors TodoRepo.PatchToDos.
+func (r *MemoryRepo) PatchToDos(_ context.Context, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matching, err := r.matching(params)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ID < matching[j].ID
+	})
+	snapshot := r.snapshot()
+	items := make([]models.ToDo, 0, len(matching))
+	for _, item := range matching {
+		patched, err := r.patchToDo(item.ID, patch, 0, force, actor)
+		if err != nil {
+			r.restore(snapshot)
+			return nil, err
+		}
+		items = append(items, patched)
+	}
+	subtasks := r.subtaskIndex()
+	for i := range items {
+		r.fillDetails(&items[i], subtasks)
+	}
+	return items, nil
+}
+
+// memorySnapshot is state changed by operations on to-do items, it's restored when bulk changes fail.
+// Stored items and tags are replaced on change, never modified in place, so copies of maps are enough.
+type memorySnapshot struct {
+	items       map[int64]models.ToDo
+	trash       map[int64]models.ToDo
+	nextID      int64
+	tags        map[int64]models.Tag
+	nextTagID   int64
+	events      int
+	nextEventID int64
+}
+
+// snapshot saves current state. Caller must hold lock.
+func (r *MemoryRepo) snapshot() memorySnapshot {
+	return memorySnapshot{
+		items:       maps.Clone(r.items),
+		trash:       maps.Clone(r.trash),
+		nextID:      r.nextID,
+		tags:        maps.Clone(r.tags),
+		nextTagID:   r.nextTagID,
+		events:      len(r.events),
+		nextEventID: r.nextEventID,
+	}
+}
+
+// restore brings back saved state. Caller must hold lock.
+func (r *MemoryRepo) restore(snapshot memorySnapshot) {
+	r.items, r.trash, r.nextID = snapshot.items, snapshot.trash, snapshot.nextID
+	r.tags, r.nextTagID = snapshot.tags, snapshot.nextTagID
+	r.events, r.nextEventID = r.events[:snapshot.events], snapshot.nextEventID
+}
+
+// GetTrash retrieves items in trash, most recently deleted first.
+func (r *MemoryRepo) GetTrash(_ context.Context) ([]models.ToDo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]models.ToDo, 0, len(r.trash))
+	subtasks := r.subtaskIndex()
+	for _, item := range r.trash {
+		item = detach(item)
+		r.fillDetails(&item, subtasks)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if *items[i].DeletedAt != *items[j].DeletedAt {
+			return *items[i].DeletedAt > *items[j].DeletedAt
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// RestoreToDo moves item with given id out of trash together with subtasks deleted along with it.
+// If parent of the item is still in trash, the item becomes top-level. Restored items are recorded in audit log with actor.
+func (r *MemoryRepo) RestoreToDo(_ context.Context, id int64, actor string) (models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.trash[id]
+	if !ok {
+		return models.ToDo{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d in trash", id), nil)
+	}
+	deletedAt := *item.DeletedAt
+	var ids []int64
+	for _, itemID := range subtree(id, subtaskIndexOf(r.trash)) {
+		if *r.trash[itemID].DeletedAt == deletedAt {
+			ids = append(ids, itemID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	for _, itemID := range ids {
+		restored := r.trash[itemID]
+		restored.DeletedAt = nil
+		restored.Version++
+		r.items[itemID] = restored
+		delete(r.trash, itemID)
+	}
+	events := trashEvents(ids, models.EventRestored, actor, &deletedAt, nil)
+	item = r.items[id]
+	if item.ParentID != nil {
+		if _, ok := r.trash[*item.ParentID]; ok {
+			i := sort.Search(len(ids), func(i int) bool {
+				return ids[i] >= id
+			})
+			events[i].Changes = append(events[i].Changes, models.DiffToDo(models.ToDo{ParentID: item.ParentID}, models.ToDo{})...)
+			item.ParentID = nil
+			item.Version++
+			r.items[id] = item
+		}
+	}
+	r.record(events...)
+	item = detach(item)
+	r.fillDetails(&item, r.subtaskIndex())
+	return item, nil
+}
+
+// PurgeToDo permanently deletes item with given id from trash together with its subtasks in trash.
+// Their history stays in audit log, purging and detaching of remaining subtasks is recorded there with actor.
+func (r *MemoryRepo) PurgeToDo(_ context.Context, id int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.trash[id]; !ok {
+		return models.NotFoundError(fmt.Sprintf("Unable to find item with id %d in trash", id), nil)
+	}
+	r.purge(subtree(id, subtaskIndexOf(r.trash)), actor)
+	return nil
+}
+
+// PurgeTrash permanently deletes items moved to trash before given unix timestamp and returns their number.
+// Purging is recorded in audit log with models.SystemActor.
+func (r *MemoryRepo) PurgeTrash(_ context.Context, before int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []int64
+	for id, item := range r.trash {
+		if *item.DeletedAt < before {
+			ids = append(ids, id)
+		}
+	}
+	r.purge(ids, models.SystemActor)
+	return int64(len(ids)), nil
+}
+
+// ReserveIdempotencyKey stores key of the record for a new request, see sqlStore.ReserveIdempotencyKey.
+func (r *MemoryRepo) ReserveIdempotencyKey(_ context.Context, record models.IdempotencyRecord, since int64) (models.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.idempotencyKeys[record.Key]; ok && stored.Created >= since {
+		return stored, false, nil
+	}
+	record.Status, record.ContentType, record.Headers, record.Body = 0, "", nil, nil
+	r.idempotencyKeys[record.Key] = record
+	return record, true, nil
+}
+
+// SaveIdempotencyResponse stores response of the request with reserved key of the record.
+func (r *MemoryRepo) SaveIdempotencyResponse(_ context.Context, record models.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.idempotencyKeys[record.Key]
+	if !ok {
+		return nil
+	}
+	stored.Status, stored.ContentType, stored.Body = record.Status, record.ContentType, slices.Clone(record.Body)
+	stored.Headers = maps.Clone(record.Headers)
+	r.idempotencyKeys[record.Key] = stored
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes reserved key without stored response, so the request can be retried.
+func (r *MemoryRepo) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.idempotencyKeys[key]; ok && !stored.Completed() {
+		delete(r.idempotencyKeys, key)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes keys created before given unix timestamp and returns their number.
+func (r *MemoryRepo) PurgeIdempotencyKeys(_ context.Context, before int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var purged int64
+	for key, record := range r.idempotencyKeys {
+		if record.Created < before {
+			delete(r.idempotencyKeys, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purge permanently deletes items in trash with given ids, remaining subtasks become top-level.
+// Purging and detaching are recorded in audit log with actor. Caller must hold write lock.
+func (r *MemoryRepo) purge(ids []int64, actor string) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		r.unlink(id)
+		delete(r.trash, id)
+	}
+	var detached []int64
+	for id, item := range r.trash {
+		if item.ParentID != nil && slices.Contains(ids, *item.ParentID) {
+			detached = append(detached, id)
+		}
+	}
+	slices.Sort(detached)
+	for _, id := range detached {
+		item := r.trash[id]
+		r.record(newEvent(id, models.EventUpdated, actor, models.DiffToDo(models.ToDo{ParentID: item.ParentID}, models.ToDo{})))
+		item.ParentID = nil
+		item.Version++
+		r.trash[id] = item
+	}
+	r.record(purgeEvents(ids, actor)...)
+}
+
+// GetHistory retrieves all changes of the item with given id in chronological order.
+func (r *MemoryRepo) GetHistory(_ context.Context, id int64) ([]models.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.matchingEvents(models.EventFilter{TodoID: id}), nil
+}
+
+// GetAuditLog retrieves changes of all items matching the filter, most recent first.
+func (r *MemoryRepo) GetAuditLog(_ context.Context, filter models.EventFilter) ([]models.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	events := r.matchingEvents(filter)
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if filter.Limit > 0 {
+		events = events[min(filter.Offset, len(events)):]
+		events = events[:min(filter.Limit, len(events))]
+	}
+	return events, nil
+}
+
+// matchingEvents returns events matching the filter in chronological order, pagination is ignored. Caller must hold lock.
+func (r *MemoryRepo) matchingEvents(filter models.EventFilter) []models.Event {
+	events := []models.Event{}
+	for _, event := range r.events {
+		if (filter.TodoID != 0 && event.TodoID != filter.TodoID) ||
+			(len(filter.Actor) != 0 && event.Actor != filter.Actor) ||
+			(len(filter.Action) != 0 && event.Action != filter.Action) ||
+			(filter.Since != nil && event.Created < *filter.Since) ||
+			(filter.Until != nil && event.Created > *filter.Until) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// record appends events to audit log. Changes are stored the way SQL backends return them (decoded JSON),
+// so all storages report the same values. Caller must hold write lock.
+func (r *MemoryRepo) record(events ...models.Event) {
+	for _, event := range events {
+		if data, err := json.Marshal(event.Changes); err == nil {
+			event.Changes = nil
+			_ = json.Unmarshal(data, &event.Changes)
+		}
+		event.ID = r.nextEventID
+		r.nextEventID++
+		r.events = append(r.events, event)
+	}
+}
+
+// GetDependencies retrieves items blocking the item with given id and items it blocks.
+func (r *MemoryRepo) GetDependencies(_ context.Context, id int64) (models.Dependencies, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.items[id]; !ok {
+		return models.Dependencies{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	return r.dependenciesOf(id), nil
+}
+
+// AddDependency marks the item with given id as blocked by another item, like AddDependency of SQL backends.
+func (r *MemoryRepo) AddDependency(_ context.Context, id, blockerID int64) (models.Dependencies, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[id]; !ok {
+		return models.Dependencies{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), nil)
+	}
+	if id == blockerID {
+		return models.Dependencies{}, models.ValidationError("Item can't block itself", nil)
+	}
+	if _, ok := r.items[blockerID]; !ok {
+		return models.Dependencies{}, models.ValidationError(fmt.Sprintf("Unable to find blocking item with id %d", blockerID), nil)
+	}
+	if !r.dependencies[id][blockerID] {
+		// Walk all blockers of the blocker, the item must not be among them.
+		seen := map[int64]bool{blockerID: true}
+		for queue := []int64{blockerID}; len(queue) > 0; queue = queue[1:] {
+			for blocker := range r.dependencies[queue[0]] {
+				if blocker == id {
+					return models.Dependencies{}, models.ValidationError(fmt.Sprintf("Item with id %d is already blocked by item with id %d", blockerID, id), nil).
+						WithCode(models.CodeDependencyCycle)
+				}
+				if !seen[blocker] {
+					seen[blocker] = true
+					queue = append(queue, blocker)
+				}
+			}
+		}
+		if r.dependencies[id] == nil {
+			r.dependencies[id] = make(map[int64]bool)
+		}
+		r.dependencies[id][blockerID] = true
+	}
+	return r.dependenciesOf(id), nil
+}
+
+// RemoveDependency unblocks the item with given id from another item.
+func (r *MemoryRepo) RemoveDependency(_ context.Context, id, blockerID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dependencies[id][blockerID] {
+		return models.NotFoundError(fmt.Sprintf("Item with id %d isn't blocked by item with id %d", id, blockerID), nil)
+	}
+	delete(r.dependencies[id], blockerID)
+	return nil
+}
+
+// dependenciesOf collects blockers and blocked items ordered by id. Caller must hold lock.
+func (r *MemoryRepo) dependenciesOf(id int64) models.Dependencies {
+	dependencies := models.Dependencies{BlockedBy: []models.ToDo{}, Blocks: []models.ToDo{}}
+	subtasks := r.subtaskIndex()
+	for _, itemID := range r.sortedIDs() {
+		item := detach(r.items[itemID])
+		if r.dependencies[id][itemID] {
+			r.fillDetails(&item, subtasks)
+			dependencies.BlockedBy = append(dependencies.BlockedBy, item)
+		}
+		if r.dependencies[itemID][id] {
+			r.fillDetails(&item, subtasks)
+			dependencies.Blocks = append(dependencies.Blocks, item)
+		}
+	}
+	return dependencies
+}
+
+// openBlockers returns ordered ids of items blocking the item, that aren't finished yet.
+// Items in trash don't block. Caller must hold lock.
+func (r *MemoryRepo) openBlockers(id int64) []int64 {
+	var blockers []int64
+	for blocker := range r.dependencies[id] {
+		if item, ok := r.items[blocker]; ok && !r.workflow.IsTerminal(item.Status) {
+			blockers = append(blockers, blocker)
+		}
+	}
+	sort.Slice(blockers, func(i, j int) bool {
+		return blockers[i] < blockers[j]
+	})
+	return blockers
+}
+
+// unlink removes all dependencies of the item being purged. Caller must hold lock.
+func (r *MemoryRepo) unlink(id int64) {
+	delete(r.dependencies, id)
+	for _, blockers := range r.dependencies {
+		delete(blockers, id)
+	}
+}
+
+// sortedIDs returns ids of all items in ascending order. Caller must hold lock.
+func (r *MemoryRepo) sortedIDs() []int64 {
+	ids := make([]int64, 0, len(r.items))
+	for id := range r.items {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// fillDetails computes progress and blocked flag of the item. Caller must hold lock.
+func (r *MemoryRepo) fillDetails(item *models.ToDo, subtasks map[int64][]int64) {
+	item.Progress = r.progressOf(item.ID, subtasks)
+	item.Blocked = len(r.openBlockers(item.ID)) > 0
+}
+
+// checkItemParent validates parent of to-do item like checkItemParent of SQL backends. Caller must hold lock.
+func (r *MemoryRepo) checkItemParent(item *models.ToDo, id int64) error {
+	if item.ParentID == nil || *item.ParentID == 0 {
+		return nil
+	}
+	parentID := *item.ParentID
+	if parentID == id {
+		return models.ValidationError("Item can't be subtask of itself", nil)
+	}
+	if _, ok := r.items[parentID]; !ok {
+		return models.ValidationError(fmt.Sprintf("Unable to find parent item with id %d", parentID), nil)
+	}
+	// Walk up from the new parent, the item must not be among its ancestors.
+	for ancestor := r.items[parentID].ParentID; ancestor != nil; ancestor = r.items[*ancestor].ParentID {
+		if *ancestor == id {
+			return models.ValidationError(fmt.Sprintf("Item with id %d is subtask of item with id %d", parentID, id), nil)
+		}
+	}
+	return nil
+}
+
+// subtaskIndex maps ids of items to ids of their direct subtasks. Caller must hold lock.
+func (r *MemoryRepo) subtaskIndex() map[int64][]int64 {
+	return subtaskIndexOf(r.items)
+}
+
+// subtaskIndexOf maps ids of given items to ids of their direct subtasks among them.
+func subtaskIndexOf(items map[int64]models.ToDo) map[int64][]int64 {
+	subtasks := make(map[int64][]int64)
+	for id, item := range items {
+		if item.ParentID != nil {
+			subtasks[*item.ParentID] = append(subtasks[*item.ParentID], id)
+		}
+	}
+	return subtasks
+}
+
+// progressOf computes progress of item over all its subtasks, nil for items without subtasks. Caller must hold lock.
+func (r *MemoryRepo) progressOf(id int64, subtasks map[int64][]int64) *models.Progress {
+	ids := subtree(id, subtasks)[1:]
+	if len(ids) == 0 {
+		return nil
+	}
+	progress := &models.Progress{Total: int64(len(ids))}
+	for _, itemID := range ids {
+		if r.workflow.IsTerminal(r.items[itemID].Status) {
+			progress.Done++
+		}
+	}
+	return progress
+}
+
+// subtree returns id of the item followed by ids of all its subtasks, including nested ones.
+func subtree(id int64, subtasks map[int64][]int64) []int64 {
+	ids := []int64{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, subtasks[ids[i]]...)
+	}
+	return ids
+}
+
+// matchesConditions checks item against all conditions, the same way SQL backends do.
+func matchesConditions(item models.ToDo, conditions []condition) bool {
+	for _, cond := range conditions {
+		if !cond.matches(fieldValue(item, cond.field)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) matches(value any) bool {
+	// NULL never matches, like in SQL.
+	if value == nil {
+		return false
+	}
+	switch c.operator {
+	case models.OpIn:
+		for _, candidate := range c.values {
+			if value == candidate {
+				return true
+			}
+		}
+		return false
+	case models.OpLike:
+		return strings.Contains(strings.ToLower(value.(string)), strings.ToLower(c.values[0].(string)))
+	}
+	cmp := compareValues(value, c.values[0])
+	switch c.operator {
+	case models.OpNe:
+		return cmp != 0
+	case models.OpGt:
+		return cmp > 0
+	case models.OpGte:
+		return cmp >= 0
+	case models.OpLt:
+		return cmp < 0
+	case models.OpLte:
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// compareValues compares two column values of the same type, returns -1, 0 or 1.
+func compareValues(a, b any) int {
+	if number, ok := a.(int64); ok {
+		return compareInt(number, b.(int64))
+	}
+	return strings.Compare(a.(string), b.(string))
+}
+
+// compare returns position of item a relative to item b in the ordering: -1, 0 or 1.
+func (o pageOrder) compare(a, b models.ToDo) int {
+	values := make([]any, len(o.keys))
+	for i, key := range o.keys {
+		values[i] = sortValue(b, key.field)
+	}
+	return o.compareTo(a, values, b.ID)
+}
+
+// compareTo returns position of item relative to the row with given key values and id in the ordering: -1, 0 or 1.
+func (o pageOrder) compareTo(item models.ToDo, values []any, id int64) int {
+	for i, key := range o.keys {
+		cmp := compareValues(sortValue(item, key.field), values[i])
+		if !key.asc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	cmp := compareInt(item.ID, id)
+	if !o.idASC {
+		cmp = -cmp
+	}
+	return cmp
+}
+
+// detach returns copy of stored item, that doesn't share memory with the storage.
+func detach(item models.ToDo) models.ToDo {
+	item.StartAt = copyInt64(item.StartAt)
+	item.DueAt = copyInt64(item.DueAt)
+	item.Priority = copyPriority(item.Priority)
+	item.Tags = append([]string{}, item.Tags...)
+	item.ProjectID = copyInt64(item.ProjectID)
+	item.ParentID = copyInt64(item.ParentID)
+	item.Recurrence = copyString(item.Recurrence)
+	item.DeletedAt = copyInt64(item.DeletedAt)
+	return item
+}
+
+// copyInt64 copies pointed value, so stored items don't share memory with callers.
+func copyInt64(value *int64) *int64 {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func copyString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func copyPriority(value *models.Priority) *models.Priority {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// matchesTags checks that item has any (or all) of the tags of the filter.
+func matchesTags(item models.ToDo, filter models.TagFilter) bool {
+	if len(filter.Names) == 0 {
+		return true
+	}
+	matched := 0
+	for _, name := range filter.Names {
+		if hasTag(item, name) {
+			matched++
+		}
+	}
+	if filter.MatchAll {
+		return matched == len(filter.Names)
+	}
+	return matched > 0
+}
+
+func hasTag(item models.ToDo, name string) bool {
+	for _, tag := range item.Tags {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTags retrieves all tags ordered by name with number of tagged items.
+func (r *MemoryRepo) GetTags(_ context.Context) ([]models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tags := make([]models.Tag, 0, len(r.tags))
+	for _, tag := range r.tags {
+		tags = append(tags, r.countTag(tag))
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// GetTag retrieves single tag by given id.
+func (r *MemoryRepo) GetTag(_ context.Context, id int64) (models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tag, ok := r.tags[id]
+	if !ok {
+		return models.Tag{}, models.NotFoundError(fmt.Sprintf("Unable to find tag with id %d", id), nil)
+	}
+	return r.countTag(tag), nil
+}
+
+// CreateTag stores new tag, names are unique.
+func (r *MemoryRepo) CreateTag(_ context.Context, tag *models.Tag) (models.Tag, error) {
+	if err := normalizeTag(tag); err != nil {
+		return models.Tag{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tagID(tag.Name); ok {
+		return models.Tag{}, models.ConflictError(fmt.Sprintf("Tag %s already exists", tag.Name), nil).WithCode(models.CodeTagExists)
+	}
+	stored := models.Tag{ID: r.nextTagID, Name: tag.Name, Color: tag.Color}
+	r.tags[stored.ID] = stored
+	r.nextTagID++
+	return stored, nil
+}
+
+// UpdateTag renames tag and/or changes its color. Blank fields keep their old values.
+func (r *MemoryRepo) UpdateTag(_ context.Context, tag *models.Tag, id int64) (models.Tag, error) {
+	if err := normalizeTagUpdate(tag); err != nil {
+		return models.Tag{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tags[id]
+	if !ok {
+		return models.Tag{}, models.NotFoundError(fmt.Sprintf("Unable to find tag with id %d", id), nil)
+	}
+	if len(tag.Name) != 0 && tag.Name != stored.Name {
+		if _, ok := r.tagID(tag.Name); ok {
+			return models.Tag{}, models.ConflictError(fmt.Sprintf("Tag %s already exists", tag.Name), nil).WithCode(models.CodeTagExists)
+		}
+		r.replaceItemTag(stored.Name, tag.Name)
+		stored.Name = tag.Name
+	}
+	if len(tag.Color) != 0 {
+		stored.Color = tag.Color
+	}
+	r.tags[id] = stored
+	return r.countTag(stored), nil
+}
+
+// DeleteTag deletes tag by given id, tagged items lose the tag. Change of their tags is recorded in audit log with actor.
+func (r *MemoryRepo) DeleteTag(_ context.Context, id int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tag, ok := r.tags[id]
+	if !ok {
+		return models.NotFoundError(fmt.Sprintf("Unable to find tag with id %d", id), nil)
+	}
+	r.retagItems(tag.Name, "", actor)
+	delete(r.tags, id)
+	return nil
+}
+
+// MergeTags moves all items of source tag to target tag and deletes source tag.
+// Change of tags of the items is recorded in audit log with actor.
+func (r *MemoryRepo) MergeTags(_ context.Context, sourceID, targetID int64, actor string) (models.Tag, error) {
+	if sourceID == targetID {
+		return models.Tag{}, models.ValidationError("Unable to merge tag into itself", nil)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source, ok := r.tags[sourceID]
+	if !ok {
+		return models.Tag{}, models.NotFoundError(fmt.Sprintf("Unable to find tag with id %d", sourceID), nil)
+	}
+	target, ok := r.tags[targetID]
+	if !ok {
+		return models.Tag{}, models.NotFoundError(fmt.Sprintf("Unable to find tag with id %d", targetID), nil)
+	}
+	r.retagItems(source.Name, target.Name, actor)
+	delete(r.tags, sourceID)
+	return r.countTag(target), nil
+}
+
+// ensureTags creates missing tags with given names. Caller must hold write lock.
+func (r *MemoryRepo) ensureTags(names []string) {
+	for _, name := range names {
+		if _, ok := r.tagID(name); !ok {
+			r.tags[r.nextTagID] = models.Tag{ID: r.nextTagID, Name: name}
+			r.nextTagID++
+		}
+	}
+}
+
+func (r *MemoryRepo) tagID(name string) (int64, bool) {
+	for id, tag := range r.tags {
+		if tag.Name == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// countTag returns tag with number of tagged items. Caller must hold lock.
+func (r *MemoryRepo) countTag(tag models.Tag) models.Tag {
+	tag.Count = 0
+	for _, item := range r.items {
+		if hasTag(item, tag.Name) {
+			tag.Count++
+		}
+	}
+	return tag
+}
+
+// replaceItemTag replaces tag name in every item including ones in trash, blank replacement removes the tag.
+// Caller must hold write lock.
+// retagItems replaces tag of items like replaceItemTag, the items get new version and change of their tags
+// is recorded in audit log with actor. Caller must hold write lock.
+func (r *MemoryRepo) retagItems(name, replacement, actor string) {
+	before := make(map[int64]models.ToDo)
+	for _, items := range []map[int64]models.ToDo{r.items, r.trash} {
+		for id, item := range items {
+			if hasTag(item, name) {
+				before[id] = item
+			}
+		}
+	}
+	r.replaceItemTag(name, replacement)
+	for _, id := range slices.Sorted(maps.Keys(before)) {
+		items := r.items
+		if _, ok := r.trash[id]; ok {
+			items = r.trash
+		}
+		item := items[id]
+		r.record(newEvent(id, models.EventUpdated, actor,
+			models.DiffToDo(models.ToDo{Tags: before[id].Tags}, models.ToDo{Tags: item.Tags})))
+		item.Version++
+		items[id] = item
+	}
+}
+
+func (r *MemoryRepo) replaceItemTag(name, replacement string) {
+	for _, items := range []map[int64]models.ToDo{r.items, r.trash} {
+		for id, item := range items {
+			if !hasTag(item, name) {
+				continue
+			}
+			tags := make([]string, 0, len(item.Tags))
+			for _, tag := range item.Tags {
+				if tag != name && tag != replacement {
+					tags = append(tags, tag)
+				}
+			}
+			if len(replacement) != 0 {
+				tags = append(tags, replacement)
+				sort.Strings(tags)
+			}
+			item.Tags = tags
+			items[id] = item
+		}
+	}
+}
+
+// GetProjects retrieves active (or archived) projects ordered by name.
+func (r *MemoryRepo) GetProjects(_ context.Context, archived bool) ([]models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	projects := []models.Project{}
+	for _, project := range r.projects {
+		if project.Archived == archived {
+			projects = append(projects, r.countProject(project))
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+// GetProject retrieves single project by given id.
+func (r *MemoryRepo) GetProject(_ context.Context, id int64) (models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	project, ok := r.projects[id]
+	if !ok {
+		return models.Project{}, models.NotFoundError(fmt.Sprintf("Unable to find project with id %d", id), nil)
+	}
+	return r.countProject(project), nil
+}
+
+// CreateProject stores new project.
+func (r *MemoryRepo) CreateProject(_ context.Context, project *models.Project) (models.Project, error) {
+	if err := models.NormalizeProject(project, false); err != nil {
+		return models.Project{}, models.ValidationError(err.Error(), err)
+	}
+	now := time.Now().Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := models.Project{
+		ID:          r.nextProjectID,
+		Name:        project.Name,
+		Description: project.Description,
+		Color:       project.Color,
+		Created:     now,
+		Updated:     now,
+	}
+	r.projects[stored.ID] = stored
+	r.nextProjectID++
+	return stored, nil
+}
+
+// UpdateProject updates name, description and/or color of the project. Blank fields keep their old values.
+func (r *MemoryRepo) UpdateProject(_ context.Context, project *models.Project, id int64) (models.Project, error) {
+	if err := models.NormalizeProject(project, true); err != nil {
+		return models.Project{}, models.ValidationError(err.Error(), err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.projects[id]
+	if !ok {
+		return models.Project{}, models.NotFoundError(fmt.Sprintf("Unable to find project with id %d", id), nil)
+	}
+	if len(project.Name) != 0 {
+		stored.Name = project.Name
+	}
+	if len(project.Description) != 0 {
+		stored.Description = project.Description
+	}
+	if len(project.Color) != 0 {
+		stored.Color = project.Color
+	}
+	stored.Updated = time.Now().Unix()
+	r.projects[id] = stored
+	return r.countProject(stored), nil
+}
+
+// ArchiveProject archives or restores the project. Items of archived project are kept, but new ones can't be added.
+func (r *MemoryRepo) ArchiveProject(_ context.Context, id int64, archived bool) (models.Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.projects[id]
+	if !ok {
+		return models.Project{}, models.NotFoundError(fmt.Sprintf("Unable to find project with id %d", id), nil)
+	}
+	stored.Archived = archived
+	stored.Updated = time.Now().Unix()
+	r.projects[id] = stored
+	return r.countProject(stored), nil
+}
+
+// DeleteProject deletes the project, its items are kept outside of projects.
+// Removing of the project from items is recorded in audit log with actor.
+func (r *MemoryRepo) DeleteProject(_ context.Context, id int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[id]; !ok {
+		return models.NotFoundError(fmt.Sprintf("Unable to find project with id %d", id), nil)
+	}
+	var detached []int64
+	for _, items := range []map[int64]models.ToDo{r.items, r.trash} {
+		for itemID, item := range items {
+			if item.ProjectID != nil && *item.ProjectID == id {
+				detached = append(detached, itemID)
+			}
+		}
+	}
+	slices.Sort(detached)
+	for _, itemID := range detached {
+		items := r.items
+		if _, ok := r.trash[itemID]; ok {
+			items = r.trash
+		}
+		item := items[itemID]
+		r.record(newEvent(itemID, models.EventUpdated, actor, models.DiffToDo(models.ToDo{ProjectID: item.ProjectID}, models.ToDo{})))
+		item.ProjectID = nil
+		item.Version++
+		items[itemID] = item
+	}
+	delete(r.projects, id)
+	return nil
+}
+
+// checkItemProject validates project of to-do item like checkItemProject of SQL backends. Caller must hold lock.
+func (r *MemoryRepo) checkItemProject(item *models.ToDo) error {
+	if item.ProjectID == nil || *item.ProjectID == 0 {
+		return nil
+	}
+	project, ok := r.projects[*item.ProjectID]
+	if !ok {
+		return models.ValidationError(fmt.Sprintf("Unable to find project with id %d", *item.ProjectID), nil)
+	}
+	if project.Archived {
+		return models.ValidationError(fmt.Sprintf("Project with id %d is archived", *item.ProjectID), nil)
+	}
+	return nil
+}
+
+// countProject returns project with number of its items. Caller must hold lock.
+func (r *MemoryRepo) countProject(project models.Project) models.Project {
+	project.Count = 0
+	for _, item := range r.items {
+		if item.ProjectID != nil && *item.ProjectID == project.ID {
+			project.Count++
+		}
+	}
+	return project
+}
+
+// optionalID returns copy of optional reference of to-do item (project, parent), 0 means none.
+func optionalID(id *int64) *int64 {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return copyInt64(id)
+}
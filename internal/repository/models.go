@@ -14,4 +14,12 @@ type Todo struct {
 	Status      sql.NullString
 	Created     sql.NullInt64
 	Updated     sql.NullInt64
+	StartAt     sql.NullInt64
+	DueAt       sql.NullInt64
+	Priority    int16
+	ProjectID   sql.NullInt64
+	ParentID    sql.NullInt64
+	Recurrence  sql.NullString
+	DeletedAt   sql.NullInt64
+	Version     int64
 }
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+)
+
+// sortKey is single key of ordering.
+type sortKey struct {
+	field string
+	asc   bool
+}
+
+// defaultSortKeys are applied when request doesn't specify sorting: the most important and urgent items first.
+var defaultSortKeys = []sortKey{{field: "priority", asc: false}, {field: "due_at", asc: true}}
+
+// pageOrder is ordering used to fetch a page: sort keys followed by id as the last tie-breaker.
+// Backward cursors reverse all directions, fetched items are reversed back by newPage.
+type pageOrder struct {
+	keys  []sortKey
+	idASC bool
+	// values are cursor's values of keys, converted to column types.
+	values []any
+}
+
+// sortKeys returns keys (except id) and id direction for requested sorting.
+func sortKeys(sort models.SortParams) ([]sortKey, bool, error) {
+	switch sort.Field {
+	case "":
+		return defaultSortKeys, true, nil
+	case "id":
+		// With sorting by id there's no separate tie-breaker, it follows sort direction.
+		return nil, sort.ASC, nil
+	}
+	if _, ok := todoColumns[sort.Field]; !ok {
+		return nil, false, models.ValidationError(fmt.Sprintf("Unknown sort field %s", sort.Field), nil)
+	}
+	return []sortKey{{field: sort.Field, asc: sort.ASC}}, true, nil
+}
+
+// preparePaging validates sorting and pagination params and returns ordering for the page query.
+func preparePaging(params *models.ParamsBag) (pageOrder, error) {
+	keys, idASC, err := sortKeys(params.Sort)
+	if err != nil {
+		return pageOrder{}, err
+	}
+	order := pageOrder{keys: keys, idASC: idASC}
+
+	cursor := params.Paging.Cursor
+	if cursor == nil {
+		return order, nil
+	}
+	if params.Paging.Offset > 0 {
+		return pageOrder{}, models.ValidationError("Cursor can't be combined with offset", nil)
+	}
+	if cursor.Field != params.Sort.Field || cursor.ASC != params.Sort.ASC || len(cursor.Values) != len(keys) {
+		return pageOrder{}, models.ValidationError("Cursor doesn't match requested sorting", nil)
+	}
+	order.keys = make([]sortKey, len(keys))
+	for i, key := range keys {
+		value, err := convertValue(todoColumns[key.field].kind, key.field, cursor.Values[i])
+		if err != nil {
+			return pageOrder{}, err
+		}
+		order.values = append(order.values, value)
+		order.keys[i] = sortKey{field: key.field, asc: key.asc != cursor.Backward}
+	}
+	order.idASC = idASC != cursor.Backward
+	return order, nil
+}
+
+// fetchLimit returns number of rows to fetch: one extra row tells if there are more items.
+// Zero means no limit.
+func fetchLimit(paging models.PaginationParams) int {
+	if paging.Limit <= 0 {
+		return 0
+	}
+	return paging.Limit + 1
+}
+
+// newPage builds page from items fetched in pageOrder, with up to fetchLimit rows.
+func newPage(items []models.ToDo, total int64, params *models.ParamsBag) models.ToDoPage {
+	page := models.ToDoPage{Items: items, Total: total}
+	limit := params.Paging.Limit
+	if limit <= 0 {
+		return page
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		page.Items = items[:limit]
+	}
+
+	cursor := params.Paging.Cursor
+	hasNext, hasPrev := hasMore, cursor != nil || params.Paging.Offset > 0
+	if cursor != nil && cursor.Backward {
+		for i, j := 0, len(page.Items)-1; i < j; i, j = i+1, j-1 {
+			page.Items[i], page.Items[j] = page.Items[j], page.Items[i]
+		}
+		hasNext, hasPrev = true, hasMore
+	}
+	if len(page.Items) == 0 {
+		return page
+	}
+	page.HasMore = hasNext
+	if hasNext {
+		page.NextCursor = cursorAt(page.Items[len(page.Items)-1], params.Sort, false).Encode()
+	}
+	if hasPrev {
+		page.PrevCursor = cursorAt(page.Items[0], params.Sort, true).Encode()
+	}
+	return page
+}
+
+func cursorAt(item models.ToDo, sort models.SortParams, backward bool) models.Cursor {
+	keys, _, _ := sortKeys(sort)
+	cursor := models.Cursor{Field: sort.Field, ASC: sort.ASC, ID: item.ID, Backward: backward}
+	for _, key := range keys {
+		cursor.Values = append(cursor.Values, fmt.Sprint(sortValue(item, key.field)))
+	}
+	return cursor
+}
+
+// sortValue returns column value of the item as it's used for ordering.
+func sortValue(item models.ToDo, field string) any {
+	value := fieldValue(item, field)
+	if value == nil && todoColumns[field].nullable {
+		return int64(nullSortValue)
+	}
+	return value
+}
+
+// fieldValue returns column value of the item, nil for NULL.
+func fieldValue(item models.ToDo, field string) any {
+	switch field {
+	case "id":
+		return item.ID
+	case "description":
+		return item.Description
+	case "status":
+		return item.Status
+	case "created":
+		return item.Created
+	case "updated":
+		return item.Updated
+	case "start_at":
+		return nullableValue(item.StartAt)
+	case "due_at":
+		return nullableValue(item.DueAt)
+	case "priority":
+		return int64(priorityOf(item))
+	case "project_id":
+		return nullableValue(item.ProjectID)
+	case "parent_id":
+		return nullableValue(item.ParentID)
+	}
+	return nil
+}
+
+func nullableValue(value *int64) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// priorityOf returns priority of the item, items without one have no priority.
+func priorityOf(item models.ToDo) models.Priority {
+	if item.Priority == nil {
+		return models.PriorityNone
+	}
+	return *item.Priority
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// projectColumns are columns of "projects" as expected by scanProject, with number of items in the project.
+const projectColumns = "projects.id, projects.name, projects.description, projects.color, projects.archived, " +
+	"projects.created, projects.updated, (SELECT COUNT(*) FROM todos WHERE todos.project_id = projects.id AND todos.deleted_at IS NULL)"
+
+// GetProjects retrieves active (or archived) projects ordered by name.
+func (s sqlStore) GetProjects(ctx context.Context, archived bool) ([]models.Project, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		"SELECT "+projectColumns+" FROM projects WHERE archived = $1 ORDER BY projects.name, projects.id"), archived)
+	if err != nil {
+		return nil, models.InternalError("Unable to get projects", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, models.InternalError("Unable to get projects", err)
+		}
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.InternalError("Unable to get projects", err)
+	}
+	return projects, nil
+}
+
+// GetProject retrieves single project by given id.
+func (s sqlStore) GetProject(ctx context.Context, id int64) (models.Project, error) {
+	return getProject(ctx, s.db, s.dialect, id)
+}
+
+// CreateProject writes new project.
+func (s sqlStore) CreateProject(ctx context.Context, project *models.Project) (models.Project, error) {
+	if err := models.NormalizeProject(project, false); err != nil {
+		return models.Project{}, models.ValidationError(err.Error(), err)
+	}
+	now := time.Now().Unix()
+	var id int64
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(
+		"INSERT INTO projects (name, description, color, archived, created, updated) VALUES ($1, $2, $3, $4, $5, $5) RETURNING id"),
+		project.Name, project.Description, nullString(project.Color), false, now).Scan(&id)
+	if err != nil {
+		return models.Project{}, models.InternalError("Unable to create project", err)
+	}
+	return getProject(ctx, s.db, s.dialect, id)
+}
+
+// UpdateProject updates name, description and/or color of the project. Blank fields keep their old values.
+func (s sqlStore) UpdateProject(ctx context.Context, project *models.Project, id int64) (models.Project, error) {
+	if err := models.NormalizeProject(project, true); err != nil {
+		return models.Project{}, models.ValidationError(err.Error(), err)
+	}
+	old, err := getProject(ctx, s.db, s.dialect, id)
+	if err != nil {
+		return models.Project{}, err
+	}
+	if len(project.Name) == 0 {
+		project.Name = old.Name
+	}
+	if len(project.Description) == 0 {
+		project.Description = old.Description
+	}
+	if len(project.Color) == 0 {
+		project.Color = old.Color
+	}
+	if _, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE projects SET name = $1, description = $2, color = $3, updated = $4 WHERE id = $5"),
+		project.Name, project.Description, nullString(project.Color), time.Now().Unix(), id); err != nil {
+		return models.Project{}, models.InternalError(fmt.Sprintf("Unable to update project with id %d", id), err)
+	}
+	return getProject(ctx, s.db, s.dialect, id)
+}
+
+// ArchiveProject archives or restores the project. Items of archived project are kept, but new ones can't be added.
+func (s sqlStore) ArchiveProject(ctx context.Context, id int64, archived bool) (models.Project, error) {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE projects SET archived = $1, updated = $2 WHERE id = $3"), archived, time.Now().Unix(), id)
+	if err != nil {
+		return models.Project{}, models.InternalError(fmt.Sprintf("Unable to update project with id %d", id), err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return models.Project{}, models.NotFoundError(fmt.Sprintf("Unable to find project with id %d", id), sql.ErrNoRows)
+	}
+	return getProject(ctx, s.db, s.dialect, id)
+}
+
+// DeleteProject deletes the project, its items are kept outside of projects.
+// Removing of the project from items is recorded in audit log with actor.
+func (s sqlStore) DeleteProject(ctx context.Context, id int64, actor string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := getProject(ctx, tx, s.dialect, id); err != nil {
+			return err
+		}
+		events, err := detachEvents(ctx, tx, s.dialect.rebind("SELECT id, project_id FROM todos WHERE project_id = $1 ORDER BY id"), []any{id}, actor,
+			func(projectID *int64) models.ToDo { return models.ToDo{ProjectID: projectID} })
+		if err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete project with id %d", id), err)
+		}
+		// Items are detached explicitly, as SQLite doesn't enforce foreign keys by default.
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind("UPDATE todos SET project_id = NULL, version = version + 1 WHERE project_id = $1"), id); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete project with id %d", id), err)
+		}
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM projects WHERE id = $1"), id); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete project with id %d", id), err)
+		}
+		return recordEvents(ctx, tx, s.dialect, events...)
+	})
+}
+
+func getProject(ctx context.Context, db DBTX, d dialect, id int64) (models.Project, error) {
+	row := db.QueryRowContext(ctx, d.rebind("SELECT "+projectColumns+" FROM projects WHERE projects.id = $1"), id)
+	project, err := scanProject(row)
+	if err != nil {
+		return models.Project{}, lookupError(err, fmt.Sprintf("Unable to find project with id %d", id))
+	}
+	return project, nil
+}
+
+// checkItemProject validates project of to-do item: it must exist and be active.
+// Items without project and with 0 (removing project on update) are valid.
+func checkItemProject(ctx context.Context, db DBTX, d dialect, item *models.ToDo) error {
+	if item.ProjectID == nil || *item.ProjectID == 0 {
+		return nil
+	}
+	var archived bool
+	err := db.QueryRowContext(ctx, d.rebind("SELECT archived FROM projects WHERE id = $1"), *item.ProjectID).Scan(&archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ValidationError(fmt.Sprintf("Unable to find project with id %d", *item.ProjectID), err)
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return models.ValidationError(fmt.Sprintf("Project with id %d is archived", *item.ProjectID), nil)
+	}
+	return nil
+}
+
+// projectChanged tells whether update moves item to other project. Items of archived project are kept and
+// can still be updated, only moving them into archived project is rejected.
+func projectChanged(before, after models.ToDo) bool {
+	projectOf := func(item models.ToDo) int64 {
+		if item.ProjectID == nil {
+			return 0
+		}
+		return *item.ProjectID
+	}
+	return projectOf(before) != projectOf(after)
+}
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var project models.Project
+	var description, color sql.NullString
+	var created, updated sql.NullInt64
+	if err := row.Scan(&project.ID, &project.Name, &description, &color, &project.Archived, &created, &updated, &project.Count); err != nil {
+		return models.Project{}, err
+	}
+	project.Description = description.String
+	project.Color = color.String
+	project.Created = created.Int64
+	project.Updated = updated.Int64
+	return project, nil
+}
@@ -11,9 +11,9 @@ import (
 )
 
 const createTodo = `-- name: CreateTodo :one
-INSERT INTO todos (description, status, created, updated)
-VALUES ($1, $2, $3, $4)
-RETURNING id, description, status, created, updated
+INSERT INTO todos (description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence, deleted_at, version
 `
 
 type CreateTodoParams struct {
@@ -21,6 +21,12 @@ type CreateTodoParams struct {
 	Status      sql.NullString
 	Created     sql.NullInt64
 	Updated     sql.NullInt64
+	StartAt     sql.NullInt64
+	DueAt       sql.NullInt64
+	Priority    int16
+	ProjectID   sql.NullInt64
+	ParentID    sql.NullInt64
+	Recurrence  sql.NullString
 }
 
 func (q *Queries) CreateTodo(ctx context.Context, arg CreateTodoParams) (Todo, error) {
@@ -29,6 +35,12 @@ func (q *Queries) CreateTodo(ctx context.Context, arg CreateTodoParams) (Todo, e
 		arg.Status,
 		arg.Created,
 		arg.Updated,
+		arg.StartAt,
+		arg.DueAt,
+		arg.Priority,
+		arg.ProjectID,
+		arg.ParentID,
+		arg.Recurrence,
 	)
 	var i Todo
 	err := row.Scan(
@@ -37,6 +49,14 @@ func (q *Queries) CreateTodo(ctx context.Context, arg CreateTodoParams) (Todo, e
 		&i.Status,
 		&i.Created,
 		&i.Updated,
+		&i.StartAt,
+		&i.DueAt,
+		&i.Priority,
+		&i.ProjectID,
+		&i.ParentID,
+		&i.Recurrence,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -52,8 +72,8 @@ func (q *Queries) DeleteTodo(ctx context.Context, id int64) error {
 }
 
 const getTodo = `-- name: GetTodo :one
-SELECT id, description, status, created, updated FROM todos
-WHERE id = $1 LIMIT 1
+SELECT id, description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence, deleted_at, version FROM todos
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetTodo(ctx context.Context, id int64) (Todo, error) {
@@ -65,12 +85,20 @@ func (q *Queries) GetTodo(ctx context.Context, id int64) (Todo, error) {
 		&i.Status,
 		&i.Created,
 		&i.Updated,
+		&i.StartAt,
+		&i.DueAt,
+		&i.Priority,
+		&i.ProjectID,
+		&i.ParentID,
+		&i.Recurrence,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
 const getTodos = `-- name: GetTodos :many
-SELECT id, description, status, created, updated FROM todos
+SELECT id, description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence, deleted_at, version FROM todos
 ORDER BY id
 `
 
@@ -89,6 +117,14 @@ func (q *Queries) GetTodos(ctx context.Context) ([]Todo, error) {
 			&i.Status,
 			&i.Created,
 			&i.Updated,
+			&i.StartAt,
+			&i.DueAt,
+			&i.Priority,
+			&i.ProjectID,
+			&i.ParentID,
+			&i.Recurrence,
+			&i.DeletedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -103,11 +139,38 @@ func (q *Queries) GetTodos(ctx context.Context) ([]Todo, error) {
 	return items, nil
 }
 
+const lockTodo = `-- name: LockTodo :one
+SELECT id, description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence, deleted_at, version FROM todos
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) LockTodo(ctx context.Context, id int64) (Todo, error) {
+	row := q.db.QueryRowContext(ctx, lockTodo, id)
+	var i Todo
+	err := row.Scan(
+		&i.ID,
+		&i.Description,
+		&i.Status,
+		&i.Created,
+		&i.Updated,
+		&i.StartAt,
+		&i.DueAt,
+		&i.Priority,
+		&i.ProjectID,
+		&i.ParentID,
+		&i.Recurrence,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
 const updateTodo = `-- name: UpdateTodo :one
 UPDATE todos
-SET description = $2, status = $3, updated = $4
-WHERE id = $1
-RETURNING id, description, status, created, updated
+SET description = $2, status = $3, updated = $4, start_at = $5, due_at = $6, priority = $7, project_id = $8, parent_id = $9, recurrence = $10, version = version + 1
+WHERE id = $1 AND version = $11
+RETURNING id, description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence, deleted_at, version
 `
 
 type UpdateTodoParams struct {
@@ -115,6 +178,13 @@ type UpdateTodoParams struct {
 	Description sql.NullString
 	Status      sql.NullString
 	Updated     sql.NullInt64
+	StartAt     sql.NullInt64
+	DueAt       sql.NullInt64
+	Priority    int16
+	ProjectID   sql.NullInt64
+	ParentID    sql.NullInt64
+	Recurrence  sql.NullString
+	Version     int64
 }
 
 func (q *Queries) UpdateTodo(ctx context.Context, arg UpdateTodoParams) (Todo, error) {
@@ -123,6 +193,13 @@ func (q *Queries) UpdateTodo(ctx context.Context, arg UpdateTodoParams) (Todo, e
 		arg.Description,
 		arg.Status,
 		arg.Updated,
+		arg.StartAt,
+		arg.DueAt,
+		arg.Priority,
+		arg.ProjectID,
+		arg.ParentID,
+		arg.Recurrence,
+		arg.Version,
 	)
 	var i Todo
 	err := row.Scan(
@@ -131,6 +208,14 @@ func (q *Queries) UpdateTodo(ctx context.Context, arg UpdateTodoParams) (Todo, e
 		&i.Status,
 		&i.Created,
 		&i.Updated,
+		&i.StartAt,
+		&i.DueAt,
+		&i.Priority,
+		&i.ProjectID,
+		&i.ParentID,
+		&i.Recurrence,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -0,0 +1,460 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// columnKind defines how values compared with the column are converted before binding.
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	intColumn
+	// priorityColumn stores ordinal of models.Priority, values may be given as names.
+	priorityColumn
+)
+
+// column describes column available for sorting and filtering.
+type column struct {
+	kind columnKind
+	// nullable columns never match filters when NULL and are sorted as the greatest value.
+	nullable bool
+}
+
+// nullSortValue replaces NULL in sort expressions, so NULLs go last in ascending order on every backend
+// and keyset conditions can compare them.
+const nullSortValue = math.MaxInt64
+
+// todoColumns is allow-list of "todos" columns (see internal/db/schema.sql), that can be used for sorting and filtering.
+// Field names coming from requests are never put into SQL unless they are listed here.
+var todoColumns = map[string]column{
+	"id":          {kind: intColumn},
+	"description": {kind: textColumn},
+	"status":      {kind: textColumn},
+	"created":     {kind: intColumn},
+	"updated":     {kind: intColumn},
+	"start_at":    {kind: intColumn, nullable: true},
+	"due_at":      {kind: intColumn, nullable: true},
+	"priority":    {kind: priorityColumn},
+	"project_id":  {kind: intColumn, nullable: true},
+	"parent_id":   {kind: intColumn, nullable: true},
+}
+
+// todoSelectColumns are columns of "todos" in schema order, as expected by scanTodo.
+var todoSelectColumns = []string{"id", "description", "status", "created", "updated", "start_at", "due_at", "priority", "project_id", "parent_id", "recurrence", "deleted_at", "version"}
+
+// dialect defines SQL flavour specifics, that matter for query building.
+type dialect int
+
+const (
+	postgresDialect dialect = iota
+	sqliteDialect
+)
+
+// placeholder returns bind parameter marker for n-th (starting from 1) argument.
+func (d dialect) placeholder(n int) string {
+	// Numbered placeholders allow reusing the same argument.
+	if d == postgresDialect {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?" + strconv.Itoa(n)
+}
+
+// forUpdate returns clause locking selected rows until the transaction ends.
+// SQLite has single connection (see NewSQLiteRepo), so its transactions never run concurrently.
+func (d dialect) forUpdate() string {
+	if d == postgresDialect {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// likeOperator returns case-insensitive LIKE operator.
+// SQLite LIKE is case-insensitive by default.
+func (d dialect) likeOperator() string {
+	if d == postgresDialect {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// comparisonOperators maps filter operators, that take single value, to SQL.
+var comparisonOperators = map[models.Operator]string{
+	models.OpEq:  "=",
+	models.OpNe:  "<>",
+	models.OpGt:  ">",
+	models.OpGte: ">=",
+	models.OpLt:  "<",
+	models.OpLte: "<=",
+}
+
+// likeEscaper escapes LIKE wildcards, so the value is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// condition is filter validated against allow-list, with values converted to column type.
+type condition struct {
+	field    string
+	kind     columnKind
+	operator models.Operator
+	values   []any
+}
+
+// compileFilter validates filter and converts its values.
+func compileFilter(allowed map[string]column, filter models.Filter) (condition, error) {
+	col, ok := allowed[filter.Field]
+	if !ok {
+		return condition{}, models.ValidationError(fmt.Sprintf("Unknown filter field %s", filter.Field), nil)
+	}
+	kind := col.kind
+	cond := condition{field: filter.Field, kind: kind, operator: filter.Operator}
+	if cond.operator == "" {
+		cond.operator = models.OpEq
+	}
+	raw := []string{filter.Value}
+	switch cond.operator {
+	case models.OpIn:
+		raw = filter.Values
+		if len(raw) == 0 {
+			return condition{}, models.ValidationError(fmt.Sprintf("No values for field %s", filter.Field), nil)
+		}
+	case models.OpLike:
+		if kind != textColumn {
+			return condition{}, models.ValidationError(fmt.Sprintf("Operator like is not supported for field %s", filter.Field), nil)
+		}
+	default:
+		if _, ok := comparisonOperators[cond.operator]; !ok {
+			return condition{}, models.ValidationError(fmt.Sprintf("Unknown filter operator %s", cond.operator), nil)
+		}
+	}
+	for _, value := range raw {
+		converted, err := convertValue(kind, filter.Field, value)
+		if err != nil {
+			return condition{}, err
+		}
+		cond.values = append(cond.values, converted)
+	}
+	return cond, nil
+}
+
+// selectBuilder builds SELECT queries with bind parameters for every value.
+// The first error (e.g. unknown field) is kept and returned from Build.
+type selectBuilder struct {
+	dialect dialect
+	table   string
+	columns []string
+	allowed map[string]column
+	where   []string
+	orderBy []string
+	args    []any
+	limit   int
+	offset  int
+	err     error
+}
+
+func newSelectBuilder(d dialect, table string, allowed map[string]column, columns ...string) *selectBuilder {
+	return &selectBuilder{dialect: d, table: table, allowed: allowed, columns: columns}
+}
+
+// bind adds argument and returns its placeholder.
+func (b *selectBuilder) bind(value any) string {
+	b.args = append(b.args, value)
+	return b.dialect.placeholder(len(b.args))
+}
+
+// Where adds condition for given filter.
+func (b *selectBuilder) Where(filter models.Filter) *selectBuilder {
+	cond, err := compileFilter(b.allowed, filter)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	switch cond.operator {
+	case models.OpIn:
+		placeholders := make([]string, len(cond.values))
+		for i, value := range cond.values {
+			placeholders[i] = b.bind(value)
+		}
+		b.where = append(b.where, cond.field+" IN ("+strings.Join(placeholders, ", ")+")")
+	case models.OpLike:
+		pattern := "%" + likeEscaper.Replace(cond.values[0].(string)) + "%"
+		b.where = append(b.where, cond.field+" "+b.dialect.likeOperator()+" "+b.bind(pattern)+` ESCAPE '\'`)
+	default:
+		b.where = append(b.where, cond.field+" "+comparisonOperators[cond.operator]+" "+b.bind(cond.values[0]))
+	}
+	return b
+}
+
+// StartAfter adds keyset condition: only rows following the row with given key values and id
+// in "keys..., id" ordering.
+func (b *selectBuilder) StartAfter(keys []sortKey, values []any, id int64, idASC bool) *selectBuilder {
+	var alternatives, equal []string
+	for i, key := range keys {
+		col, ok := b.allowed[key.field]
+		if !ok {
+			b.fail(models.ValidationError(fmt.Sprintf("Unknown sort field %s", key.field), nil))
+			return b
+		}
+		expr := sortExpression(key.field, col)
+		placeholder := b.bind(values[i])
+		alternatives = append(alternatives, conjunction(append(equal, expr+" "+followingOperator(key.asc)+" "+placeholder)))
+		equal = append(equal, expr+" = "+placeholder)
+	}
+	alternatives = append(alternatives, conjunction(append(equal, "id "+followingOperator(idASC)+" "+b.bind(id))))
+	b.where = append(b.where, "("+strings.Join(alternatives, " OR ")+")")
+	return b
+}
+
+// followingOperator returns operator selecting values, that follow given one in the ordering.
+func followingOperator(asc bool) string {
+	if asc {
+		return comparisonOperators[models.OpGt]
+	}
+	return comparisonOperators[models.OpLt]
+}
+
+func conjunction(conditions []string) string {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+// Tagged adds condition matching items with any (or all) of the tags. Tag names must be normalized.
+func (b *selectBuilder) Tagged(filter models.TagFilter) *selectBuilder {
+	if len(filter.Names) == 0 {
+		return b
+	}
+	placeholders := make([]string, len(filter.Names))
+	for i, name := range filter.Names {
+		placeholders[i] = b.bind(name)
+	}
+	subquery := "SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id " +
+		"WHERE tags.name IN (" + strings.Join(placeholders, ", ") + ")"
+	if filter.MatchAll {
+		subquery += " GROUP BY todo_tags.todo_id HAVING COUNT(*) = " + b.bind(len(filter.Names))
+	}
+	b.where = append(b.where, "id IN ("+subquery+")")
+	return b
+}
+
+// Blocked adds condition matching items, that are (or aren't) blocked by items with statuses other than done ones.
+// Blockers in trash are ignored.
+func (b *selectBuilder) Blocked(blocked bool, done []string) *selectBuilder {
+	subquery := "SELECT todo_dependencies.todo_id FROM todo_dependencies JOIN todos blockers ON blockers.id = todo_dependencies.blocker_id " +
+		"WHERE blockers.deleted_at IS NULL"
+	if len(done) != 0 {
+		placeholders := make([]string, len(done))
+		for i, status := range done {
+			placeholders[i] = b.bind(status)
+		}
+		subquery += " AND blockers.status NOT IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	operator := "IN"
+	if !blocked {
+		operator = "NOT IN"
+	}
+	b.where = append(b.where, "id "+operator+" ("+subquery+")")
+	return b
+}
+
+// Active adds condition excluding items in trash.
+func (b *selectBuilder) Active() *selectBuilder {
+	b.where = append(b.where, "deleted_at IS NULL")
+	return b
+}
+
+// Search adds full-text condition: description must contain all terms.
+func (b *selectBuilder) Search(terms []string) *selectBuilder {
+	b.where = append(b.where, b.dialect.searchCondition(b.bind(b.dialect.searchArgument(terms))))
+	return b
+}
+
+// OrderBy adds sorting by field.
+func (b *selectBuilder) OrderBy(field string, asc bool) *selectBuilder {
+	col, ok := b.allowed[field]
+	if !ok {
+		b.fail(models.ValidationError(fmt.Sprintf("Unknown sort field %s", field), nil))
+		return b
+	}
+	direction := "ASC"
+	if !asc {
+		direction = "DESC"
+	}
+	b.orderBy = append(b.orderBy, sortExpression(field, col)+" "+direction)
+	return b
+}
+
+// sortExpression returns expression used for ordering by column.
+func sortExpression(field string, col column) string {
+	if col.nullable {
+		return fmt.Sprintf("COALESCE(%s, %d)", field, int64(nullSortValue))
+	}
+	return field
+}
+
+// Limit restricts number of returned rows, non-positive values are ignored.
+func (b *selectBuilder) Limit(limit int) *selectBuilder {
+	b.limit = limit
+	return b
+}
+
+// Offset skips given number of rows, non-positive values are ignored.
+func (b *selectBuilder) Offset(offset int) *selectBuilder {
+	b.offset = offset
+	return b
+}
+
+// Build returns SQL query and its arguments.
+func (b *selectBuilder) Build() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	var query strings.Builder
+	b.writeFrom(&query, strings.Join(b.columns, ", "))
+	if len(b.orderBy) > 0 {
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		query.WriteString(" LIMIT ")
+		query.WriteString(b.bind(b.limit))
+	}
+	if b.offset > 0 {
+		// SQLite doesn't allow OFFSET without LIMIT, -1 means no limit there.
+		if b.limit <= 0 && b.dialect == sqliteDialect {
+			query.WriteString(" LIMIT -1")
+		}
+		query.WriteString(" OFFSET ")
+		query.WriteString(b.bind(b.offset))
+	}
+	return query.String(), b.args, nil
+}
+
+// BuildCount returns query counting rows matching conditions. Ordering and pagination are ignored.
+func (b *selectBuilder) BuildCount() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	var query strings.Builder
+	b.writeFrom(&query, "COUNT(*)")
+	return query.String(), b.args, nil
+}
+
+func (b *selectBuilder) writeFrom(query *strings.Builder, columns string) {
+	query.WriteString("SELECT ")
+	query.WriteString(columns)
+	query.WriteString(" FROM ")
+	query.WriteString(b.table)
+	if len(b.where) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(b.where, " AND "))
+	}
+}
+
+func (b *selectBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// convertValue converts raw request value to type of the column.
+func convertValue(kind columnKind, field, value string) (any, error) {
+	switch kind {
+	case priorityColumn:
+		priority, err := models.ParsePriority(value)
+		if err != nil {
+			return nil, models.ValidationError(fmt.Sprintf("Invalid value %q for field %s", value, field), err)
+		}
+		return int64(priority), nil
+	case intColumn:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, models.ValidationError(fmt.Sprintf("Invalid value %q for field %s", value, field), err)
+		}
+		return number, nil
+	}
+	return value, nil
+}
+
+// buildTodosQuery translates query parameters into SELECT of a single page from "todos" table.
+// Done are terminal statuses of the workflow, items blocked only by done items aren't blocked.
+func buildTodosQuery(d dialect, params *models.ParamsBag, done []string) (string, []any, error) {
+	order, err := preparePaging(params)
+	if err != nil {
+		return "", nil, err
+	}
+	b := todosSelect(d, params, done)
+	if cursor := params.Paging.Cursor; cursor != nil {
+		b.StartAfter(order.keys, order.values, cursor.ID, order.idASC)
+	}
+	for _, key := range order.keys {
+		b.OrderBy(key.field, key.asc)
+	}
+	// Keep order stable for equal sort keys.
+	b.OrderBy("id", order.idASC)
+	return b.Limit(fetchLimit(params.Paging)).Offset(params.Paging.Offset).Build()
+}
+
+// buildTodosCountQuery translates query parameters into COUNT of all matching rows from "todos" table.
+func buildTodosCountQuery(d dialect, params *models.ParamsBag, done []string) (string, []any, error) {
+	return todosSelect(d, params, done).BuildCount()
+}
+
+// buildTodoIDsQuery translates filters of query parameters into SELECT of ids of all matching items.
+// Sorting and pagination are ignored, ids are ordered ascending.
+func buildTodoIDsQuery(d dialect, params *models.ParamsBag, done []string) (string, []any, error) {
+	b := todosSelect(d, params, done)
+	b.columns = []string{"id"}
+	return b.OrderBy("id", true).Build()
+}
+
+func todosSelect(d dialect, params *models.ParamsBag, done []string) *selectBuilder {
+	b := newSelectBuilder(d, "todos", todoColumns, todoSelectColumns...).Active()
+	for _, filter := range params.Filter.Filters {
+		b.Where(filter)
+	}
+	b.Tagged(params.Tags)
+	if params.Blocked != nil {
+		b.Blocked(*params.Blocked, done)
+	}
+	if len(params.Search) != 0 {
+		terms, err := searchTerms(params.Search)
+		if err != nil {
+			b.fail(err)
+			return b
+		}
+		b.Search(terms)
+	}
+	return b
+}
+
+// buildSearchQuery translates search query and parameters into SELECT of ranked page from "todos" table.
+// Results are ordered by relevance, sort params are ignored.
+func buildSearchQuery(d dialect, query string, params *models.ParamsBag, done []string) (string, []any, error) {
+	if params.Paging.Cursor != nil {
+		return "", nil, models.ValidationError("Cursor pagination isn't supported for search", nil)
+	}
+	terms, err := searchTerms(query)
+	if err != nil {
+		return "", nil, err
+	}
+	b := todosSelect(d, params, done)
+	argument := b.bind(d.searchArgument(terms))
+	b.columns = append(b.columns, d.searchRank(argument)+" AS rank", d.searchSnippet(argument)+" AS snippet")
+	b.where = append(b.where, d.searchCondition(argument))
+	b.orderBy = append(b.orderBy, "rank DESC", "id ASC")
+	return b.Limit(fetchLimit(params.Paging)).Offset(params.Paging.Offset).Build()
+}
+
+// buildSearchCountQuery translates search query and parameters into COUNT of all matching rows from "todos" table.
+func buildSearchCountQuery(d dialect, query string, params *models.ParamsBag, done []string) (string, []any, error) {
+	terms, err := searchTerms(query)
+	if err != nil {
+		return "", nil, err
+	}
+	return todosSelect(d, params, done).Search(terms).BuildCount()
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selectTodos is beginning of every query selecting to-do items, items in trash are always excluded.
+var selectTodos = "SELECT " + strings.Join(todoSelectColumns, ", ") + " FROM todos WHERE deleted_at IS NULL"
+
+// defaultOrderBy is ordering applied when request doesn't specify one.
+const defaultOrderBy = "ORDER BY priority DESC, COALESCE(due_at, 9223372036854775807) ASC, id ASC"
+
+// TestBuildTodosQuery checks that values never end up in SQL text and fields are validated.
+func TestBuildTodosQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		dialect       dialect
+		params        models.ParamsBag
+		done          []string
+		expectedQuery string
+		expectedArgs  []any
+		expectedCode  int
+	}{
+		{
+			name:          "Defaults to priority and due date ordering",
+			dialect:       postgresDialect,
+			expectedQuery: selectTodos + " " + defaultOrderBy,
+		},
+		{
+			name:    "Filter value is bound",
+			dialect: postgresDialect,
+			params: models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "status", Value: "x' OR '1'='1"}}},
+				Sort:   models.SortParams{Field: "updated", ASC: false},
+				Paging: models.PaginationParams{Limit: 10, Offset: 20},
+			},
+			expectedQuery: selectTodos + " AND status = $1 " +
+				"ORDER BY updated DESC, id ASC LIMIT $2 OFFSET $3",
+			// One extra row is fetched to tell if there are more items.
+			expectedArgs: []any{"x' OR '1'='1", 11, 20},
+		},
+		{
+			name:    "SQLite placeholders",
+			dialect: sqliteDialect,
+			params: models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "created", Value: "1700000000"}}},
+				Paging: models.PaginationParams{Offset: 5},
+			},
+			expectedQuery: selectTodos + " AND created = ?1 " +
+				defaultOrderBy + " LIMIT -1 OFFSET ?2",
+			expectedArgs: []any{int64(1700000000), 5},
+		},
+		{
+			name:    "Filter operators",
+			dialect: postgresDialect,
+			params: models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{
+					{Field: "updated", Operator: models.OpGte, Value: "1700000000"},
+					{Field: "status", Operator: models.OpIn, Values: []string{"TO DO", "DONE"}},
+					{Field: "status", Operator: models.OpNe, Value: "DONE"},
+					{Field: "description", Operator: models.OpLike, Value: "50%_off"},
+				}},
+			},
+			expectedQuery: selectTodos + " " +
+				"AND updated >= $1 AND status IN ($2, $3) AND status <> $4 AND description ILIKE $5 ESCAPE '\\' " +
+				defaultOrderBy,
+			expectedArgs: []any{int64(1700000000), "TO DO", "DONE", "DONE", `%50\%\_off%`},
+		},
+		{
+			name:    "Priority names are converted to ordinals",
+			dialect: postgresDialect,
+			params: models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "priority", Operator: models.OpGte, Value: "High"}}},
+				Sort:   models.SortParams{Field: "id", ASC: true},
+			},
+			expectedQuery: selectTodos + " AND priority >= $1 ORDER BY id ASC",
+			expectedArgs:  []any{int64(models.PriorityHigh)},
+		},
+		{
+			name:    "Cursor compares every sort key",
+			dialect: postgresDialect,
+			params: models.ParamsBag{
+				Paging: models.PaginationParams{Limit: 2, Cursor: &models.Cursor{ASC: true, Values: []string{"3", "100"}, ID: 7}},
+				Sort:   models.SortParams{ASC: true},
+			},
+			expectedQuery: selectTodos + " AND (priority < $1 OR (priority = $1 AND COALESCE(due_at, 9223372036854775807) > $2) " +
+				"OR (priority = $1 AND COALESCE(due_at, 9223372036854775807) = $2 AND id > $3)) " + defaultOrderBy + " LIMIT $4",
+			expectedArgs: []any{int64(3), int64(100), int64(7), 3},
+		},
+		{
+			name:    "All of the tags",
+			dialect: sqliteDialect,
+			params: models.ParamsBag{
+				Tags: models.TagFilter{Names: []string{"work", "urgent"}, MatchAll: true},
+				Sort: models.SortParams{Field: "id", ASC: true},
+			},
+			expectedQuery: selectTodos + " AND id IN (SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id " +
+				"WHERE tags.name IN (?1, ?2) GROUP BY todo_tags.todo_id HAVING COUNT(*) = ?3) ORDER BY id ASC",
+			expectedArgs: []any{"work", "urgent", 2},
+		},
+		{
+			name:    "Only actionable items",
+			dialect: postgresDialect,
+			params: models.ParamsBag{
+				Filter:  models.FilterParams{Filters: []models.Filter{{Field: "status", Value: "TO DO"}}},
+				Blocked: new(bool),
+				Sort:    models.SortParams{Field: "id", ASC: true},
+			},
+			done: []string{"DONE", "CANCELLED"},
+			expectedQuery: selectTodos + " AND status = $1 AND id NOT IN (SELECT todo_dependencies.todo_id FROM todo_dependencies " +
+				"JOIN todos blockers ON blockers.id = todo_dependencies.blocker_id WHERE blockers.deleted_at IS NULL AND blockers.status NOT IN ($2, $3)) " +
+				"ORDER BY id ASC",
+			expectedArgs: []any{"TO DO", "DONE", "CANCELLED"},
+		},
+		{
+			name:         "Unknown priority",
+			params:       models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{{Field: "priority", Value: "asap"}}}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Like on numeric field",
+			params:       models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{{Field: "id", Operator: models.OpLike, Value: "1"}}}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unknown sort field",
+			params:       models.ParamsBag{Sort: models.SortParams{Field: "id; DROP TABLE todos"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unknown filter field",
+			params:       models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{{Field: "1=1 --", Value: "x"}}}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Non-numeric value for numeric field",
+			params:       models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{{Field: "id", Value: "1 OR 1=1"}}}},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args, err := buildTodosQuery(test.dialect, &test.params, test.done)
+			if test.expectedCode != 0 {
+				assertErrorStatus(t, err, test.expectedCode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedQuery, query)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+// TestBuildSearchQuery checks that search query is turned into prefix tsquery argument.
+func TestBuildSearchQuery(t *testing.T) {
+	query, args, err := buildSearchQuery(postgresDialect, "Buy MILK & eggs!", &models.ParamsBag{
+		Filter: models.FilterParams{Filters: []models.Filter{{Field: "status", Value: "TO DO"}}},
+		Paging: models.PaginationParams{Limit: 5},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT "+strings.Join(todoSelectColumns, ", ")+", "+
+		"ts_rank(to_tsvector('simple', COALESCE(description, '')), to_tsquery('simple', $2)) AS rank, "+
+		"ts_headline('simple', translate(COALESCE(description, ''), chr(2) || chr(3), ''), to_tsquery('simple', $2), "+
+		"'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true') AS snippet "+
+		"FROM todos WHERE deleted_at IS NULL AND status = $1 AND to_tsvector('simple', COALESCE(description, '')) @@ to_tsquery('simple', $2) "+
+		"ORDER BY rank DESC, id ASC LIMIT $3", query)
+	assert.Equal(t, []any{"TO DO", "buy:* & milk:* & eggs:*", 6}, args)
+
+	_, _, err = buildSearchQuery(postgresDialect, " & | ! ", &models.ParamsBag{}, nil)
+	assertErrorStatus(t, err, http.StatusBadRequest)
+}
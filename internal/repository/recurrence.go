@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// normalizeRecurrence validates recurrence rule of the item and stores it in canonical form.
+// Blank rule removes recurrence, recurring items must have due date, as it's advanced by the rule.
+func normalizeRecurrence(item *models.ToDo) error {
+	if item.Recurrence == nil || len(strings.TrimSpace(*item.Recurrence)) == 0 {
+		item.Recurrence = nil
+		return nil
+	}
+	rule, err := models.ParseRRule(*item.Recurrence)
+	if err != nil {
+		return models.ValidationError(err.Error(), err)
+	}
+	if item.DueAt == nil {
+		return models.ValidationError("Recurring item must have due_at", nil)
+	}
+	recurrence := rule.String()
+	item.Recurrence = &recurrence
+	return nil
+}
+
+// nextOccurrence returns next occurrence of recurring item, which has just moved from given status to terminal one.
+// The occurrence has initial status, due date advanced by the rule and start date shifted by the same amount.
+// Returns nil, if item isn't recurring or the rule has no more occurrences.
+func nextOccurrence(workflow models.Workflow, from string, item models.ToDo) *models.ToDo {
+	if item.Recurrence == nil || item.DueAt == nil || workflow.IsTerminal(from) || !workflow.IsTerminal(item.Status) {
+		return nil
+	}
+	rule, err := models.ParseRRule(*item.Recurrence)
+	if err != nil {
+		return nil
+	}
+	due, rule, ok := rule.Next(time.Unix(*item.DueAt, 0))
+	if !ok {
+		return nil
+	}
+	dueAt := due.Unix()
+	recurrence := rule.String()
+	next := &models.ToDo{
+		Description: item.Description,
+		Status:      workflow.Initial,
+		DueAt:       &dueAt,
+		Priority:    item.Priority,
+		Tags:        append([]string{}, item.Tags...),
+		ProjectID:   item.ProjectID,
+		ParentID:    item.ParentID,
+		Recurrence:  &recurrence,
+	}
+	if item.StartAt != nil {
+		startAt := *item.StartAt + dueAt - *item.DueAt
+		next.StartAt = &startAt
+	}
+	return next
+}
+
+// dropArchivedProject removes project of next occurrence, if it was archived (or deleted) since the item was created.
+// Archived projects don't accept new items, but finishing the item mustn't fail because of that.
+func dropArchivedProject(ctx context.Context, db DBTX, d dialect, next *models.ToDo) error {
+	if next.ProjectID == nil {
+		return nil
+	}
+	var archived bool
+	err := db.QueryRowContext(ctx, d.rebind("SELECT archived FROM projects WHERE id = $1"), *next.ProjectID).Scan(&archived)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && archived) {
+		next.ProjectID = nil
+		return nil
+	}
+	return err
+}
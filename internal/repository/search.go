@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+
+	"modernc.org/sqlite"
+)
+
+// Markers wrapping matched words in search snippets.
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// Markers of matched words in snippets produced by storage. They are control characters, which are removed
+// from descriptions, so renderSnippet can escape the text and only then insert highlight markers.
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+var snippetMarkers = strings.NewReplacer(snippetStart, highlightStart, snippetStop, highlightStop)
+
+// renderSnippet turns snippet marked by storage into HTML: text is escaped, so descriptions can't inject markup.
+func renderSnippet(snippet string) string {
+	return snippetMarkers.Replace(html.EscapeString(snippet))
+}
+
+// SQLite counterparts of Postgres full-text search, so both backends share SQL shape.
+// Memory storage calls the same Go functions directly.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("todo_match", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		_, ok := matchText(textArg(args[0]), strings.Fields(textArg(args[1])))
+		return ok, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("todo_rank", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		rank, _ := matchText(textArg(args[0]), strings.Fields(textArg(args[1])))
+		return rank, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("todo_snippet", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return highlight(textArg(args[0]), strings.Fields(textArg(args[1]))), nil
+	})
+}
+
+func textArg(value driver.Value) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// searchTerms splits search query into lowercase words. Everything except letters and digits is a separator,
+// so the terms are safe to use in tsquery syntax.
+func searchTerms(query string) ([]string, error) {
+	terms := strings.FieldsFunc(strings.ToLower(query), isWordSeparator)
+	if len(terms) == 0 {
+		return nil, models.ValidationError("Search query has no words", nil)
+	}
+	return terms, nil
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// postgresSearchVector must match expression of idx_todos_search index (migrations/002), otherwise index isn't used.
+const postgresSearchVector = "to_tsvector('simple', COALESCE(description, ''))"
+
+// searchArgument returns value bound to search expressions of the dialect.
+// Postgres gets tsquery with prefix matching of every term: "buy:* & milk:*".
+func (d dialect) searchArgument(terms []string) string {
+	if d == postgresDialect {
+		return strings.Join(terms, ":* & ") + ":*"
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchCondition returns condition matching items, whose description contains all terms (as word prefixes).
+func (d dialect) searchCondition(placeholder string) string {
+	if d == postgresDialect {
+		return postgresSearchVector + " @@ to_tsquery('simple', " + placeholder + ")"
+	}
+	return "todo_match(description, " + placeholder + ")"
+}
+
+// searchRank returns relevance expression, the higher is better.
+func (d dialect) searchRank(placeholder string) string {
+	if d == postgresDialect {
+		return "ts_rank(" + postgresSearchVector + ", to_tsquery('simple', " + placeholder + "))"
+	}
+	return "todo_rank(description, " + placeholder + ")"
+}
+
+// searchSnippet returns description with matched words wrapped in snippet markers, see renderSnippet.
+func (d dialect) searchSnippet(placeholder string) string {
+	if d == postgresDialect {
+		return "ts_headline('simple', translate(COALESCE(description, ''), chr(2) || chr(3), ''), to_tsquery('simple', " + placeholder + "), " +
+			"'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true')"
+	}
+	return "todo_snippet(description, " + placeholder + ")"
+}
+
+// matchText checks that every term is a prefix of some word of the text.
+// Rank is share of text words, that matched any term.
+func matchText(text string, terms []string) (float64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), isWordSeparator)
+	if len(words) == 0 || len(terms) == 0 {
+		return 0, false
+	}
+	found := make([]bool, len(terms))
+	matched := 0
+	for _, word := range words {
+		wordMatched := false
+		for i, term := range terms {
+			if strings.HasPrefix(word, term) {
+				found[i] = true
+				wordMatched = true
+			}
+		}
+		if wordMatched {
+			matched++
+		}
+	}
+	for _, ok := range found {
+		if !ok {
+			return 0, false
+		}
+	}
+	return float64(matched) / float64(len(words)), true
+}
+
+// highlight wraps words of the text, that start with any of the terms, into snippet markers, see renderSnippet.
+// Markers present in the text are removed.
+func highlight(text string, terms []string) string {
+	var result strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if isWordSeparator(runes[i]) {
+			if !strings.ContainsRune(snippetStart+snippetStop, runes[i]) {
+				result.WriteRune(runes[i])
+			}
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && !isWordSeparator(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if hasAnyPrefix(strings.ToLower(word), terms) {
+			result.WriteString(snippetStart + word + snippetStop)
+		} else {
+			result.WriteString(word)
+		}
+		i = j
+	}
+	return result.String()
+}
+
+func hasAnyPrefix(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// querySearchPage fetches single page of search results and total number of matching items.
+// Done are terminal statuses of the workflow, as in queryTodoPage.
+func querySearchPage(ctx context.Context, db DBTX, d dialect, query string, params *models.ParamsBag, done []string) (models.SearchPage, error) {
+	selectQuery, args, err := buildSearchQuery(d, query, params, done)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	rows, err := db.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	var results []models.SearchResult
+	for rows.Next() {
+		var item Todo
+		var result models.SearchResult
+		if err := rows.Scan(&item.ID, &item.Description, &item.Status, &item.Created, &item.Updated, &item.StartAt, &item.DueAt, &item.Priority, &item.ProjectID, &item.ParentID, &item.Recurrence, &item.DeletedAt, &item.Version,
+			&result.Rank, &result.Snippet); err != nil {
+			return models.SearchPage{}, err
+		}
+		result.ToDo = parseItem(item)
+		result.Snippet = renderSnippet(result.Snippet)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return models.SearchPage{}, err
+	}
+
+	items := make([]*models.ToDo, len(results))
+	for i := range results {
+		items[i] = &results[i].ToDo
+	}
+	if err := loadDetails(ctx, db, d, items, done); err != nil {
+		return models.SearchPage{}, err
+	}
+
+	countQuery, countArgs, err := buildSearchCountQuery(d, query, params, done)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	var total int64
+	if err := db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return models.SearchPage{}, err
+	}
+	return newSearchPage(results, total, params.Paging), nil
+}
+
+// newSearchPage builds page from results fetched with up to fetchLimit rows.
+func newSearchPage(results []models.SearchResult, total int64, paging models.PaginationParams) models.SearchPage {
+	page := models.SearchPage{Items: results, Total: total}
+	if paging.Limit > 0 && len(results) > paging.Limit {
+		page.Items = results[:paging.Limit]
+		page.HasMore = true
+	}
+	return page
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// sqlStore has hand-written queries shared by Postgres and SQLite repositories: tags, projects and subtasks.
+// Queries are written with Postgres placeholders (see dialect.rebind).
+// Workflow of to-do items is shared as well, as both repositories enforce it the same way.
+type sqlStore struct {
+	db       *sql.DB
+	dialect  dialect
+	workflow models.Workflow
+}
+
+// Close closes connection pool of the database.
+func (s sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind converts Postgres placeholders ($1) of the query to the dialect.
+func (d dialect) rebind(query string) string {
+	if d == postgresDialect {
+		return query
+	}
+	return strings.ReplaceAll(query, "$", "?")
+}
+
+// withTx runs fn in transaction, which is committed if fn succeeds and rolled back otherwise.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return withTxOptions(ctx, db, nil, fn)
+}
+
+// withSerializableTx runs fn like withTx, Postgres runs it as SERIALIZABLE, so checks spanning many rows can't be
+// invalidated by concurrent transactions. If that happens, 409 AppError asks to retry the request.
+// SQLite has single connection (see NewSQLiteRepo), so its transactions are serialized anyway.
+func withSerializableTx(ctx context.Context, db *sql.DB, d dialect, fn func(tx *sql.Tx) error) error {
+	var opts *sql.TxOptions
+	if d == postgresDialect {
+		opts = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+	err := withTxOptions(ctx, db, opts, fn)
+	var pqError *pq.Error
+	if errors.As(err, &pqError) && pqError.Code == serializationFailure {
+		return models.ConflictError("Request conflicted with concurrent one, retry it", err).WithCode(models.CodeConcurrentUpdate)
+	}
+	return err
+}
+
+// serializationFailure is SQLSTATE of SERIALIZABLE transaction conflicting with concurrent one.
+const serializationFailure = "40001"
+
+func withTxOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Println(rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: len(value) != 0}
+}
+
+// nullIDParam converts optional reference of to-do item (project, parent) to query argument: 0 and nil are stored as NULL.
+func nullIDParam(id *int64) sql.NullInt64 {
+	if id == nil || *id == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *id, Valid: true}
+}
+
+// checkVersion fails with 412, when expected version of the item is given and differs from its current version.
+func checkVersion(id, expected, current int64) error {
+	if expected != 0 && expected != current {
+		return models.PreconditionFailedError(fmt.Sprintf("Item with id %d was changed, its current version is %d", id, current), nil).
+			WithCode(models.CodeVersionMismatch)
+	}
+	return nil
+}
+
+// concurrentUpdateError reports item changed by another transaction between reading and updating it.
+func concurrentUpdateError(id int64, err error) error {
+	return models.ConflictError(fmt.Sprintf("Item with id %d was changed concurrently, retry the update", id), err).WithCode(models.CodeConcurrentUpdate)
+}
+
+// lookupError returns 404 AppError with given message, if the looked up row doesn't exist.
+// Other errors of the lookup (lost connection, cancelled context, failed scan) are internal ones.
+func lookupError(err error, message string) *models.AppError {
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NotFoundError(message, err)
+	}
+	return models.InternalError(message, err)
+}
+
+// wrapError wraps unexpected error into internal AppError with given message, AppErrors are returned as is.
+func wrapError(err error, message string) error {
+	var appError *models.AppError
+	if errors.As(err, &appError) {
+		return err
+	}
+	return models.InternalError(message, err)
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // blank import to initialize the driver
+)
+
+// sqliteMigrations are applied in order on startup. Index + 1 is stored in "PRAGMA user_version".
+// Never edit existing entries, append new ones instead.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS todos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		description VARCHAR(255),
+		status VARCHAR(255) DEFAULT 'TO DO',
+		created BIGINT,
+		updated BIGINT
+	);
+	CREATE INDEX IF NOT EXISTS idx_status ON todos(status);
+	CREATE INDEX IF NOT EXISTS idx_created ON todos(created);
+	CREATE INDEX IF NOT EXISTS idx_updated ON todos(updated);`,
+	`ALTER TABLE todos ADD COLUMN start_at BIGINT;
+	ALTER TABLE todos ADD COLUMN due_at BIGINT;
+	CREATE INDEX IF NOT EXISTS idx_due_at ON todos(due_at);`,
+	`ALTER TABLE todos ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS idx_priority ON todos(priority);`,
+	`CREATE TABLE tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(64) NOT NULL UNIQUE,
+		color VARCHAR(7)
+	);
+	CREATE TABLE todo_tags (
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		PRIMARY KEY (todo_id, tag_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON todo_tags(tag_id);`,
+	`CREATE TABLE projects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		color VARCHAR(7),
+		archived BOOLEAN NOT NULL DEFAULT FALSE,
+		created BIGINT,
+		updated BIGINT
+	);
+	ALTER TABLE todos ADD COLUMN project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_project_id ON todos(project_id);`,
+	`ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_parent_id ON todos(parent_id);`,
+	`ALTER TABLE todos ADD COLUMN recurrence VARCHAR(255);`,
+	`CREATE TABLE todo_dependencies (
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		blocker_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		PRIMARY KEY (todo_id, blocker_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);`,
+	`ALTER TABLE todos ADD COLUMN deleted_at BIGINT;
+	CREATE INDEX IF NOT EXISTS idx_deleted_at ON todos(deleted_at);`,
+	`CREATE TABLE todo_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		todo_id INTEGER NOT NULL,
+		action VARCHAR(16) NOT NULL,
+		actor VARCHAR(255) NOT NULL,
+		changes TEXT NOT NULL,
+		created BIGINT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_todo_events_todo_id ON todo_events(todo_id);
+	CREATE INDEX IF NOT EXISTS idx_todo_events_created ON todo_events(created);`,
+	`ALTER TABLE todos ADD COLUMN version BIGINT NOT NULL DEFAULT 1;`,
+	`CREATE TABLE idempotency_keys (
+		key VARCHAR(255) PRIMARY KEY,
+		request_hash VARCHAR(64) NOT NULL,
+		status INTEGER NOT NULL DEFAULT 0,
+		content_type VARCHAR(255) NOT NULL DEFAULT '',
+		body TEXT NOT NULL DEFAULT '',
+		created BIGINT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created);`,
+	`ALTER TABLE idempotency_keys ADD COLUMN headers TEXT NOT NULL DEFAULT '{}';`,
+}
+
+var sqliteTodoColumns = strings.Join(todoSelectColumns, ", ")
+
+// SQLiteRepo stores to-do items in SQLite database file.
+type SQLiteRepo struct {
+	sqlStore
+}
+
+// NewSQLiteRepo opens (or creates) SQLite database at given path, migrates it and constructs SQLiteRepo object.
+func NewSQLiteRepo(path string) (*SQLiteRepo, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows single writer, so serialize access through one connection.
+	db.SetMaxOpenConns(1)
+	if err := migrateSQLite(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &SQLiteRepo{sqlStore: sqlStore{db: db, dialect: sqliteDialect, workflow: models.DefaultWorkflow()}}, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("sqlite migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetToDos retrieves all to-dos within given parameters.
+// Mirrors TodoRepo.GetToDos.
+func (r *SQLiteRepo) GetToDos(ctx context.Context, params *models.ParamsBag) (models.ToDoPage, error) {
+	return queryTodoPage(ctx, r.db, sqliteDialect, params, r.workflow.Terminal())
+}
+
+// SearchToDos finds to-dos by words of the query. Mirrors TodoRepo.SearchToDos.
+func (r *SQLiteRepo) SearchToDos(ctx context.Context, query string, params *models.ParamsBag) (models.SearchPage, error) {
+	return querySearchPage(ctx, r.db, sqliteDialect, query, params, r.workflow.Terminal())
+}
+
+// CreateToDo writes to-do item to DB. Actor is recorded in audit log.
+func (r *SQLiteRepo) CreateToDo(ctx context.Context, item *models.ToDo, actor string) (models.ToDo, error) {
+	var inserted models.ToDo
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		inserted, err = r.createToDo(ctx, tx, item, actor)
+		return err
+	})
+	if err != nil {
+		return models.ToDo{}, wrapError(err, "Unable to create item")
+	}
+	return inserted, nil
+}
+
+// createToDo validates to-do item and writes it within transaction.
+func (r *SQLiteRepo) createToDo(ctx context.Context, tx *sql.Tx, item *models.ToDo, actor string) (models.ToDo, error) {
+	if err := prepareNewItem(r.workflow, item); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := checkItemProject(ctx, tx, sqliteDialect, item); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := checkItemParent(ctx, tx, sqliteDialect, item, 0); err != nil {
+		return models.ToDo{}, err
+	}
+	return insertSQLiteToDo(ctx, tx, item, actor)
+}
+
+// insertSQLiteToDo writes validated to-do item with its tags and creation event within transaction.
+func insertSQLiteToDo(ctx context.Context, tx *sql.Tx, item *models.ToDo, actor string) (models.ToDo, error) {
+	now := time.Now().Unix()
+	row := tx.QueryRowContext(ctx,
+		"INSERT INTO todos (description, status, created, updated, start_at, due_at, priority, project_id, parent_id, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING "+sqliteTodoColumns,
+		item.Description, item.Status, now, now, ptrNullInt64(item.StartAt), ptrNullInt64(item.DueAt), priorityOf(*item),
+		nullIDParam(item.ProjectID), nullIDParam(item.ParentID), ptrNullString(item.Recurrence),
+	)
+	inserted, err := scanTodo(row)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if err := setTodoTags(ctx, tx, sqliteDialect, inserted.ID, item.Tags); err != nil {
+		return models.ToDo{}, err
+	}
+	inserted.Tags = append([]string{}, item.Tags...)
+	if err := recordEvents(ctx, tx, sqliteDialect, newEvent(inserted.ID, models.EventCreated, actor, models.DiffToDo(models.ToDo{}, inserted))); err != nil {
+		return models.ToDo{}, err
+	}
+	return inserted, nil
+}
+
+// GetToDo retrieves single to-do item from DB by given id.
+func (r *SQLiteRepo) GetToDo(ctx context.Context, id int64) (models.ToDo, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+sqliteTodoColumns+" FROM todos WHERE id = ? AND deleted_at IS NULL", id)
+	item, err := scanTodo(row)
+	if err != nil {
+		return models.ToDo{}, lookupError(err, fmt.Sprintf("Unable to find item with id %d", id))
+	}
+	if err := loadDetails(ctx, r.db, sqliteDialect, []*models.ToDo{&item}, r.workflow.Terminal()); err != nil {
+		return models.ToDo{}, models.InternalError(fmt.Sprintf("Unable to get details of item with id %d", id), err)
+	}
+	return item, nil
+}
+
+// UpdateToDo replaces all editable fields of single to-do item by given id, missing fields are cleared.
+// Non-zero version must match current version of the item.
+// Item blocked by unfinished items can be finished only with force. Changed fields are recorded in audit log with actor.
+func (r *SQLiteRepo) UpdateToDo(ctx context.Context, updatedItem *models.ToDo, id, version int64, force bool, actor string) (models.ToDo, error) {
+	return r.PatchToDo(ctx, id, models.Replacement(*updatedItem), version, force, actor)
+}
+
+// PatchToDo applies patch to single to-do item by given id. The item is read, patched and updated in single transaction.
+// Version, force and actor work the same way as in UpdateToDo.
+func (r *SQLiteRepo) PatchToDo(ctx context.Context, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error) {
+	var item models.ToDo
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		item, err = r.patchToDo(ctx, tx, id, patch, version, force, actor)
+		return err
+	})
+	if err != nil {
+		return models.ToDo{}, wrapError(err, fmt.Sprintf("Unable to update item with id %d", id))
+	}
+	if err := loadDetails(ctx, r.db, sqliteDialect, []*models.ToDo{&item}, r.workflow.Terminal()); err != nil {
+		return models.ToDo{}, models.InternalError(fmt.Sprintf("Unable to get details of item with id %d", id), err)
+	}
+	return item, nil
+}
+
+// patchToDo applies patch to to-do item within transaction. Returned item has tags, but not other details.
+func (r *SQLiteRepo) patchToDo(ctx context.Context, tx *sql.Tx, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error) {
+	row := tx.QueryRowContext(ctx, "SELECT "+sqliteTodoColumns+" FROM todos WHERE id = ? AND deleted_at IS NULL", id)
+	oldItem, err := scanTodo(row)
+	if err != nil {
+		return models.ToDo{}, lookupError(err, fmt.Sprintf("Unable to find item with id %d", id))
+	}
+	if err := checkVersion(id, version, oldItem.Version); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := loadDetails(ctx, tx, sqliteDialect, []*models.ToDo{&oldItem}, r.workflow.Terminal()); err != nil {
+		return models.ToDo{}, err
+	}
+	updatedItem, err := applyPatch(patch, oldItem)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if err := checkUpdate(r.workflow, oldItem, &updatedItem); err != nil {
+		return models.ToDo{}, err
+	}
+	if projectChanged(oldItem, updatedItem) {
+		if err := checkItemProject(ctx, tx, sqliteDialect, &updatedItem); err != nil {
+			return models.ToDo{}, err
+		}
+	}
+	if err := checkItemParent(ctx, tx, sqliteDialect, &updatedItem, id); err != nil {
+		return models.ToDo{}, err
+	}
+	if err := checkItemBlockers(ctx, tx, sqliteDialect, r.workflow, oldItem.Status, &updatedItem, id, force); err != nil {
+		return models.ToDo{}, err
+	}
+	row = tx.QueryRowContext(ctx,
+		"UPDATE todos SET description = ?, status = ?, updated = ?, start_at = ?, due_at = ?, priority = ?, project_id = ?, parent_id = ?, recurrence = ?, version = version + 1 WHERE id = ? AND version = ? RETURNING "+sqliteTodoColumns,
+		updatedItem.Description, updatedItem.Status, time.Now().Unix(),
+		ptrNullInt64(updatedItem.StartAt), ptrNullInt64(updatedItem.DueAt), priorityOf(updatedItem),
+		nullIDParam(updatedItem.ProjectID), nullIDParam(updatedItem.ParentID), ptrNullString(updatedItem.Recurrence), id, oldItem.Version,
+	)
+	item, err := scanTodo(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ToDo{}, concurrentUpdateError(id, err)
+	}
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if err := setTodoTags(ctx, tx, sqliteDialect, id, updatedItem.Tags); err != nil {
+		return models.ToDo{}, err
+	}
+	item.Tags = updatedItem.Tags
+	if err := recordUpdate(ctx, tx, sqliteDialect, oldItem, item, actor); err != nil {
+		return models.ToDo{}, err
+	}
+	// Finishing recurring item schedules its next occurrence.
+	if next := nextOccurrence(r.workflow, oldItem.Status, updatedItem); next != nil {
+		if err := dropArchivedProject(ctx, tx, sqliteDialect, next); err != nil {
+			return models.ToDo{}, err
+		}
+		if _, err := insertSQLiteToDo(ctx, tx, next, actor); err != nil {
+			return models.ToDo{}, err
+		}
+	}
+	return item, nil
+}
+
+// Close releases database file.
+func (r *SQLiteRepo) Close() error {
+	return r.db.Close()
+}
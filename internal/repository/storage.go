@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Supported storage drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Delays between attempts to connect to database, doubled after every failed attempt.
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// defaultSQLiteDSN is database file used when configuration doesn't provide one.
+const defaultSQLiteDSN = "lazytodo.db"
+
+// Repository is implemented by every storage backend.
+// All backends share the same semantics: initial status of the workflow on create, full replacement on update
+// and patches applied within the update, status changes allowed by the workflow and 404 AppErrors for missing items.
+// Deleted items are kept in trash until restored or purged, reads other than GetTrash don't see them.
+// Every change of items is recorded in audit log together with actor, who made it.
+// Version of item is incremented on every change, updates with non-zero version fail with 412 if it doesn't match.
+// Bulk changes run in single transaction, atomic ones and patches of matching items are all-or-nothing.
+// Idempotency keys are reserved atomically, so only one of concurrent requests with the same key is processed.
+// Queries of SQL backends are cancelled with context passed to methods.
+type Repository interface {
+	CreateToDo(ctx context.Context, item *models.ToDo, actor string) (models.ToDo, error)
+	GetToDos(ctx context.Context, bag *models.ParamsBag) (models.ToDoPage, error)
+	SearchToDos(ctx context.Context, query string, bag *models.ParamsBag) (models.SearchPage, error)
+	GetToDo(ctx context.Context, id int64) (models.ToDo, error)
+	GetToDoTree(ctx context.Context, id int64) (models.ToDo, error)
+	UpdateToDo(ctx context.Context, updatedItem *models.ToDo, id, version int64, force bool, actor string) (models.ToDo, error)
+	PatchToDo(ctx context.Context, id int64, patch models.ToDoPatch, version int64, force bool, actor string) (models.ToDo, error)
+	PatchToDos(ctx context.Context, params *models.ParamsBag, patch models.ToDoPatch, force bool, actor string) ([]models.ToDo, error)
+	BulkToDos(ctx context.Context, request models.BulkRequest, actor string) ([]models.BulkResult, error)
+	DeleteToDo(ctx context.Context, id int64, subtasks models.SubtaskPolicy, actor string) error
+	GetTrash(ctx context.Context) ([]models.ToDo, error)
+	RestoreToDo(ctx context.Context, id int64, actor string) (models.ToDo, error)
+	PurgeToDo(ctx context.Context, id int64, actor string) error
+	PurgeTrash(ctx context.Context, before int64) (int64, error)
+	GetHistory(ctx context.Context, id int64) ([]models.Event, error)
+	GetAuditLog(ctx context.Context, filter models.EventFilter) ([]models.Event, error)
+	GetDependencies(ctx context.Context, id int64) (models.Dependencies, error)
+	AddDependency(ctx context.Context, id, blockerID int64) (models.Dependencies, error)
+	RemoveDependency(ctx context.Context, id, blockerID int64) error
+	GetTags(ctx context.Context) ([]models.Tag, error)
+	GetTag(ctx context.Context, id int64) (models.Tag, error)
+	CreateTag(ctx context.Context, tag *models.Tag) (models.Tag, error)
+	UpdateTag(ctx context.Context, tag *models.Tag, id int64) (models.Tag, error)
+	DeleteTag(ctx context.Context, id int64, actor string) error
+	MergeTags(ctx context.Context, sourceID, targetID int64, actor string) (models.Tag, error)
+	GetProjects(ctx context.Context, archived bool) ([]models.Project, error)
+	GetProject(ctx context.Context, id int64) (models.Project, error)
+	CreateProject(ctx context.Context, project *models.Project) (models.Project, error)
+	UpdateProject(ctx context.Context, project *models.Project, id int64) (models.Project, error)
+	ArchiveProject(ctx context.Context, id int64, archived bool) (models.Project, error)
+	DeleteProject(ctx context.Context, id int64, actor string) error
+	ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord, since int64) (models.IdempotencyRecord, bool, error)
+	SaveIdempotencyResponse(ctx context.Context, record models.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, before int64) (int64, error)
+	Workflow() models.Workflow
+	// Close releases connections of the storage, repository must not be used after it.
+	Close() error
+}
+
+// Config selects storage backend and its data source.
+type Config struct {
+	// Driver is one of DriverPostgres, DriverSQLite or DriverMemory. Postgres is used if empty.
+	Driver string
+	// DSN is connection string (Postgres, required) or database file path (SQLite). Ignored by memory storage.
+	DSN string
+	// Pool limits connections to Postgres, SQLite always uses single connection.
+	Pool PoolConfig
+	// ConnectTimeout is how long Open waits for Postgres to respond, it's pinged with backoff until then.
+	ConnectTimeout time.Duration
+	// Workflow of to-do items, models.DefaultWorkflow is used if nil.
+	Workflow *models.Workflow
+	// TrashRetention is how long deleted items are kept in trash before purging, 0 keeps them forever.
+	TrashRetention time.Duration
+	// IdempotencyWindow is how long responses of requests with Idempotency-Key are kept and replayed.
+	IdempotencyWindow time.Duration
+}
+
+// PoolConfig limits connections of database pool, zero values keep defaults of database/sql (no limits).
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// apply sets limits of the pool.
+func (p PoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// ping waits until database responds, retrying with exponential backoff from minBackoff up to maxBackoff.
+// Gives up after timeout, database is pinged once if timeout isn't positive.
+func ping(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := minBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return fmt.Errorf("database isn't available after %d attempts: %w", attempt, err)
+		}
+		log.Printf("Database isn't available (attempt %d), retrying: %v", attempt, err)
+		time.Sleep(min(backoff, wait))
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// Open constructs repository for configured storage backend.
+func Open(cfg Config) (Repository, error) {
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		if cfg.DSN == "" {
+			return nil, errors.New("postgres storage requires DSN")
+		}
+		repo, err := NewPostgresRepo(cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Pool.apply(repo.db)
+		if err := ping(repo.db, cfg.ConnectTimeout); err != nil {
+			_ = repo.Close()
+			return nil, err
+		}
+		if cfg.Workflow != nil {
+			repo.workflow = *cfg.Workflow
+		}
+		return repo, nil
+	case DriverSQLite:
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		repo, err := NewSQLiteRepo(dsn)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Workflow != nil {
+			repo.workflow = *cfg.Workflow
+		}
+		return repo, nil
+	case DriverMemory:
+		repo := NewMemoryRepo()
+		if cfg.Workflow != nil {
+			repo.workflow = *cfg.Workflow
+		}
+		return repo, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,1429 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testActor is recorded in audit log for changes made by tests.
+const testActor = "tester"
+
+// storageBackends returns constructors of all backends, that can run without external database server.
+func storageBackends(t *testing.T) map[string]func() Repository {
+	return map[string]func() Repository{
+		DriverMemory: func() Repository {
+			return NewMemoryRepo()
+		},
+		DriverSQLite: func() Repository {
+			repo, err := NewSQLiteRepo(filepath.Join(t.TempDir(), "todos.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = repo.Close() })
+			return repo
+		},
+	}
+}
+
+func assertErrorStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var appError *models.AppError
+	if assert.True(t, errors.As(err, &appError), "expected AppError, got %v", err) {
+		assert.Equal(t, status, appError.Status())
+	}
+}
+
+// TestRepositoryContract checks that every backend has the same semantics.
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			created, err := repo.CreateToDo(ctx, &models.ToDo{Description: "First"}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), created.ID)
+			assert.Equal(t, models.DefaultStatus, created.Status)
+			assert.NotZero(t, created.Created)
+
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Second", Status: "DONE"}, testActor)
+			require.NoError(t, err)
+
+			got, err := repo.GetToDo(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created, got)
+
+			_, err = repo.GetToDo(ctx, 42)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			updated, err := repo.PatchToDo(ctx, created.ID, models.MergePatch{"status": "IN PROGRESS"}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, "First", updated.Description)
+			assert.Equal(t, "IN PROGRESS", updated.Status)
+
+			_, err = repo.PatchToDo(ctx, 42, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{
+				Sort: models.SortParams{Field: "description", ASC: false},
+			})
+			require.NoError(t, err)
+			if assert.Len(t, page.Items, 2) {
+				assert.Equal(t, "Second", page.Items[0].Description)
+			}
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "status", Value: "DONE"}}},
+			})
+			require.NoError(t, err)
+			if assert.Len(t, page.Items, 1) {
+				assert.Equal(t, "Second", page.Items[0].Description)
+			}
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{
+					{Field: "status", Operator: models.OpIn, Values: []string{"DONE", "IN PROGRESS"}},
+					{Field: "description", Operator: models.OpLike, Value: "irs"},
+					{Field: "id", Operator: models.OpLt, Value: "2"},
+				}},
+			})
+			require.NoError(t, err)
+			if assert.Len(t, page.Items, 1) {
+				assert.Equal(t, "First", page.Items[0].Description)
+			}
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "status", Operator: models.OpNe, Value: "DONE"}}},
+			})
+			require.NoError(t, err)
+			assert.Len(t, page.Items, 1)
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{Paging: models.PaginationParams{Limit: 1, Offset: 1}})
+			require.NoError(t, err)
+			if assert.Len(t, page.Items, 1) {
+				assert.Equal(t, int64(2), page.Items[0].ID)
+			}
+			assert.Equal(t, int64(2), page.Total)
+			assert.False(t, page.HasMore)
+			assert.NotEmpty(t, page.PrevCursor)
+
+			_, err = repo.GetToDos(ctx, &models.ParamsBag{Sort: models.SortParams{Field: "unknown"}})
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			require.NoError(t, repo.DeleteToDo(ctx, created.ID, models.SubtasksRestrict, testActor))
+			assertErrorStatus(t, repo.DeleteToDo(ctx, created.ID, models.SubtasksRestrict, testActor), http.StatusNotFound)
+		})
+	}
+}
+
+// TestCursorPagination walks pages forward and backward with cursors on every backend.
+func TestCursorPagination(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			// Equal statuses check id tie-breaker.
+			for _, status := range []string{"IN PROGRESS", "DONE", "IN PROGRESS", "TO DO", "DONE"} {
+				_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Item", Status: status}, testActor)
+				require.NoError(t, err)
+			}
+			sorting := models.SortParams{Field: "status", ASC: false}
+			expected := []int64{4, 1, 3, 2, 5}
+
+			var forward []int64
+			var cursor *models.Cursor
+			var last models.ToDoPage
+			for {
+				page, err := repo.GetToDos(ctx, &models.ParamsBag{
+					Sort:   sorting,
+					Paging: models.PaginationParams{Limit: 2, Cursor: cursor},
+				})
+				require.NoError(t, err)
+				assert.Equal(t, int64(5), page.Total)
+				for _, item := range page.Items {
+					forward = append(forward, item.ID)
+				}
+				last = page
+				if !page.HasMore {
+					break
+				}
+				cursor, err = models.DecodeCursor(page.NextCursor)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, expected, forward)
+
+			var backward []int64
+			for len(last.PrevCursor) != 0 {
+				cursor, err := models.DecodeCursor(last.PrevCursor)
+				require.NoError(t, err)
+				last, err = repo.GetToDos(ctx, &models.ParamsBag{
+					Sort:   sorting,
+					Paging: models.PaginationParams{Limit: 2, Cursor: cursor},
+				})
+				require.NoError(t, err)
+				assert.True(t, last.HasMore)
+				backward = append(append([]int64{}, idsOf(last.Items)...), backward...)
+			}
+			assert.Equal(t, expected[:4], backward)
+
+			_, err := repo.GetToDos(ctx, &models.ParamsBag{
+				Sort:   models.SortParams{Field: "id", ASC: true},
+				Paging: models.PaginationParams{Limit: 2, Cursor: cursor},
+			})
+			assertErrorStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
+
+// TestSearch checks simple full-text search implementations.
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			for _, description := range []string{"Buy milk", "Call mom about milk and milkshakes", "Buy bread", "Build shed"} {
+				_, err := repo.CreateToDo(ctx, &models.ToDo{Description: description}, testActor)
+				require.NoError(t, err)
+			}
+
+			page, err := repo.SearchToDos(ctx, "mil", &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), page.Total)
+			if assert.Len(t, page.Items, 2) {
+				// 2 of 6 words match in the second item, 1 of 2 in the first.
+				assert.Equal(t, int64(1), page.Items[0].ID)
+				assert.Equal(t, "Buy <mark>milk</mark>", page.Items[0].Snippet)
+				assert.Greater(t, page.Items[0].Rank, page.Items[1].Rank)
+			}
+
+			// Description is escaped, only highlight markers are markup.
+			markup, err := repo.CreateToDo(ctx, &models.ToDo{Description: `<script>alert("x")</script> & <mark>cookies` + "\x02"}, testActor)
+			require.NoError(t, err)
+			page, err = repo.SearchToDos(ctx, "script cookies", &models.ParamsBag{})
+			require.NoError(t, err)
+			if assert.Len(t, page.Items, 1) {
+				assert.Equal(t, markup.ID, page.Items[0].ID)
+				assert.Equal(t, "&lt;<mark>script</mark>&gt;alert(&#34;x&#34;)&lt;/<mark>script</mark>&gt; &amp; &lt;mark&gt;<mark>cookies</mark>",
+					page.Items[0].Snippet)
+			}
+			require.NoError(t, repo.DeleteToDo(ctx, markup.ID, models.SubtasksRestrict, testActor))
+
+			page, err = repo.SearchToDos(ctx, "BU", &models.ParamsBag{Paging: models.PaginationParams{Limit: 2}})
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), page.Total)
+			assert.Len(t, page.Items, 2)
+			assert.True(t, page.HasMore)
+
+			todos, err := repo.GetToDos(ctx, &models.ParamsBag{Search: "buy milk"})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1}, idsOf(todos.Items))
+
+			_, err = repo.SearchToDos(ctx, "?!", &models.ParamsBag{})
+			assertErrorStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
+
+// TestSchedule checks start/due dates handling on every backend.
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+	dates := func(start, due int64) (*int64, *int64) {
+		return &start, &due
+	}
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			start, due := dates(100, 200)
+			_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Scheduled", StartAt: start, DueAt: due}, testActor)
+			require.NoError(t, err)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Unscheduled"}, testActor)
+			require.NoError(t, err)
+			_, due = dates(0, 150)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Due earlier", DueAt: due}, testActor)
+			require.NoError(t, err)
+
+			start, due = dates(300, 200)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Invalid", StartAt: start, DueAt: due}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			// Start after existing due date.
+			start, _ = dates(250, 0)
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"start_at": *start}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			// Dates are kept on partial update.
+			updated, err := repo.PatchToDo(ctx, 1, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			require.NoError(t, err)
+			if assert.NotNil(t, updated.DueAt) {
+				assert.Equal(t, int64(200), *updated.DueAt)
+			}
+
+			// Items without due date go last.
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{Sort: models.SortParams{Field: "due_at", ASC: true}})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{3, 1, 2}, idsOf(page.Items))
+
+			cursor, err := models.DecodeCursor(cursorAt(page.Items[1], models.SortParams{Field: "due_at", ASC: true}, false).Encode())
+			require.NoError(t, err)
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Sort:   models.SortParams{Field: "due_at", ASC: true},
+				Paging: models.PaginationParams{Limit: 5, Cursor: cursor},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2}, idsOf(page.Items))
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "due_at", Operator: models.OpLt, Value: "180"}}},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{3}, idsOf(page.Items))
+		})
+	}
+}
+
+// TestPriority checks priorities and default ordering on every backend.
+func TestPriority(t *testing.T) {
+	ctx := context.Background()
+	priority := func(p models.Priority) *models.Priority {
+		return &p
+	}
+	due := func(value int64) *int64 {
+		return &value
+	}
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			items := []models.ToDo{
+				{Description: "No priority"},
+				{Description: "High, due later", Priority: priority(models.PriorityHigh), DueAt: due(200)},
+				{Description: "High, due sooner", Priority: priority(models.PriorityHigh), DueAt: due(100)},
+				{Description: "High, no due date", Priority: priority(models.PriorityHigh)},
+				{Description: "Urgent", Priority: priority(models.PriorityUrgent)},
+			}
+			for i := range items {
+				created, err := repo.CreateToDo(ctx, &items[i], testActor)
+				require.NoError(t, err)
+				if assert.NotNil(t, created.Priority) && items[i].Priority == nil {
+					assert.Equal(t, models.PriorityNone, *created.Priority)
+				}
+			}
+
+			// Priority is kept on partial update.
+			updated, err := repo.PatchToDo(ctx, 5, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			require.NoError(t, err)
+			if assert.NotNil(t, updated.Priority) {
+				assert.Equal(t, models.PriorityUrgent, *updated.Priority)
+			}
+
+			expected := []int64{5, 3, 2, 4, 1}
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, expected, idsOf(page.Items))
+
+			// Walk the default ordering page by page.
+			var walked []int64
+			params := &models.ParamsBag{Sort: models.SortParams{ASC: true}, Paging: models.PaginationParams{Limit: 2}}
+			for {
+				page, err := repo.GetToDos(ctx, params)
+				require.NoError(t, err)
+				walked = append(walked, idsOf(page.Items)...)
+				if !page.HasMore {
+					break
+				}
+				params.Paging.Cursor, err = models.DecodeCursor(page.NextCursor)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, expected, walked)
+
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{
+				Filter: models.FilterParams{Filters: []models.Filter{{Field: "priority", Operator: models.OpGte, Value: "high"}}},
+				Sort:   models.SortParams{Field: "priority", ASC: true},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 3, 4, 5}, idsOf(page.Items))
+		})
+	}
+}
+
+// TestTags checks tagging, tag filters and tag management on every backend.
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			items := []models.ToDo{
+				{Description: "Write report", Tags: []string{"Work", " urgent", "work"}},
+				{Description: "Plan sprint", Tags: []string{"work"}},
+				{Description: "Buy milk", Tags: []string{"home", "urgent"}},
+				{Description: "Read book"},
+			}
+			for i := range items {
+				_, err := repo.CreateToDo(ctx, &items[i], testActor)
+				require.NoError(t, err)
+			}
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"urgent", "work"}, item.Tags)
+
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Bad tag", Tags: []string{" "}}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			tagged := func(all bool, names ...string) []int64 {
+				page, err := repo.GetToDos(ctx, &models.ParamsBag{Tags: models.TagFilter{Names: names, MatchAll: all}})
+				require.NoError(t, err)
+				return idsOf(page.Items)
+			}
+			assert.Equal(t, []int64{1, 2, 3}, tagged(false, "work", "urgent"))
+			assert.Equal(t, []int64{1}, tagged(true, "work", "urgent"))
+
+			// Tags are kept on partial update and replaced when given.
+			updated, err := repo.PatchToDo(ctx, 2, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"work"}, updated.Tags)
+			updated, err = repo.PatchToDo(ctx, 2, models.MergePatch{"tags": []string{}}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Empty(t, updated.Tags)
+
+			tags, err := repo.GetTags(ctx)
+			require.NoError(t, err)
+			require.Len(t, tags, 3)
+			assert.Equal(t, "home", tags[0].Name)
+			assert.Equal(t, int64(1), tags[2].Count)
+			home, urgent, work := tags[0], tags[1], tags[2]
+
+			_, err = repo.CreateTag(ctx, &models.Tag{Name: "HOME"})
+			assertErrorStatus(t, err, http.StatusConflict)
+			_, err = repo.CreateTag(ctx, &models.Tag{Name: "errands", Color: "red"})
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			errands, err := repo.CreateTag(ctx, &models.Tag{Name: "Errands", Color: "#FF0000"})
+			require.NoError(t, err)
+			assert.Equal(t, models.Tag{ID: errands.ID, Name: "errands", Color: "#ff0000"}, errands)
+
+			// Rename is visible on items.
+			renamed, err := repo.UpdateTag(ctx, &models.Tag{Name: "job"}, work.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "job", renamed.Name)
+			item, err = repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"job", "urgent"}, item.Tags)
+			_, err = repo.UpdateTag(ctx, &models.Tag{Name: "home"}, work.ID)
+			assertErrorStatus(t, err, http.StatusConflict)
+
+			// Merge moves items to target tag, items having both tags keep one.
+			item, err = repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			merged, err := repo.MergeTags(ctx, urgent.ID, home.ID, "alice")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), merged.Count)
+			assert.Equal(t, []int64{1, 3}, tagged(false, "home"))
+			// Retagged items get new version and the change is in their history.
+			retagged, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, item.Version+1, retagged.Version)
+			history, err := repo.GetHistory(ctx, 1)
+			require.NoError(t, err)
+			event := history[len(history)-1]
+			assert.Equal(t, models.EventUpdated, event.Action)
+			assert.Equal(t, "alice", event.Actor)
+			if assert.Len(t, event.Changes, 1) {
+				assert.Equal(t, "tags", event.Changes[0].Field)
+			}
+			_, err = repo.GetTag(ctx, urgent.ID)
+			assertErrorStatus(t, err, http.StatusNotFound)
+			_, err = repo.MergeTags(ctx, home.ID, home.ID, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			item, err = repo.GetToDo(ctx, 3)
+			require.NoError(t, err)
+			require.NoError(t, repo.DeleteTag(ctx, home.ID, "bob"))
+			untagged, err := repo.GetToDo(ctx, 3)
+			require.NoError(t, err)
+			assert.Empty(t, untagged.Tags)
+			assert.Equal(t, item.Version+1, untagged.Version)
+			history, err = repo.GetHistory(ctx, 3)
+			require.NoError(t, err)
+			event = history[len(history)-1]
+			assert.Equal(t, "bob", event.Actor)
+			assert.Equal(t, []models.FieldChange{{Field: "tags", Before: []any{"home"}, After: nil}}, event.Changes)
+			assertErrorStatus(t, repo.DeleteTag(ctx, home.ID, testActor), http.StatusNotFound)
+		})
+	}
+}
+
+// TestProjects checks projects and moving items between them on every backend.
+func TestProjects(t *testing.T) {
+	ctx := context.Background()
+	projectID := func(id int64) *int64 {
+		return &id
+	}
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			_, err := repo.CreateProject(ctx, &models.Project{Name: " "})
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			backend, err := repo.CreateProject(ctx, &models.Project{Name: " Backend ", Color: "#00AA00"})
+			require.NoError(t, err)
+			assert.Equal(t, "Backend", backend.Name)
+			assert.Equal(t, "#00aa00", backend.Color)
+			frontend, err := repo.CreateProject(ctx, &models.Project{Name: "Frontend", Description: "Web app"})
+			require.NoError(t, err)
+
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Add API", ProjectID: projectID(backend.ID)}, testActor)
+			require.NoError(t, err)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Add page", ProjectID: projectID(frontend.ID)}, testActor)
+			require.NoError(t, err)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "No project"}, testActor)
+			require.NoError(t, err)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Unknown project", ProjectID: projectID(100)}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			inProject := func(id int64) []int64 {
+				page, err := repo.GetToDos(ctx, &models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{
+					{Field: "project_id", Value: strconv.FormatInt(id, 10)},
+				}}})
+				require.NoError(t, err)
+				return idsOf(page.Items)
+			}
+			assert.Equal(t, []int64{1}, inProject(backend.ID))
+
+			// Move item to other project, project is kept on partial update and removed with 0.
+			moved, err := repo.PatchToDo(ctx, 2, models.MergePatch{"project_id": backend.ID}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, backend.ID, *moved.ProjectID)
+			moved, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, backend.ID, *moved.ProjectID)
+			assert.Equal(t, []int64{1, 2}, inProject(backend.ID))
+			moved, err = repo.PatchToDo(ctx, 2, models.MergePatch{"project_id": 0}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Nil(t, moved.ProjectID)
+
+			project, err := repo.GetProject(ctx, backend.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), project.Count)
+
+			updated, err := repo.UpdateProject(ctx, &models.Project{Description: "Services"}, frontend.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Frontend", updated.Name)
+			assert.Equal(t, "Services", updated.Description)
+
+			// Archived projects are listed separately and don't accept items.
+			archived, err := repo.ArchiveProject(ctx, frontend.ID, true)
+			require.NoError(t, err)
+			assert.True(t, archived.Archived)
+			_, err = repo.PatchToDo(ctx, 3, models.MergePatch{"project_id": frontend.ID}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			projects, err := repo.GetProjects(ctx, false)
+			require.NoError(t, err)
+			assert.Len(t, projects, 1)
+			projects, err = repo.GetProjects(ctx, true)
+			require.NoError(t, err)
+			assert.Len(t, projects, 1)
+			_, err = repo.ArchiveProject(ctx, 100, true)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			// Items of deleted project stay without project.
+			require.NoError(t, repo.DeleteProject(ctx, backend.ID, "alice"))
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Nil(t, item.ProjectID)
+			history, err := repo.GetHistory(ctx, 1)
+			require.NoError(t, err)
+			detached := history[len(history)-1]
+			assert.Equal(t, models.EventUpdated, detached.Action)
+			assert.Equal(t, "alice", detached.Actor)
+			if assert.Len(t, detached.Changes, 1) {
+				assert.Equal(t, "project_id", detached.Changes[0].Field)
+				assert.Nil(t, detached.Changes[0].After)
+			}
+			assertErrorStatus(t, repo.DeleteProject(ctx, backend.ID, testActor), http.StatusNotFound)
+		})
+	}
+}
+
+// TestSerializableTx checks that serialization failures ask to retry the request.
+func TestSerializableTx(t *testing.T) {
+	repo, err := NewSQLiteRepo(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	err = withSerializableTx(context.Background(), repo.db, repo.dialect, func(tx *sql.Tx) error {
+		return &pq.Error{Code: serializationFailure}
+	})
+	assertErrorStatus(t, err, http.StatusConflict)
+	err = withSerializableTx(context.Background(), repo.db, repo.dialect, func(tx *sql.Tx) error {
+		return errors.New("disk full")
+	})
+	assert.EqualError(t, err, "disk full")
+}
+
+// TestSubtaskCycle checks that reads of subtasks end even if parents formed a cycle.
+func TestSubtaskCycle(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLiteRepo(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	parent, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Parent"}, testActor)
+	require.NoError(t, err)
+	_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Subtask", ParentID: &parent.ID}, testActor)
+	require.NoError(t, err)
+	_, err = repo.db.Exec("UPDATE todos SET parent_id = 2 WHERE id = 1")
+	require.NoError(t, err)
+
+	page, err := repo.GetToDos(ctx, &models.ParamsBag{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	tree, err := repo.GetToDoTree(ctx, parent.ID)
+	require.NoError(t, err)
+	assert.Len(t, tree.Children, 1)
+	require.NoError(t, repo.DeleteToDo(ctx, parent.ID, models.SubtasksCascade, testActor))
+	trash, err := repo.GetTrash(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, idsOf(trash))
+}
+
+// TestSubtasks checks hierarchy of items: progress, trees, cycle prevention and deleting with subtasks.
+func TestSubtasks(t *testing.T) {
+	ctx := context.Background()
+	parentID := func(id int64) *int64 {
+		return &id
+	}
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			for _, item := range []models.ToDo{
+				{Description: "Release"},
+				{Description: "Write notes", ParentID: parentID(1)},
+				{Description: "Build", ParentID: parentID(1)},
+				{Description: "Compile", Status: models.StatusDone, ParentID: parentID(3)},
+				{Description: "Unrelated"},
+			} {
+				_, err := repo.CreateToDo(ctx, &item, testActor)
+				require.NoError(t, err)
+			}
+			_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Orphan", ParentID: parentID(100)}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			// Progress rolls up nested subtasks, items without subtasks have none.
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, &models.Progress{Done: 1, Total: 3}, item.Progress)
+			item, err = repo.GetToDo(ctx, 4)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), *item.ParentID)
+			assert.Nil(t, item.Progress)
+
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{
+				{Field: "parent_id", Value: "1"},
+			}}})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 3}, idsOf(page.Items))
+			assert.Equal(t, &models.Progress{Done: 1, Total: 1}, page.Items[1].Progress)
+
+			// Parents can't form cycles.
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"parent_id": 1}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"parent_id": 4}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			updated, err := repo.PatchToDo(ctx, 2, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), *updated.ParentID)
+			tree, err := repo.GetToDoTree(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, &models.Progress{Done: 2, Total: 3}, tree.Progress)
+			if assert.Equal(t, []int64{2, 3}, idsOf(tree.Children)) {
+				assert.Equal(t, []int64{4}, idsOf(tree.Children[1].Children))
+			}
+			_, err = repo.GetToDoTree(ctx, 100)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			// Items with subtasks are deleted only with explicit policy.
+			assertErrorStatus(t, repo.DeleteToDo(ctx, 3, models.SubtasksRestrict, testActor), http.StatusConflict)
+			require.NoError(t, repo.DeleteToDo(ctx, 3, models.SubtasksDetach, testActor))
+			item, err = repo.GetToDo(ctx, 4)
+			require.NoError(t, err)
+			assert.Nil(t, item.ParentID)
+
+			_, err = repo.PatchToDo(ctx, 4, models.MergePatch{"parent_id": 2}, 0, false, testActor)
+			require.NoError(t, err)
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksCascade, testActor))
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{5}, idsOf(page.Items))
+		})
+	}
+}
+
+// TestRecurrence checks that finishing recurring item schedules its next occurrence.
+func TestRecurrence(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			rule := "freq=weekly;byday=we,mo;count=3"
+			startAt, dueAt := int64(1704186000), int64(1704204000) // Tue 09:00 and 14:00, 2 Jan 2024 (UTC)
+
+			_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "No due date", Recurrence: &rule}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			invalid := "FREQ=HOURLY"
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Invalid", DueAt: &dueAt, Recurrence: &invalid}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			item, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Standup notes", StartAt: &startAt, DueAt: &dueAt,
+				Tags: []string{"work"}, Recurrence: &rule}, testActor)
+			require.NoError(t, err)
+			require.NotNil(t, item.Recurrence)
+			assert.Equal(t, "FREQ=WEEKLY;COUNT=3;BYDAY=WE,MO", *item.Recurrence)
+
+			// Non-terminal transitions don't schedule anything.
+			_, err = repo.PatchToDo(ctx, item.ID, models.MergePatch{"status": "IN PROGRESS"}, 0, false, testActor)
+			require.NoError(t, err)
+			done, err := repo.PatchToDo(ctx, item.ID, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusDone, done.Status)
+
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 2)
+			next := page.Items[1]
+			assert.Equal(t, "Standup notes", next.Description)
+			assert.Equal(t, models.DefaultStatus, next.Status)
+			assert.Equal(t, dueAt+86400, *next.DueAt)
+			assert.Equal(t, startAt+86400, *next.StartAt)
+			assert.Equal(t, []string{"work"}, next.Tags)
+			assert.Equal(t, "FREQ=WEEKLY;COUNT=2;BYDAY=WE,MO", *next.Recurrence)
+
+			// The last occurrence isn't followed by another one.
+			_, err = repo.PatchToDo(ctx, next.ID, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 3)
+			last := page.Items[2]
+			assert.Equal(t, dueAt+6*86400, *last.DueAt)
+			assert.Equal(t, "FREQ=WEEKLY;COUNT=1;BYDAY=WE,MO", *last.Recurrence)
+			_, err = repo.PatchToDo(ctx, last.ID, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Len(t, page.Items, 3)
+
+			// Empty rule removes recurrence.
+			updated, err := repo.PatchToDo(ctx, item.ID, models.MergePatch{"recurrence": ""}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Nil(t, updated.Recurrence)
+
+			// Next occurrence isn't added to project archived meanwhile.
+			project, err := repo.CreateProject(ctx, &models.Project{Name: "Team"})
+			require.NoError(t, err)
+			daily := "FREQ=DAILY"
+			item, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Water plants", DueAt: &dueAt, ProjectID: &project.ID, Recurrence: &daily}, testActor)
+			require.NoError(t, err)
+			_, err = repo.ArchiveProject(ctx, project.ID, true)
+			require.NoError(t, err)
+			_, err = repo.PatchToDo(ctx, item.ID, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 5)
+			next = slices.MaxFunc(page.Items, func(a, b models.ToDo) int { return cmp.Compare(a.ID, b.ID) })
+			assert.Equal(t, "Water plants", next.Description)
+			assert.Equal(t, dueAt+86400, *next.DueAt)
+			assert.Nil(t, next.ProjectID)
+		})
+	}
+}
+
+// TestDependencies checks blocking links, blocked flag and finishing of blocked items.
+func TestDependencies(t *testing.T) {
+	ctx := context.Background()
+	blocked := func(value bool) *bool {
+		return &value
+	}
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			for _, description := range []string{"Design", "Build", "Release", "Unrelated"} {
+				_, err := repo.CreateToDo(ctx, &models.ToDo{Description: description}, testActor)
+				require.NoError(t, err)
+			}
+			_, err := repo.AddDependency(ctx, 2, 1)
+			require.NoError(t, err)
+			dependencies, err := repo.AddDependency(ctx, 3, 2)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2}, idsOf(dependencies.BlockedBy))
+			assert.True(t, dependencies.BlockedBy[0].Blocked)
+			_, err = repo.AddDependency(ctx, 3, 2)
+			require.NoError(t, err)
+
+			// Links can't form cycles or point to missing items.
+			_, err = repo.AddDependency(ctx, 1, 3)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.AddDependency(ctx, 1, 1)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.AddDependency(ctx, 1, 100)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.AddDependency(ctx, 100, 1)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			dependencies, err = repo.GetDependencies(ctx, 2)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1}, idsOf(dependencies.BlockedBy))
+			assert.Equal(t, []int64{3}, idsOf(dependencies.Blocks))
+
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{Blocked: blocked(false)})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 4}, idsOf(page.Items))
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{Blocked: blocked(true)})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{2, 3}, idsOf(page.Items))
+			assert.True(t, page.Items[0].Blocked)
+
+			// Blocked item is finished only with force, finishing blocker unblocks it.
+			_, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusConflict)
+			_, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": "IN PROGRESS"}, 0, false, testActor)
+			require.NoError(t, err)
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			item, err := repo.GetToDo(ctx, 2)
+			require.NoError(t, err)
+			assert.False(t, item.Blocked)
+			_, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			require.NoError(t, err)
+			_, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": models.DefaultStatus}, 0, false, testActor)
+			require.NoError(t, err)
+			_, err = repo.PatchToDo(ctx, 3, models.MergePatch{"status": models.StatusDone}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusConflict)
+			_, err = repo.PatchToDo(ctx, 3, models.MergePatch{"status": models.StatusDone}, 0, true, testActor)
+			require.NoError(t, err)
+
+			require.NoError(t, repo.RemoveDependency(ctx, 3, 2))
+			assertErrorStatus(t, repo.RemoveDependency(ctx, 3, 2), http.StatusNotFound)
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksRestrict, testActor))
+			item, err = repo.GetToDo(ctx, 2)
+			require.NoError(t, err)
+			assert.False(t, item.Blocked)
+			dependencies, err = repo.GetDependencies(ctx, 2)
+			require.NoError(t, err)
+			assert.Empty(t, dependencies.BlockedBy)
+		})
+	}
+}
+
+// TestTrash checks that deleted items are hidden from reads until restored and can be purged.
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			parentID := int64(1)
+			subtaskID := int64(2)
+			for _, item := range []*models.ToDo{
+				{Description: "Plan trip", Tags: []string{"travel"}},
+				{Description: "Book flights", ParentID: &parentID},
+				{Description: "Pack", ParentID: &subtaskID},
+				{Description: "Unrelated"},
+			} {
+				_, err := repo.CreateToDo(ctx, item, testActor)
+				require.NoError(t, err)
+			}
+			_, err := repo.AddDependency(ctx, 4, 2)
+			require.NoError(t, err)
+
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksCascade, testActor))
+			_, err = repo.GetToDo(ctx, 3)
+			assertErrorStatus(t, err, http.StatusNotFound)
+			assertErrorStatus(t, repo.DeleteToDo(ctx, 1, models.SubtasksCascade, testActor), http.StatusNotFound)
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{4}, idsOf(page.Items))
+			// Items in trash don't block and aren't counted.
+			assert.False(t, page.Items[0].Blocked)
+			tags, err := repo.GetTags(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), tags[0].Count)
+
+			trash, err := repo.GetTrash(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 2, 3}, idsOf(trash))
+			assert.NotNil(t, trash[0].DeletedAt)
+
+			// Parent is still in trash, so restored subtask becomes top-level, its own subtask is restored with it.
+			restored, err := repo.RestoreToDo(ctx, 2, testActor)
+			require.NoError(t, err)
+			assert.Nil(t, restored.ParentID)
+			assert.Nil(t, restored.DeletedAt)
+			assert.Equal(t, &models.Progress{Total: 1}, restored.Progress)
+			item, err := repo.GetToDo(ctx, 3)
+			require.NoError(t, err)
+			assert.Equal(t, &subtaskID, item.ParentID)
+			item, err = repo.GetToDo(ctx, 4)
+			require.NoError(t, err)
+			assert.True(t, item.Blocked)
+			_, err = repo.RestoreToDo(ctx, 2, testActor)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			assertErrorStatus(t, repo.PurgeToDo(ctx, 4, testActor), http.StatusNotFound)
+			require.NoError(t, repo.PurgeToDo(ctx, 1, testActor))
+			_, err = repo.RestoreToDo(ctx, 1, testActor)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			require.NoError(t, repo.DeleteToDo(ctx, 4, models.SubtasksRestrict, testActor))
+			purged, err := repo.PurgeTrash(ctx, time.Now().Add(-time.Hour).Unix())
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), purged)
+			purged, err = repo.PurgeTrash(ctx, time.Now().Add(time.Hour).Unix())
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+			trash, err = repo.GetTrash(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, trash)
+			dependencies, err := repo.GetDependencies(ctx, 2)
+			require.NoError(t, err)
+			assert.Empty(t, dependencies.Blocks)
+
+		})
+	}
+}
+
+// TestPurgeDetach checks that subtasks remaining in trash lose purged parent and that is recorded in audit log.
+func TestPurgeDetach(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			parent, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Move out"}, testActor)
+			require.NoError(t, err)
+			subtask, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Sell sofa", ParentID: &parent.ID}, testActor)
+			require.NoError(t, err)
+			require.NoError(t, repo.DeleteToDo(ctx, subtask.ID, models.SubtasksRestrict, testActor))
+			require.NoError(t, repo.DeleteToDo(ctx, parent.ID, models.SubtasksRestrict, testActor))
+			// Only the parent is old enough to be purged.
+			backdateDeletion(t, repo, parent.ID, time.Hour)
+
+			purged, err := repo.PurgeTrash(ctx, time.Now().Add(-time.Minute).Unix())
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+			trash, err := repo.GetTrash(ctx)
+			require.NoError(t, err)
+			if assert.Equal(t, []int64{subtask.ID}, idsOf(trash)) {
+				assert.Nil(t, trash[0].ParentID)
+				assert.Equal(t, subtask.Version+2, trash[0].Version)
+			}
+			history, err := repo.GetHistory(ctx, subtask.ID)
+			require.NoError(t, err)
+			detached := history[len(history)-1]
+			assert.Equal(t, models.EventUpdated, detached.Action)
+			assert.Equal(t, models.SystemActor, detached.Actor)
+			if assert.Len(t, detached.Changes, 1) {
+				assert.Equal(t, "parent_id", detached.Changes[0].Field)
+				assert.NotNil(t, detached.Changes[0].Before)
+				assert.Nil(t, detached.Changes[0].After)
+			}
+		})
+	}
+}
+
+// backdateDeletion moves deletion time of the item in trash back by given duration.
+func backdateDeletion(t *testing.T, repo Repository, id int64, by time.Duration) {
+	switch repo := repo.(type) {
+	case *MemoryRepo:
+		item := repo.trash[id]
+		deletedAt := *item.DeletedAt - int64(by.Seconds())
+		item.DeletedAt = &deletedAt
+		repo.trash[id] = item
+	case *SQLiteRepo:
+		_, err := repo.db.Exec("UPDATE todos SET deleted_at = deleted_at - ? WHERE id = ?", int64(by.Seconds()), id)
+		require.NoError(t, err)
+	default:
+		t.Fatalf("unsupported backend %T", repo)
+	}
+}
+
+// TestWorkflow checks that statuses follow default and configured workflows.
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			start := time.Now().Unix()
+			_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Write report", Tags: []string{"work"}}, "alice")
+			require.NoError(t, err)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Unrelated"}, "alice")
+			require.NoError(t, err)
+
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"description": "Write annual report"}, 0, false, "bob")
+			require.NoError(t, err)
+			// Update, that changes nothing, isn't recorded.
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"description": "Write annual report"}, 0, false, "bob")
+			require.NoError(t, err)
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksRestrict, "alice"))
+			_, err = repo.RestoreToDo(ctx, 1, "bob")
+			require.NoError(t, err)
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksRestrict, "alice"))
+			require.NoError(t, repo.PurgeToDo(ctx, 1, "alice"))
+
+			// History outlives the item.
+			history, err := repo.GetHistory(ctx, 1)
+			require.NoError(t, err)
+			actions := make([]models.EventAction, len(history))
+			for i, event := range history {
+				actions[i] = event.Action
+				assert.Equal(t, int64(1), event.TodoID)
+				assert.GreaterOrEqual(t, event.Created, start)
+			}
+			assert.Equal(t, []models.EventAction{models.EventCreated, models.EventUpdated, models.EventDeleted,
+				models.EventRestored, models.EventDeleted, models.EventPurged}, actions)
+			assert.Equal(t, "alice", history[0].Actor)
+			assert.Contains(t, history[0].Changes, models.FieldChange{Field: "description", Before: nil, After: "Write report"})
+			assert.Contains(t, history[0].Changes, models.FieldChange{Field: "tags", Before: nil, After: []any{"work"}})
+			assert.Equal(t, "bob", history[1].Actor)
+			assert.Equal(t, []models.FieldChange{{Field: "description", Before: "Write report", After: "Write annual report"}}, history[1].Changes)
+			if assert.Len(t, history[2].Changes, 1) {
+				assert.Equal(t, "deleted_at", history[2].Changes[0].Field)
+				assert.Nil(t, history[2].Changes[0].Before)
+			}
+
+			history, err = repo.GetHistory(ctx, 42)
+			require.NoError(t, err)
+			assert.Empty(t, history)
+
+			events, err := repo.GetAuditLog(ctx, models.EventFilter{})
+			require.NoError(t, err)
+			assert.Len(t, events, 7)
+			assert.Equal(t, models.EventPurged, events[0].Action)
+
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{Actor: "bob"})
+			require.NoError(t, err)
+			assert.Len(t, events, 2)
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{Action: models.EventCreated})
+			require.NoError(t, err)
+			assert.Len(t, events, 2)
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{TodoID: 2})
+			require.NoError(t, err)
+			assert.Len(t, events, 1)
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{TodoID: 1, Action: models.EventDeleted, Actor: "alice"})
+			require.NoError(t, err)
+			assert.Len(t, events, 2)
+
+			future := time.Now().Add(time.Hour).Unix()
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{Since: &future})
+			require.NoError(t, err)
+			assert.Empty(t, events)
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{Since: &start, Until: &future})
+			require.NoError(t, err)
+			assert.Len(t, events, 7)
+
+			events, err = repo.GetAuditLog(ctx, models.EventFilter{Limit: 2, Offset: 1})
+			require.NoError(t, err)
+			if assert.Len(t, events, 2) {
+				assert.Equal(t, models.EventDeleted, events[0].Action)
+				assert.Equal(t, models.EventRestored, events[1].Action)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			created, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Parent"}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), created.Version)
+			parentID := created.ID
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Subtask", ParentID: &parentID}, testActor)
+			require.NoError(t, err)
+
+			// Version is ignored in replacements.
+			updated, err := repo.UpdateToDo(ctx, &models.ToDo{Description: "Renamed", Status: models.DefaultStatus, Version: 7}, 1, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), updated.Version)
+			updated, err = repo.PatchToDo(ctx, 1, models.MergePatch{"status": "IN PROGRESS"}, 2, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), updated.Version)
+
+			// Stale version doesn't change the item.
+			_, err = repo.PatchToDo(ctx, 1, models.MergePatch{"description": "Stale"}, 2, false, testActor)
+			assertErrorStatus(t, err, http.StatusPreconditionFailed)
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "Renamed", item.Description)
+			assert.Equal(t, int64(3), item.Version)
+			_, err = repo.PatchToDo(ctx, 42, models.MergePatch{"description": "Missing"}, 1, false, testActor)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			// Changes made by other operations increment version as well.
+			require.NoError(t, repo.DeleteToDo(ctx, 1, models.SubtasksDetach, testActor))
+			item, err = repo.GetToDo(ctx, 2)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), item.Version)
+			restored, err := repo.RestoreToDo(ctx, 1, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), restored.Version)
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			high := models.PriorityHigh
+			due := time.Now().Add(24 * time.Hour).Unix()
+			parentID := int64(1)
+			_, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Parent"}, testActor)
+			require.NoError(t, err)
+			created, err := repo.CreateToDo(ctx, &models.ToDo{
+				Description: "Write report", Priority: &high, DueAt: &due, Tags: []string{"work"}, ParentID: &parentID,
+			}, testActor)
+			require.NoError(t, err)
+
+			// Merge patch keeps fields missing in it.
+			patched, err := repo.PatchToDo(ctx, created.ID, models.MergePatch{"description": "Write annual report", "due_at": nil}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, "Write annual report", patched.Description)
+			assert.Nil(t, patched.DueAt)
+			assert.Equal(t, []string{"work"}, patched.Tags)
+			assert.Equal(t, models.PriorityHigh, *patched.Priority)
+			assert.Equal(t, int64(1), *patched.ParentID)
+
+			// JSON Patch is applied to the current state of the item.
+			jsonPatch, err := models.ParseJSONPatch([]byte(`[
+				{"op": "test", "path": "/version", "value": 2},
+				{"op": "add", "path": "/tags/-", "value": "Urgent"},
+				{"op": "replace", "path": "/status", "value": "IN PROGRESS"}
+			]`))
+			require.NoError(t, err)
+			patched, err = repo.PatchToDo(ctx, created.ID, jsonPatch, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"urgent", "work"}, patched.Tags)
+			assert.Equal(t, "IN PROGRESS", patched.Status)
+			_, err = repo.PatchToDo(ctx, created.ID, jsonPatch, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusConflict)
+			_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"version": 1}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusUnprocessableEntity)
+			_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"status": nil}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"description": nil}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+			_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"description": strings.Repeat("a", models.MaxDescriptionLength+1)}, 0, false, testActor)
+			var appError *models.AppError
+			if assert.ErrorAs(t, err, &appError) {
+				assert.Equal(t, []models.FieldError{{Field: "description", Message: "must be at most 255 characters"}}, appError.Fields())
+			}
+			_, err = repo.PatchToDo(ctx, 42, models.MergePatch{"status": "DONE"}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusNotFound)
+
+			// Replacement clears fields missing in it.
+			replaced, err := repo.UpdateToDo(ctx, &models.ToDo{Description: "Report", Status: "IN PROGRESS"}, created.ID, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, "Report", replaced.Description)
+			assert.Equal(t, models.PriorityNone, *replaced.Priority)
+			assert.Empty(t, replaced.Tags)
+			assert.Nil(t, replaced.ParentID)
+			assert.Equal(t, int64(4), replaced.Version)
+			_, err = repo.UpdateToDo(ctx, &models.ToDo{Description: "Report"}, created.ID, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
+
+// TestBulk checks that bulk operations are all-or-nothing in atomic mode and independent in best effort mode.
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			for _, item := range []*models.ToDo{{Description: "Write report"}, {Description: "Read book"}} {
+				_, err := repo.CreateToDo(ctx, item, testActor)
+				require.NoError(t, err)
+			}
+
+			results, err := repo.BulkToDos(ctx, models.BulkRequest{Mode: models.BulkAtomic, Operations: []models.BulkOperation{
+				{Op: models.BulkCreate, Item: &models.ToDo{Description: "Buy milk", Tags: []string{"home"}}},
+				{Op: models.BulkUpdate, ID: 1, Patch: models.MergePatch{"status": "IN PROGRESS"}},
+				{Op: models.BulkDelete, ID: 2},
+			}}, testActor)
+			require.NoError(t, err)
+			require.Len(t, results, 3)
+			assert.Equal(t, http.StatusCreated, results[0].Status)
+			assert.Equal(t, int64(3), results[0].Item.ID)
+			assert.Equal(t, []string{"home"}, results[0].Item.Tags)
+			assert.Equal(t, http.StatusOK, results[1].Status)
+			assert.Equal(t, "IN PROGRESS", results[1].Item.Status)
+			assert.Equal(t, models.BulkResult{Index: 2, Status: http.StatusNoContent}, results[2])
+			page, err := repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 3}, idsOf(page.Items))
+
+			// Failed operation rolls back the whole atomic request.
+			history, err := repo.GetHistory(ctx, 1)
+			require.NoError(t, err)
+			results, err = repo.BulkToDos(ctx, models.BulkRequest{Mode: models.BulkAtomic, Operations: []models.BulkOperation{
+				{Op: models.BulkCreate, Item: &models.ToDo{Description: "Dig", Tags: []string{"garden"}}},
+				{Op: models.BulkUpdate, ID: 1, Item: &models.ToDo{Description: "Rolled back", Status: "DONE"}},
+				{Op: models.BulkUpdate, ID: 42, Patch: models.MergePatch{"status": "DONE"}},
+			}}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound},
+				[]int{results[0].Status, results[1].Status, results[2].Status})
+			assert.Nil(t, results[0].Item)
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "Write report", item.Description)
+			rolledBack, err := repo.GetHistory(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, rolledBack, len(history))
+			tags, err := repo.GetTags(ctx)
+			require.NoError(t, err)
+			assert.Len(t, tags, 1)
+
+			// Best effort request skips failed operations.
+			results, err = repo.BulkToDos(ctx, models.BulkRequest{Mode: models.BulkBestEffort, Operations: []models.BulkOperation{
+				{Op: models.BulkUpdate, ID: 1, Version: 1, Patch: models.MergePatch{"description": "Stale"}},
+				{Op: models.BulkCreate, Item: &models.ToDo{Description: "Dig"}},
+				{Op: models.BulkDelete, ID: 2},
+			}}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, []int{http.StatusPreconditionFailed, http.StatusCreated, http.StatusNotFound},
+				[]int{results[0].Status, results[1].Status, results[2].Status})
+			page, err = repo.GetToDos(ctx, &models.ParamsBag{})
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 3, 4}, idsOf(page.Items))
+		})
+	}
+}
+
+// TestPatchToDos checks that patch of matching items is applied to all of them or none.
+func TestPatchToDos(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			for _, item := range []*models.ToDo{{Description: "A"}, {Description: "B"}, {Description: "C", Status: "IN PROGRESS"}} {
+				_, err := repo.CreateToDo(ctx, item, testActor)
+				require.NoError(t, err)
+			}
+			todo := &models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{
+				{Field: "status", Operator: models.OpEq, Value: models.DefaultStatus},
+			}}}
+
+			items, err := repo.PatchToDos(ctx, todo, models.MergePatch{"priority": "high"}, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{1, 2}, idsOf(items))
+			assert.Equal(t, models.PriorityHigh, *items[1].Priority)
+
+			// Failure on any item leaves all of them unchanged.
+			patch, err := models.ParseJSONPatch([]byte(`[
+				{"op": "replace", "path": "/priority", "value": "low"},
+				{"op": "test", "path": "/description", "value": "A"}
+			]`))
+			require.NoError(t, err)
+			_, err = repo.PatchToDos(ctx, todo, patch, false, testActor)
+			assertErrorStatus(t, err, http.StatusConflict)
+			item, err := repo.GetToDo(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, models.PriorityHigh, *item.Priority)
+
+			items, err = repo.PatchToDos(ctx, &models.ParamsBag{Search: "missing"}, models.MergePatch{"priority": "low"}, false, testActor)
+			require.NoError(t, err)
+			assert.Empty(t, items)
+			_, err = repo.PatchToDos(ctx, &models.ParamsBag{Filter: models.FilterParams{Filters: []models.Filter{
+				{Field: "title", Operator: models.OpEq, Value: "A"},
+			}}}, models.MergePatch{"priority": "low"}, false, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			record := models.IdempotencyRecord{Key: "k1", RequestHash: "hash", Created: 100}
+
+			stored, reserved, err := repo.ReserveIdempotencyKey(ctx, record, 50)
+			require.NoError(t, err)
+			assert.True(t, reserved)
+			assert.Equal(t, record, stored)
+
+			// Retry sees the request in progress, then its response.
+			stored, reserved, err = repo.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{Key: "k1", RequestHash: "other", Created: 120}, 70)
+			require.NoError(t, err)
+			assert.False(t, reserved)
+			assert.False(t, stored.Completed())
+			assert.Equal(t, "hash", stored.RequestHash)
+
+			record.Status, record.ContentType, record.Body = http.StatusOK, "application/json", []byte(`{"id":1}`)
+			record.Headers = map[string]string{"Location": "/todos/1", "ETag": `"1"`}
+			require.NoError(t, repo.SaveIdempotencyResponse(ctx, record))
+			stored, reserved, err = repo.ReserveIdempotencyKey(ctx, record, 70)
+			require.NoError(t, err)
+			assert.False(t, reserved)
+			assert.Equal(t, record, stored)
+
+			// Completed key isn't released, expired one is reserved anew.
+			require.NoError(t, repo.ReleaseIdempotencyKey(ctx, "k1"))
+			expired := models.IdempotencyRecord{Key: "k1", RequestHash: "other", Created: 200}
+			stored, reserved, err = repo.ReserveIdempotencyKey(ctx, expired, 150)
+			require.NoError(t, err)
+			assert.True(t, reserved)
+			assert.Equal(t, expired, stored)
+
+			require.NoError(t, repo.ReleaseIdempotencyKey(ctx, "k1"))
+			_, reserved, err = repo.ReserveIdempotencyKey(ctx, record, 150)
+			require.NoError(t, err)
+			assert.True(t, reserved)
+
+			purged, err := repo.PurgeIdempotencyKeys(ctx, 101)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+			purged, err = repo.PurgeIdempotencyKeys(ctx, 101)
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), purged)
+		})
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	ctx := context.Background()
+	for name, newRepo := range storageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			created, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Typo", Status: "done"}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusDone, created.Status)
+			_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Unknown", Status: "Pending"}, testActor)
+			assertErrorStatus(t, err, http.StatusBadRequest)
+
+			_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"status": "IN PROGRESS"}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusUnprocessableEntity)
+			updated, err := repo.PatchToDo(ctx, created.ID, models.MergePatch{"description": "Same status", "status": "DONE"}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusDone, updated.Status)
+			updated, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"status": "to do"}, 0, false, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, models.DefaultStatus, updated.Status)
+		})
+	}
+
+	workflow, err := models.ParseWorkflow([]byte(`{"statuses": [
+		{"name": "Open", "transitions": ["Closed", "Rejected"]},
+		{"name": "Closed", "terminal": true},
+		{"name": "Rejected", "terminal": true}
+	]}`))
+	require.NoError(t, err)
+	for _, driver := range []string{DriverMemory, DriverSQLite} {
+		t.Run("configured "+driver, func(t *testing.T) {
+			repo, err := Open(Config{Driver: driver, DSN: filepath.Join(t.TempDir(), "todos.db"), Workflow: &workflow})
+			require.NoError(t, err)
+			if sqlite, ok := repo.(*SQLiteRepo); ok {
+				t.Cleanup(func() { _ = sqlite.Close() })
+			}
+			assert.Equal(t, workflow, repo.Workflow())
+
+			parent, err := repo.CreateToDo(ctx, &models.ToDo{Description: "Parent"}, testActor)
+			require.NoError(t, err)
+			assert.Equal(t, "Open", parent.Status)
+			for _, status := range []string{"Closed", "Rejected", "Open"} {
+				_, err := repo.CreateToDo(ctx, &models.ToDo{Description: status, Status: status, ParentID: &parent.ID}, testActor)
+				require.NoError(t, err)
+			}
+			_, err = repo.PatchToDo(ctx, 2, models.MergePatch{"status": "Open"}, 0, false, testActor)
+			assertErrorStatus(t, err, http.StatusUnprocessableEntity)
+
+			// Every terminal status counts as done.
+			parent, err = repo.GetToDo(ctx, parent.ID)
+			require.NoError(t, err)
+			assert.Equal(t, &models.Progress{Done: 2, Total: 3}, parent.Progress)
+		})
+	}
+}
+
+func idsOf(items []models.ToDo) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// TestOpen checks storage factory.
+func TestOpen(t *testing.T) {
+	repo, err := Open(Config{Driver: DriverMemory})
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryRepo{}, repo)
+
+	repo, err = Open(Config{Driver: DriverSQLite, DSN: filepath.Join(t.TempDir(), "todos.db")})
+	require.NoError(t, err)
+	assert.IsType(t, &SQLiteRepo{}, repo)
+	require.NoError(t, repo.Close())
+	_, err = repo.GetTags(context.Background())
+	assert.Error(t, err, "closed repository must not be usable")
+
+	_, err = Open(Config{Driver: "mongo"})
+	assert.Error(t, err)
+
+	_, err = Open(Config{Driver: DriverPostgres})
+	assert.Error(t, err, "postgres requires DSN")
+
+	started := time.Now()
+	_, err = Open(Config{
+		Driver:         DriverPostgres,
+		DSN:            "postgres://todo@127.0.0.1:1/todos?sslmode=disable",
+		ConnectTimeout: 500 * time.Millisecond,
+	})
+	assert.ErrorContains(t, err, "database isn't available")
+	assert.GreaterOrEqual(t, time.Since(started), 500*time.Millisecond, "unavailable database must be retried until timeout")
+}
+
+// TestCancelledContext checks that SQL backends stop working on items as soon as context of the request is cancelled.
+func TestCancelledContext(t *testing.T) {
+	repo, err := NewSQLiteRepo(filepath.Join(t.TempDir(), "todos.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = repo.GetToDos(ctx, &models.ParamsBag{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.CreateToDo(ctx, &models.ToDo{Description: "Never"}, testActor)
+	assert.ErrorIs(t, err, context.Canceled)
+	page, err := repo.GetToDos(context.Background(), &models.ParamsBag{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	// Failed lookups aren't reported as missing items.
+	created, err := repo.CreateToDo(context.Background(), &models.ToDo{Description: "Exists"}, testActor)
+	require.NoError(t, err)
+	_, err = repo.GetToDo(ctx, created.ID)
+	assertErrorStatus(t, err, http.StatusInternalServerError)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.PatchToDo(ctx, created.ID, models.MergePatch{"priority": "high"}, 0, false, testActor)
+	assertErrorStatus(t, err, http.StatusInternalServerError)
+	_, err = repo.GetTag(ctx, 1)
+	assertErrorStatus(t, err, http.StatusInternalServerError)
+	_, err = repo.GetProject(ctx, 1)
+	assertErrorStatus(t, err, http.StatusInternalServerError)
+	_, err = repo.GetToDo(context.Background(), created.ID+1)
+	assertErrorStatus(t, err, http.StatusNotFound)
+}
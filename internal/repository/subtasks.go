@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// subtreeIDs selects id of the item ($1) and ids of all its subtasks, including nested ones.
+// Parents never form cycles (see checkItemParent), UNION drops repeated rows, so recursion ends even if they did.
+const subtreeIDs = `WITH RECURSIVE subtree(id) AS (
+		SELECT id FROM todos WHERE id = $1
+		UNION
+		SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	) SELECT id FROM subtree`
+
+// GetToDoTree retrieves single to-do item by given id with all its subtasks nested in Children. Items in trash are skipped.
+func (s sqlStore) GetToDoTree(ctx context.Context, id int64) (models.ToDo, error) {
+	items, err := queryTodos(ctx, s.db, s.dialect.rebind("SELECT "+strings.Join(todoSelectColumns, ", ")+" FROM todos WHERE deleted_at IS NULL AND id IN ("+subtreeIDs+")"), id)
+	if err != nil {
+		return models.ToDo{}, models.InternalError(fmt.Sprintf("Unable to get subtasks of item with id %d", id), err)
+	}
+	if len(items) == 0 {
+		return models.ToDo{}, models.NotFoundError(fmt.Sprintf("Unable to find item with id %d", id), sql.ErrNoRows)
+	}
+	if err := loadItemDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal()); err != nil {
+		return models.ToDo{}, models.InternalError(fmt.Sprintf("Unable to get subtasks of item with id %d", id), err)
+	}
+	return buildTree(items, id), nil
+}
+
+// DeleteToDo moves single to-do item by given id to trash, policy defines what happens to its subtasks.
+// Items in trash keep their tags and dependencies, so restoring brings them back (see RestoreToDo).
+// Every item moved to trash or detached from the item is recorded in audit log with actor.
+func (s sqlStore) DeleteToDo(ctx context.Context, id int64, policy models.SubtaskPolicy, actor string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		return s.deleteToDo(ctx, tx, id, policy, actor)
+	})
+}
+
+// deleteToDo moves to-do item to trash within transaction.
+func (s sqlStore) deleteToDo(ctx context.Context, tx *sql.Tx, id int64, policy models.SubtaskPolicy, actor string) error {
+	if err := checkItemExists(ctx, tx, s.dialect, id); err != nil {
+		return err
+	}
+	ids := []int64{id}
+	var events []models.Event
+	switch policy {
+	case models.SubtasksCascade:
+		// Subtasks share deletion time with the item, so they are restored together.
+		var err error
+		if ids, err = queryIDs(ctx, tx, s.dialect.rebind("SELECT id FROM todos WHERE deleted_at IS NULL AND id IN ("+subtreeIDs+") ORDER BY id"), id); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+	case models.SubtasksDetach:
+		subtasks, err := queryIDs(ctx, tx, s.dialect.rebind("SELECT id FROM todos WHERE parent_id = $1 AND deleted_at IS NULL ORDER BY id"), id)
+		if err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"UPDATE todos SET parent_id = NULL, version = version + 1 WHERE parent_id = $1 AND deleted_at IS NULL"), id); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+		for _, subtask := range subtasks {
+			events = append(events, newEvent(subtask, models.EventUpdated, actor,
+				models.DiffToDo(models.ToDo{ParentID: &id}, models.ToDo{})))
+		}
+	default:
+		var subtasks int64
+		if err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			"SELECT COUNT(*) FROM todos WHERE parent_id = $1 AND deleted_at IS NULL"), id).Scan(&subtasks); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+		if subtasks > 0 {
+			return models.ConflictError(fmt.Sprintf("Item with id %d has subtasks", id), nil).WithCode(models.CodeHasSubtasks)
+		}
+	}
+	now := time.Now().Unix()
+	placeholders := make([]string, len(ids))
+	args := []any{now}
+	for i, itemID := range ids {
+		args = append(args, itemID)
+		placeholders[i] = s.dialect.placeholder(i + 2)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE todos SET deleted_at = "+s.dialect.placeholder(1)+", version = version + 1 WHERE id IN ("+strings.Join(placeholders, ", ")+")", args...); err != nil {
+		return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+	}
+	return recordEvents(ctx, tx, s.dialect, append(events, trashEvents(ids, models.EventDeleted, actor, nil, &now)...)...)
+}
+
+// checkItemParent validates parent of to-do item with given id (0 for new items): it must exist outside of trash
+// and must be neither the item itself nor any of its subtasks, so parents never form cycles.
+// The parent stays locked until the transaction ends, so concurrent updates can't form a cycle either:
+// the item being updated is locked too (see patchToDo), so one of them waits and sees the other's change.
+func checkItemParent(ctx context.Context, db DBTX, d dialect, item *models.ToDo, id int64) error {
+	if item.ParentID == nil || *item.ParentID == 0 {
+		return nil
+	}
+	parentID := *item.ParentID
+	if parentID == id {
+		return models.ValidationError("Item can't be subtask of itself", nil)
+	}
+	var existing int64
+	err := db.QueryRowContext(ctx, d.rebind("SELECT id FROM todos WHERE id = $1 AND deleted_at IS NULL"+d.forUpdate()), parentID).Scan(&existing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ValidationError(fmt.Sprintf("Unable to find parent item with id %d", parentID), err)
+	}
+	if err != nil || id == 0 {
+		return err
+	}
+	var cycles int64
+	if err := db.QueryRowContext(ctx, d.rebind(
+		"SELECT COUNT(*) FROM todos WHERE id = $2 AND id IN ("+subtreeIDs+")"), id, parentID).Scan(&cycles); err != nil {
+		return err
+	}
+	if cycles > 0 {
+		return models.ValidationError(fmt.Sprintf("Item with id %d is subtask of item with id %d", parentID, id), nil)
+	}
+	return nil
+}
+
+// loadProgress fills progress of given items having subtasks with single query. Subtasks with done statuses count as done,
+// subtasks in trash aren't counted.
+func loadProgress(ctx context.Context, db DBTX, d dialect, items []*models.ToDo, done []string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.ToDo, len(items))
+	placeholders := make([]string, len(items))
+	args := make([]any, len(items), len(items)+len(done))
+	for i, item := range items {
+		item.Progress = nil
+		byID[item.ID] = item
+		placeholders[i] = d.placeholder(i + 1)
+		args[i] = item.ID
+	}
+	isDone := "0 = 1"
+	if len(done) != 0 {
+		statuses := make([]string, len(done))
+		for i, status := range done {
+			args = append(args, status)
+			statuses[i] = d.placeholder(len(args))
+		}
+		isDone = "status IN (" + strings.Join(statuses, ", ") + ")"
+	}
+	rows, err := db.QueryContext(ctx,
+		`WITH RECURSIVE subtasks(root, id, status) AS (
+			SELECT parent_id, id, status FROM todos WHERE deleted_at IS NULL AND parent_id IN (`+strings.Join(placeholders, ", ")+`)
+			UNION
+			SELECT subtasks.root, todos.id, todos.status FROM todos JOIN subtasks ON todos.parent_id = subtasks.id
+			WHERE todos.deleted_at IS NULL
+		)
+		SELECT root, COUNT(*), SUM(CASE WHEN `+isDone+` THEN 1 ELSE 0 END) FROM subtasks GROUP BY root`, args...)
+	if err != nil {
+		return err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	for rows.Next() {
+		var id int64
+		var progress models.Progress
+		if err := rows.Scan(&id, &progress.Total, &progress.Done); err != nil {
+			return err
+		}
+		byID[id].Progress = &progress
+	}
+	return rows.Err()
+}
+
+// queryIDs executes query selecting single id column.
+func queryIDs(ctx context.Context, db DBTX, query string, args ...any) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// deleteTodos permanently deletes items with given ids, their links to tags and dependencies.
+// Links are removed explicitly, as SQLite doesn't enforce foreign keys by default. Remaining subtasks become top-level,
+// detaching is recorded in audit log with actor.
+func deleteTodos(ctx context.Context, tx *sql.Tx, d dialect, ids []int64, actor string) error {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = d.placeholder(i + 1)
+		args[i] = id
+	}
+	in := "(" + strings.Join(placeholders, ", ") + ")"
+	if _, err := tx.ExecContext(ctx, "DELETE FROM todo_tags WHERE todo_id IN "+in, args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM todo_dependencies WHERE todo_id IN "+in+" OR blocker_id IN "+in, args...); err != nil {
+		return err
+	}
+	events, err := detachEvents(ctx, tx, "SELECT id, parent_id FROM todos WHERE parent_id IN "+in+" AND id NOT IN "+in+" ORDER BY id", args, actor,
+		func(parentID *int64) models.ToDo { return models.ToDo{ParentID: parentID} })
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE todos SET parent_id = NULL, version = version + 1 WHERE parent_id IN "+in, args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM todos WHERE id IN "+in, args...); err != nil {
+		return err
+	}
+	return recordEvents(ctx, tx, d, events...)
+}
+
+// buildTree nests items under their parents and returns the root item.
+// Subtasks are ordered the same way as default to-do list.
+func buildTree(items []models.ToDo, rootID int64) models.ToDo {
+	order := pageOrder{keys: defaultSortKeys, idASC: true}
+	sort.Slice(items, func(i, j int) bool {
+		return order.compare(items[i], items[j]) < 0
+	})
+	var root models.ToDo
+	subtasks := make(map[int64][]models.ToDo)
+	for _, item := range items {
+		if item.ID == rootID {
+			root = item
+			continue
+		}
+		subtasks[*item.ParentID] = append(subtasks[*item.ParentID], item)
+	}
+	var nest func(item models.ToDo) models.ToDo
+	nest = func(item models.ToDo) models.ToDo {
+		for _, subtask := range subtasks[item.ID] {
+			item.Children = append(item.Children, nest(subtask))
+		}
+		return item
+	}
+	return nest(root)
+}
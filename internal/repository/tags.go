@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// GetTags retrieves all tags ordered by name with number of tagged items. Items in trash aren't counted.
+func (s sqlStore) GetTags(ctx context.Context) ([]models.Tag, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		`SELECT tags.id, tags.name, tags.color, COUNT(todo_tags.todo_id) FROM tags
+		LEFT JOIN todo_tags ON todo_tags.tag_id = tags.id AND todo_tags.todo_id IN (SELECT id FROM todos WHERE deleted_at IS NULL)
+		GROUP BY tags.id, tags.name, tags.color
+		ORDER BY tags.name`))
+	if err != nil {
+		return nil, models.InternalError("Unable to get tags", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	tags := []models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, models.InternalError("Unable to get tags", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.InternalError("Unable to get tags", err)
+	}
+	return tags, nil
+}
+
+// GetTag retrieves single tag by given id.
+func (s sqlStore) GetTag(ctx context.Context, id int64) (models.Tag, error) {
+	return getTag(ctx, s.db, s.dialect, id)
+}
+
+// CreateTag writes new tag, names are unique.
+func (s sqlStore) CreateTag(ctx context.Context, tag *models.Tag) (models.Tag, error) {
+	if err := normalizeTag(tag); err != nil {
+		return models.Tag{}, err
+	}
+	var created models.Tag
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := checkTagNameFree(ctx, tx, s.dialect, tag.Name, 0); err != nil {
+			return err
+		}
+		var id int64
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO tags (name, color) VALUES ($1, $2) RETURNING id"),
+			tag.Name, nullString(tag.Color)).Scan(&id)
+		if err != nil {
+			return models.InternalError("Unable to create tag", err)
+		}
+		created, err = getTag(ctx, tx, s.dialect, id)
+		return err
+	})
+	return created, err
+}
+
+// UpdateTag renames tag and/or changes its color. Blank fields keep their old values.
+func (s sqlStore) UpdateTag(ctx context.Context, tag *models.Tag, id int64) (models.Tag, error) {
+	if err := normalizeTagUpdate(tag); err != nil {
+		return models.Tag{}, err
+	}
+	var updated models.Tag
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		old, err := getTag(ctx, tx, s.dialect, id)
+		if err != nil {
+			return err
+		}
+		if len(tag.Name) == 0 {
+			tag.Name = old.Name
+		}
+		if len(tag.Color) == 0 {
+			tag.Color = old.Color
+		}
+		if err := checkTagNameFree(ctx, tx, s.dialect, tag.Name, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind("UPDATE tags SET name = $1, color = $2 WHERE id = $3"),
+			tag.Name, nullString(tag.Color), id); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to update tag with id %d", id), err)
+		}
+		updated, err = getTag(ctx, tx, s.dialect, id)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteTag deletes tag by given id, tagged items lose the tag. Change of their tags is recorded in audit log with actor.
+func (s sqlStore) DeleteTag(ctx context.Context, id int64, actor string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := getTag(ctx, tx, s.dialect, id); err != nil {
+			return err
+		}
+		return retagItems(ctx, tx, s.dialect, id, actor, func() error {
+			return deleteTag(ctx, tx, s.dialect, id)
+		})
+	})
+}
+
+// MergeTags moves all items of source tag to target tag and deletes source tag.
+// Change of tags of the items is recorded in audit log with actor.
+func (s sqlStore) MergeTags(ctx context.Context, sourceID, targetID int64, actor string) (models.Tag, error) {
+	if sourceID == targetID {
+		return models.Tag{}, models.ValidationError("Unable to merge tag into itself", nil)
+	}
+	var merged models.Tag
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := getTag(ctx, tx, s.dialect, sourceID); err != nil {
+			return err
+		}
+		if _, err := getTag(ctx, tx, s.dialect, targetID); err != nil {
+			return err
+		}
+		err := retagItems(ctx, tx, s.dialect, sourceID, actor, func() error {
+			// Items having both tags keep the target link, their source links are deleted with the source tag.
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(
+				`UPDATE todo_tags SET tag_id = $1 WHERE tag_id = $2
+				AND todo_id NOT IN (SELECT todo_id FROM todo_tags WHERE tag_id = $1)`), targetID, sourceID); err != nil {
+				return models.InternalError(fmt.Sprintf("Unable to merge tag with id %d", sourceID), err)
+			}
+			return deleteTag(ctx, tx, s.dialect, sourceID)
+		})
+		if err != nil {
+			return err
+		}
+		merged, err = getTag(ctx, tx, s.dialect, targetID)
+		return err
+	})
+	return merged, err
+}
+
+func getTag(ctx context.Context, db DBTX, d dialect, id int64) (models.Tag, error) {
+	row := db.QueryRowContext(ctx, d.rebind(
+		`SELECT tags.id, tags.name, tags.color, (SELECT COUNT(*) FROM todo_tags WHERE todo_tags.tag_id = tags.id
+			AND todo_tags.todo_id IN (SELECT id FROM todos WHERE deleted_at IS NULL))
+		FROM tags WHERE tags.id = $1`), id)
+	tag, err := scanTag(row)
+	if err != nil {
+		return models.Tag{}, lookupError(err, fmt.Sprintf("Unable to find tag with id %d", id))
+	}
+	return tag, nil
+}
+
+func deleteTag(ctx context.Context, tx *sql.Tx, d dialect, id int64) error {
+	// Links are removed explicitly, as SQLite doesn't enforce foreign keys by default.
+	if _, err := tx.ExecContext(ctx, d.rebind("DELETE FROM todo_tags WHERE tag_id = $1"), id); err != nil {
+		return models.InternalError(fmt.Sprintf("Unable to delete tag with id %d", id), err)
+	}
+	if _, err := tx.ExecContext(ctx, d.rebind("DELETE FROM tags WHERE id = $1"), id); err != nil {
+		return models.InternalError(fmt.Sprintf("Unable to delete tag with id %d", id), err)
+	}
+	return nil
+}
+
+// retagItems applies change to tags of items having the tag with given id. The items get new version
+// and change of their tags is recorded in audit log with actor.
+func retagItems(ctx context.Context, tx *sql.Tx, d dialect, tagID int64, actor string, change func() error) error {
+	ids, err := queryIDs(ctx, tx, d.rebind("SELECT todo_id FROM todo_tags WHERE tag_id = $1 ORDER BY todo_id"), tagID)
+	if err != nil {
+		return models.InternalError("Unable to get tagged items", err)
+	}
+	if len(ids) == 0 {
+		return change()
+	}
+	before := make([]*models.ToDo, len(ids))
+	after := make([]*models.ToDo, len(ids))
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		before[i], after[i] = &models.ToDo{ID: id}, &models.ToDo{ID: id}
+		placeholders[i] = d.placeholder(i + 1)
+		args[i] = id
+	}
+	if err := loadTags(ctx, tx, d, before); err != nil {
+		return models.InternalError("Unable to get tagged items", err)
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	if err := loadTags(ctx, tx, d, after); err != nil {
+		return models.InternalError("Unable to get tagged items", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE todos SET version = version + 1 WHERE id IN ("+strings.Join(placeholders, ", ")+")", args...); err != nil {
+		return models.InternalError("Unable to update tagged items", err)
+	}
+	events := make([]models.Event, len(ids))
+	for i, id := range ids {
+		events[i] = newEvent(id, models.EventUpdated, actor, models.DiffToDo(*before[i], *after[i]))
+	}
+	if err := recordEvents(ctx, tx, d, events...); err != nil {
+		return models.InternalError("Unable to update tagged items", err)
+	}
+	return nil
+}
+
+// checkTagNameFree returns conflict error, if other tag (not the one with given id) has the name.
+func checkTagNameFree(ctx context.Context, db DBTX, d dialect, name string, id int64) error {
+	var existing int64
+	err := db.QueryRowContext(ctx, d.rebind("SELECT id FROM tags WHERE name = $1"), name).Scan(&existing)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return models.InternalError("Unable to check tag name", err)
+	case existing != id:
+		return models.ConflictError(fmt.Sprintf("Tag %s already exists", name), nil).WithCode(models.CodeTagExists)
+	}
+	return nil
+}
+
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	var color sql.NullString
+	if err := row.Scan(&tag.ID, &tag.Name, &color, &tag.Count); err != nil {
+		return models.Tag{}, err
+	}
+	tag.Color = color.String
+	return tag, nil
+}
+
+// normalizeTag validates name and color of new tag.
+func normalizeTag(tag *models.Tag) error {
+	name, err := models.NormalizeTagName(tag.Name)
+	if err != nil {
+		return models.ValidationError(err.Error(), err)
+	}
+	tag.Name = name
+	color, err := models.NormalizeColor(tag.Color)
+	if err != nil {
+		return models.ValidationError(err.Error(), err)
+	}
+	tag.Color = color
+	return nil
+}
+
+// normalizeTagUpdate validates given fields of tag update, blank ones are kept blank.
+func normalizeTagUpdate(tag *models.Tag) error {
+	if len(strings.TrimSpace(tag.Name)) == 0 {
+		tag.Name = ""
+		color, err := models.NormalizeColor(tag.Color)
+		if err != nil {
+			return models.ValidationError(err.Error(), err)
+		}
+		tag.Color = color
+		return nil
+	}
+	return normalizeTag(tag)
+}
+
+// normalizeItemTags validates tags of to-do item, nil tags stay nil.
+func normalizeItemTags(item *models.ToDo) error {
+	tags, err := models.NormalizeTags(item.Tags)
+	if err != nil {
+		return models.ValidationError(err.Error(), err)
+	}
+	item.Tags = tags
+	return nil
+}
+
+// setTodoTags replaces tags of to-do item with given ones, missing tags are created.
+func setTodoTags(ctx context.Context, tx *sql.Tx, d dialect, todoID int64, names []string) error {
+	if _, err := tx.ExecContext(ctx, d.rebind("DELETE FROM todo_tags WHERE todo_id = $1"), todoID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, d.rebind("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"), name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, d.rebind(
+			"INSERT INTO todo_tags (todo_id, tag_id) VALUES ($1, (SELECT id FROM tags WHERE name = $2))"), todoID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills tags of given items with single query.
+func loadTags(ctx context.Context, db DBTX, d dialect, items []*models.ToDo) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.ToDo, len(items))
+	placeholders := make([]string, len(items))
+	args := make([]any, len(items))
+	for i, item := range items {
+		item.Tags = []string{}
+		byID[item.ID] = item
+		placeholders[i] = d.placeholder(i + 1)
+		args[i] = item.ID
+	}
+	rows, err := db.QueryContext(ctx,
+		"SELECT todo_tags.todo_id, tags.name FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id "+
+			"WHERE todo_tags.todo_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY tags.name", args...)
+	if err != nil {
+		return err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(rows)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// GetTrash retrieves items in trash, most recently deleted first.
+func (s sqlStore) GetTrash(ctx context.Context) ([]models.ToDo, error) {
+	items, err := queryTodos(ctx, s.db, "SELECT "+strings.Join(todoSelectColumns, ", ")+
+		" FROM todos WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+	if err != nil {
+		return nil, models.InternalError("Unable to get trash", err)
+	}
+	if err := loadItemDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal()); err != nil {
+		return nil, models.InternalError("Unable to get trash", err)
+	}
+	return items, nil
+}
+
+// RestoreToDo moves item with given id out of trash together with subtasks deleted along with it.
+// If parent of the item is still in trash, the item becomes top-level. Restored items are recorded in audit log with actor.
+func (s sqlStore) RestoreToDo(ctx context.Context, id int64, actor string) (models.ToDo, error) {
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var deletedAt int64
+		var parentID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, s.dialect.rebind(
+			"SELECT deleted_at, parent_id FROM todos WHERE id = $1 AND deleted_at IS NOT NULL"), id).Scan(&deletedAt, &parentID); err != nil {
+			return lookupError(err, fmt.Sprintf("Unable to find item with id %d in trash", id))
+		}
+		ids, err := queryIDs(ctx, tx, s.dialect.rebind("SELECT id FROM todos WHERE deleted_at = $2 AND id IN ("+subtreeIDs+") ORDER BY id"), id, deletedAt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"UPDATE todos SET deleted_at = NULL, version = version + 1 WHERE deleted_at = $2 AND id IN ("+subtreeIDs+")"), id, deletedAt); err != nil {
+			return err
+		}
+		events := trashEvents(ids, models.EventRestored, actor, &deletedAt, nil)
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(
+			"UPDATE todos SET parent_id = NULL, version = version + 1 WHERE id = $1 AND parent_id IN (SELECT id FROM todos WHERE deleted_at IS NOT NULL)"), id)
+		if err != nil {
+			return err
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+			i := slices.Index(ids, id)
+			events[i].Changes = append(events[i].Changes, models.DiffToDo(models.ToDo{ParentID: nullInt64Ptr(parentID)}, models.ToDo{})...)
+		}
+		return recordEvents(ctx, tx, s.dialect, events...)
+	})
+	if err != nil {
+		return models.ToDo{}, wrapError(err, fmt.Sprintf("Unable to restore item with id %d", id))
+	}
+	items, err := queryTodos(ctx, s.db, s.dialect.rebind("SELECT "+strings.Join(todoSelectColumns, ", ")+" FROM todos WHERE id = $1"), id)
+	if err == nil && len(items) == 0 {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		err = loadItemDetails(ctx, s.db, s.dialect, items, s.workflow.Terminal())
+	}
+	if err != nil {
+		return models.ToDo{}, models.InternalError(fmt.Sprintf("Unable to get details of item with id %d", id), err)
+	}
+	return items[0], nil
+}
+
+// PurgeToDo permanently deletes item with given id from trash together with its subtasks in trash.
+// Their history stays in audit log, purging and detaching of remaining subtasks is recorded there with actor.
+func (s sqlStore) PurgeToDo(ctx context.Context, id int64, actor string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids, err := queryIDs(ctx, tx, s.dialect.rebind("SELECT id FROM todos WHERE deleted_at IS NOT NULL AND id IN ("+subtreeIDs+") ORDER BY id"), id)
+		if err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+		if !slices.Contains(ids, id) {
+			return models.NotFoundError(fmt.Sprintf("Unable to find item with id %d in trash", id), sql.ErrNoRows)
+		}
+		if err := deleteTodos(ctx, tx, s.dialect, ids, actor); err != nil {
+			return models.InternalError(fmt.Sprintf("Unable to delete item with id %d", id), err)
+		}
+		return recordEvents(ctx, tx, s.dialect, purgeEvents(ids, actor)...)
+	})
+}
+
+// PurgeTrash permanently deletes items moved to trash before given unix timestamp and returns their number.
+// Purging is recorded in audit log with models.SystemActor.
+func (s sqlStore) PurgeTrash(ctx context.Context, before int64) (int64, error) {
+	var purged int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		ids, err := queryIDs(ctx, tx, s.dialect.rebind("SELECT id FROM todos WHERE deleted_at < $1 ORDER BY id"), before)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		purged = int64(len(ids))
+		if err := deleteTodos(ctx, tx, s.dialect, ids, models.SystemActor); err != nil {
+			return err
+		}
+		return recordEvents(ctx, tx, s.dialect, purgeEvents(ids, models.SystemActor)...)
+	})
+	if err != nil {
+		return 0, models.InternalError("Unable to purge trash", err)
+	}
+	return purged, nil
+}
+
+// purgeEvents returns events of permanent deleting of items with given ids.
+func purgeEvents(ids []int64, actor string) []models.Event {
+	events := make([]models.Event, len(ids))
+	for i, id := range ids {
+		events[i] = newEvent(id, models.EventPurged, actor, nil)
+	}
+	return events
+}
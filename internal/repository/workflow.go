@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"LazyToDo/internal/models"
+	"fmt"
+	"strings"
+)
+
+// Workflow returns statuses of to-do items and transitions allowed between them.
+func (s sqlStore) Workflow() models.Workflow {
+	return s.workflow
+}
+
+// resolveNewStatus resolves validated status of new item against the workflow, blank status becomes the initial one.
+func resolveNewStatus(workflow models.Workflow, status string) string {
+	if resolved, ok := workflow.Resolve(status); ok {
+		return resolved
+	}
+	return workflow.Initial
+}
+
+// checkTransition resolves new status of updated item and checks that workflow allows moving item into it.
+// Keeping the same status is always allowed.
+func checkTransition(workflow models.Workflow, from, to string) (string, error) {
+	if strings.TrimSpace(to) == from {
+		return from, nil
+	}
+	resolved, ok := workflow.Resolve(to)
+	if ok && resolved == from {
+		return resolved, nil
+	}
+	transitions := workflow.Transitions(from)
+	for _, transition := range transitions {
+		if ok && transition == resolved {
+			return resolved, nil
+		}
+	}
+	return "", models.UnprocessableError(fmt.Sprintf("Transition from %q to %q isn't allowed, valid transitions: %s", from, to, statusList(transitions)), nil).
+		WithCode(models.CodeTransitionNotAllowed)
+}
+
+func statusList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,16 +1,131 @@
 package server
 
 import (
+	"LazyToDo/internal/config"
 	"LazyToDo/internal/handler"
+	"LazyToDo/internal/repository"
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Start(port string) error {
-	r := gin.Default()
-	handler.Route(r)
-	err := r.Run(":" + port)
+// purgeInterval is how often items with expired retention are purged from trash and expired idempotency keys deleted.
+const purgeInterval = time.Hour
+
+// Start opens configured storage, shared by all requests and closed when serving stops, and serves API
+// with configured timeouts and routes until SIGINT or SIGTERM. Then it stops accepting connections and waits
+// for requests in flight up to shutdown timeout, requests still running after it are cancelled.
+// Log level debug turns on debug mode of Gin, warn and error turn off logging of requests.
+func Start(cfg config.Config, storage repository.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, err := repository.Open(storage)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err := repo.Close(); err != nil {
+			log.Println("Failed to close storage:", err)
+		}
+	}()
+	if storage.TrashRetention > 0 {
+		go purgeTrash(ctx, repo, storage.TrashRetention)
+	}
+	go purgeIdempotencyKeys(ctx, repo, storage.IdempotencyWindow)
+
+	gin.SetMode(gin.ReleaseMode)
+	if cfg.Log.Level == config.LevelDebug {
+		gin.SetMode(gin.DebugMode)
+	}
+	r := gin.New()
+	if cfg.Log.Level == config.LevelDebug || cfg.Log.Level == config.LevelInfo {
+		r.Use(gin.Logger())
+	}
+	// Panics are recovered by handler.Problems, so they're rendered as problem details with request id.
+	handler.Route(r, handler.NewTodoHandler(repo), handler.Options{
+		IdempotencyWindow: storage.IdempotencyWindow,
+		LegacyRoutes:      cfg.Features.LegacyRoutes,
+		Swagger:           cfg.Features.Swagger,
+		DocsDir:           cfg.Server.DocsDir,
+	})
+	server := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+	log.Printf("Serving API on port %s", cfg.Server.Port)
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	// Another signal kills the process right away.
+	stop()
+	return shutdown(server, cfg.Server.ShutdownTimeout)
+}
+
+// shutdown stops server gracefully: requests in flight are given timeout to finish, then their connections are closed,
+// which cancels contexts of the requests and so their queries.
+func shutdown(server *http.Server, timeout time.Duration) error {
+	log.Printf("Shutting down, waiting up to %s for requests in flight", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		if closeErr := server.Close(); closeErr != nil {
+			log.Println("Failed to close connections:", closeErr)
+		}
+		return fmt.Errorf("requests in flight didn't finish within %s: %w", timeout, err)
+	}
+	log.Println("Server stopped")
 	return nil
 }
+
+// purgeTrash periodically deletes items, that have been in trash longer than retention, until ctx is done.
+func purgeTrash(ctx context.Context, repo repository.Repository, retention time.Duration) {
+	every(ctx, purgeInterval, func() {
+		purged, err := repo.PurgeTrash(ctx, time.Now().Add(-retention).Unix())
+		if err != nil {
+			log.Println("Failed to purge trash:", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("Purged %d items from trash", purged)
+		}
+	})
+}
+
+// purgeIdempotencyKeys periodically deletes idempotency keys, whose responses are no longer replayed, until ctx is done.
+func purgeIdempotencyKeys(ctx context.Context, repo repository.Repository, window time.Duration) {
+	every(ctx, purgeInterval, func() {
+		if _, err := repo.PurgeIdempotencyKeys(ctx, time.Now().Add(-window).Unix()); err != nil {
+			log.Println("Failed to purge idempotency keys:", err)
+		}
+	})
+}
+
+// every runs fn right away and then with given interval until ctx is done.
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,2 @@
+-- Full-text search over descriptions. Queries must use the same expression to hit the index.
+CREATE INDEX idx_todos_search ON todos USING GIN (to_tsvector('simple', COALESCE(description, '')));
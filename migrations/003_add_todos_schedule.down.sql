@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_due_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS due_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS start_at;
@@ -0,0 +1,5 @@
+-- Optional schedule of the item: unix timestamps, start must precede due.
+ALTER TABLE todos ADD COLUMN start_at BIGINT;
+ALTER TABLE todos ADD COLUMN due_at BIGINT;
+
+CREATE INDEX idx_due_at ON todos(due_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_priority;
+ALTER TABLE todos DROP COLUMN IF EXISTS priority;
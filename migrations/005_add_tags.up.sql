@@ -0,0 +1,14 @@
+-- Tags are shared by to-do items, names are unique.
+CREATE TABLE tags (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    color VARCHAR(7)
+);
+
+CREATE TABLE todo_tags (
+    todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX idx_todo_tags_tag_id ON todo_tags(tag_id);
@@ -0,0 +1,14 @@
+-- Projects group to-do items, items of deleted project stay without project.
+CREATE TABLE projects (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    color VARCHAR(7),
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created BIGINT,
+    updated BIGINT
+);
+
+ALTER TABLE todos ADD COLUMN project_id BIGINT REFERENCES projects(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_project_id ON todos(project_id);
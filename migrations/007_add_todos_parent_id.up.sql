@@ -0,0 +1,4 @@
+-- Subtasks reference their parent item, deleting parent is handled by the application (restrict, cascade or detach).
+ALTER TABLE todos ADD COLUMN parent_id BIGINT REFERENCES todos(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_parent_id ON todos(parent_id);
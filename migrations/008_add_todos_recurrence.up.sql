@@ -0,0 +1,2 @@
+-- RFC 5545 recurrence rule, next occurrence is created when item reaches terminal status.
+ALTER TABLE todos ADD COLUMN recurrence VARCHAR(255);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_deleted_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS deleted_at;
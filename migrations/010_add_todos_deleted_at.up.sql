@@ -0,0 +1,4 @@
+-- Deleted items stay in trash until restored or purged, NULL means the item isn't deleted.
+ALTER TABLE todos ADD COLUMN deleted_at BIGINT;
+
+CREATE INDEX idx_deleted_at ON todos(deleted_at);
@@ -0,0 +1,12 @@
+-- Append-only audit log of changes of to-do items. There's no foreign key, so history outlives purged items.
+CREATE TABLE todo_events (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    changes TEXT NOT NULL,
+    created BIGINT NOT NULL
+);
+
+CREATE INDEX idx_todo_events_todo_id ON todo_events(todo_id);
+CREATE INDEX idx_todo_events_created ON todo_events(created);
@@ -0,0 +1,2 @@
+-- Version is incremented on every change of the item, it's exposed as ETag for optimistic concurrency.
+ALTER TABLE todos ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
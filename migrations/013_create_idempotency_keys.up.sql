@@ -0,0 +1,11 @@
+-- Responses of requests made with Idempotency-Key header, replayed for retries within configured window.
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    created BIGINT NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created);
@@ -0,0 +1,2 @@
+-- Headers of stored response replayed for retries (Location, ETag) as JSON object.
+ALTER TABLE idempotency_keys ADD COLUMN headers TEXT NOT NULL DEFAULT '{}';